@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/yalagtyarzh/leafsite/internal/config"
+	"github.com/yalagtyarzh/leafsite/internal/driver"
+	"github.com/yalagtyarzh/leafsite/internal/importer"
+	"github.com/yalagtyarzh/leafsite/internal/repository/dbrepo"
+)
+
+//main imports reservations from a CSV file, see -h for the flags
+func main() {
+	dbName := flag.String("dbname", "", "Database name")
+	dbHost := flag.String("dbhost", "localhost", "Database host")
+	dbUser := flag.String("dbuser", "", "Database user")
+	dbPass := flag.String("dbpass", "", "Database password")
+	dbPort := flag.String("dbport", "5432", "Database port")
+	dbSSL := flag.String("dbssl", "disable", "Database ssl settings (disable, prefer, require)")
+	file := flag.String("file", "", "CSV file to import")
+	mapping := flag.String("map", "", "Columns of the fields, like first_name=First Name,room=Room No")
+	layout := flag.String("layout", importer.DefaultDateLayout, "Date layout of the file, in Go time format")
+	dryRun := flag.Bool("dry-run", false, "Only validate the file")
+
+	flag.Parse()
+
+	if *dbName == "" || *dbUser == "" || *file == "" {
+		fmt.Println("Missing required flags")
+		os.Exit(1)
+	}
+
+	m, err := importer.ParseMapping(*mapping)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	connectionString := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s sslmode=%s", *dbHost, *dbPort, *dbName, *dbUser, *dbPass, *dbSSL)
+	db, err := driver.ConnectSQL(connectionString)
+	if err != nil {
+		log.Fatal("Cannot connect to database! Dying...")
+	}
+	defer db.SQL.Close()
+
+	repo := dbrepo.NewPostgresRepo(db.SQL, &config.AppConfig{})
+
+	report, err := importer.Import(repo, f, importer.Options{
+		Mapping:    m,
+		DateLayout: *layout,
+		DryRun:     *dryRun,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, x := range report.Rows {
+		status := "ok"
+		if len(x.Errors) > 0 {
+			status = "error"
+		}
+
+		fmt.Printf("line %d: %s, %s %s, %s, %s - %s\n", x.Line, status, x.Reservation.FirstName,
+			x.Reservation.LastName, x.Reservation.Room.RoomName, x.Reservation.StartDate.Format("2006-01-02"),
+			x.Reservation.EndDate.Format("2006-01-02"))
+
+		for _, e := range x.Errors {
+			fmt.Printf("\t%s\n", e)
+		}
+	}
+
+	fmt.Printf("%d rows, %d with errors, %d imported\n", len(report.Rows), report.Invalid, report.Imported)
+
+	if !report.Valid() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/handlers"
+)
+
+//holdSweepInterval is how often expired booking holds and waitlist offers are released
+const holdSweepInterval = time.Minute
+
+//listenForExpiredHolds releases expired booking holds in the background, and passes expired waitlist offers on
+//to the next guests in line
+func listenForExpiredHolds() {
+	go func() {
+		ticker := time.NewTicker(holdSweepInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			released, err := handlers.Repo.DB.DeleteExpiredHolds()
+			if err != nil {
+				errorLog.Println(err)
+			} else if released > 0 {
+				infoLog.Printf("Released %d expired holds\n", released)
+			}
+
+			expired, err := handlers.Repo.ExpireWaitlistOffers()
+			if err != nil {
+				errorLog.Println(err)
+			} else if expired > 0 {
+				infoLog.Printf("Closed %d expired waitlist offers\n", expired)
+			}
+		}
+	}()
+}
@@ -7,15 +7,19 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/yalagtyarzh/leafsite/internal/config"
+	"github.com/yalagtyarzh/leafsite/internal/currency"
 	"github.com/yalagtyarzh/leafsite/internal/driver"
 	"github.com/yalagtyarzh/leafsite/internal/handlers"
 	"github.com/yalagtyarzh/leafsite/internal/helpers"
 	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/payments"
 	"github.com/yalagtyarzh/leafsite/internal/render"
+	"github.com/yalagtyarzh/leafsite/internal/urlsigner"
 )
 
 const portNumber = ":8080"
@@ -39,6 +43,14 @@ func main() {
 
 	listenForMail()
 
+	fmt.Println("Starting hold sweeper...")
+
+	listenForExpiredHolds()
+
+	fmt.Println("Starting no-show job...")
+
+	listenForNoShows()
+
 	fmt.Printf("Starting application on port %s\n", portNumber)
 
 	srv := &http.Server{
@@ -67,6 +79,16 @@ func run() (*driver.DB, error) {
 	dbPass := flag.String("dbpass", "", "Database password")
 	dbPort := flag.String("dbport", "5432", "Database port")
 	dbSSL := flag.String("dbssl", "disable", "Database ssl settings (disable, prefer, require)")
+	siteURL := flag.String("url", "http://localhost:8080", "Public URL of the site, used for links in emails")
+	secret := flag.String("secret", "", "Secret key used to sign links in emails")
+	paymentProvider := flag.String("payments", "", "Payment provider (fake, none), fake unless in production")
+	paymentSecret := flag.String("payments-secret", "", "Secret key the payment provider signs webhooks with")
+	depositPercent := flag.Int("deposit", 0, "Percentage of the total paid as a deposit when booking")
+	depositFullWithin := flag.Int("deposit-full-within", 0, "Bookings arriving within this many days are paid in full")
+	companyName := flag.String("company-name", "Fort Smyth Bed and Breakfast", "Company name printed on invoices")
+	companyAddress := flag.String("company-address", "", "Company address printed on invoices, lines separated by semicolons")
+	companyVAT := flag.String("company-vat", "", "Company VAT number printed on invoices")
+	baseCurrency := flag.String("currency", "USD", "Currency all prices are set and charged in, like USD")
 
 	flag.Parse()
 
@@ -75,12 +97,50 @@ func run() (*driver.DB, error) {
 		os.Exit(1)
 	}
 
+	if *inProduction && *secret == "" {
+		fmt.Println("Missing required flags")
+		os.Exit(1)
+	}
+
+	if !currency.ValidCode(*baseCurrency) {
+		fmt.Printf("Invalid currency %s\n", *baseCurrency)
+		os.Exit(1)
+	}
+
 	mailChan := make(chan models.MailData)
 	app.MailChan = mailChan
 
 	//Change this to true when in production
 	app.InProduction = *inProduction
 	app.UseCache = *useCache
+	app.URL = strings.TrimSuffix(*siteURL, "/")
+	app.Signer = urlsigner.New(*secret)
+	app.Deposit = payments.DepositRule{Percent: *depositPercent, FullWithinDays: *depositFullWithin}
+	app.Currency = *baseCurrency
+	app.ExchangeRates = currency.NewRates()
+	app.Company = models.BillingDetails{
+		Name:      *companyName,
+		Address:   strings.Join(strings.Split(*companyAddress, ";"), "\n"),
+		VATNumber: *companyVAT,
+	}
+
+	if *paymentProvider == "" && !*inProduction {
+		*paymentProvider = "fake"
+	}
+
+	switch *paymentProvider {
+	case "fake":
+		if *inProduction {
+			fmt.Println("The fake payment provider can't be used in production")
+			os.Exit(1)
+		}
+		app.Payments = payments.NewFake(*paymentSecret, app.URL)
+	case "", "none":
+		//no online payments, staff record payments taken at the desk
+	default:
+		fmt.Printf("Unknown payment provider %s\n", *paymentProvider)
+		os.Exit(1)
+	}
 
 	infoLog = log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 	app.InfoLog = infoLog
@@ -116,6 +176,11 @@ func run() (*driver.DB, error) {
 
 	repo := handlers.NewRepo(&app, db)
 	handlers.NewHandlers(repo)
+	err = repo.LoadExchangeRates()
+	if err != nil {
+		log.Fatal("cannot load exchange rates")
+		return nil, err
+	}
 	render.NewRenderer(&app)
 	helpers.NewHelpers(&app)
 
@@ -11,6 +11,9 @@ import (
 func NoSurf(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
 
+	//the payment provider signs its webhooks instead
+	csrfHandler.ExemptPath("/payments/webhook")
+
 	csrfHandler.SetBaseCookie(http.Cookie{
 		HttpOnly: true,
 		Path:     "/",
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/handlers"
+)
+
+//noShowCheckInterval is how often reservations whose arrival day has ended are checked for no-shows
+const noShowCheckInterval = time.Hour
+
+//listenForNoShows marks confirmed reservations that weren't checked in by the end of the arrival day as
+//no-shows in the background. Only the arrival day that has just ended is checked
+func listenForNoShows() {
+	go func() {
+		ticker := time.NewTicker(noShowCheckInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			marked, err := handlers.Repo.MarkNoShows(time.Now())
+			if err != nil {
+				errorLog.Println(err)
+				continue
+			}
+
+			if marked > 0 {
+				infoLog.Printf("Marked %d reservations as no-shows\n", marked)
+			}
+		}
+	}()
+}
@@ -26,15 +26,30 @@ func routes(app *config.AppConfig) http.Handler {
 	mux.Get("/search-availability", handlers.Repo.Availability)
 	mux.Post("/search-availability", handlers.Repo.PostAvailability)
 	mux.Post("/search-availability-json", handlers.Repo.AvailabilityJSON)
+	mux.Get("/availability-calendar", handlers.Repo.AvailabilityCalendarJSON)
 	mux.Get("/choose-room/{id}", handlers.Repo.ChooseRoom)
 	mux.Get("/book-room", handlers.Repo.BookRoom)
 
+	mux.Get("/waitlist", handlers.Repo.Waitlist)
+	mux.Post("/waitlist", handlers.Repo.PostWaitlist)
+	mux.Get("/waitlist/book", handlers.Repo.WaitlistBook)
+	mux.Get("/waitlist/decline", handlers.Repo.WaitlistDecline)
+
 	mux.Get("/contact", handlers.Repo.Contact)
+	mux.Post("/currency", handlers.Repo.SetCurrency)
 
 	mux.Get("/make-reservation", handlers.Repo.Reservation)
 	mux.Post("/make-reservation", handlers.Repo.PostReservation)
 	mux.Get("/reservation-summary", handlers.Repo.ReservationSummary)
 
+	mux.Post("/payments/webhook", handlers.Repo.PaymentWebhook)
+	mux.Get("/payments/fake/{ref}", handlers.Repo.FakeCheckout)
+	mux.Post("/payments/fake/{ref}", handlers.Repo.PostFakeCheckout)
+
+	mux.Get("/reservations/{id}/documents", handlers.Repo.GuestDocuments)
+	mux.Get("/reservations/{id}/invoices/{number}", handlers.Repo.GuestInvoicePDF)
+	mux.Get("/reservations/{id}/receipts/{payment}", handlers.Repo.GuestReceiptPDF)
+
 	mux.Get("/user/login", handlers.Repo.ShowLogin)
 	mux.Post("/user/login", handlers.Repo.PostShowLogin)
 	mux.Get("/user/logout", handlers.Repo.Logout)
@@ -52,9 +67,90 @@ func routes(app *config.AppConfig) http.Handler {
 		mux.Post("/reservations-calendar", handlers.Repo.AdminPostReservationsCalendar)
 		mux.Get("/process-reservation/{src}/{id}/do", handlers.Repo.AdminProcessReservation)
 		mux.Get("/delete-reservation/{src}/{id}/do", handlers.Repo.AdminDeleteReservation)
+		mux.Get("/cancel-reservation/{src}/{id}/do", handlers.Repo.AdminCancelReservation)
+
+		mux.Get("/import", handlers.Repo.AdminImport)
+		mux.Post("/import", handlers.Repo.AdminPostImport)
+
+		mux.Get("/export/reservations", handlers.Repo.AdminExportReservations)
+		mux.Get("/export/guests", handlers.Repo.AdminExportGuests)
+
+		mux.Get("/reports", handlers.Repo.AdminReports)
+		mux.Get("/reports/download", handlers.Repo.AdminDownloadReport)
+
+		mux.Get("/front-desk", handlers.Repo.AdminFrontDesk)
+		mux.Get("/check-in/{id}/do", handlers.Repo.AdminCheckIn)
+		mux.Get("/check-out/{id}/do", handlers.Repo.AdminCheckOut)
+		mux.Get("/no-show/{id}/do", handlers.Repo.AdminMarkNoShow)
+
+		mux.Get("/housekeeping", handlers.Repo.AdminHousekeeping)
+		mux.Post("/housekeeping/{id}", handlers.Repo.AdminPostHousekeeping)
+		mux.Get("/maintenance", handlers.Repo.AdminMaintenance)
+		mux.Get("/maintenance/{id}", handlers.Repo.AdminShowMaintenanceTicket)
+		mux.Post("/maintenance/{id}", handlers.Repo.AdminPostMaintenanceTicket)
+
+		mux.Get("/guests", handlers.Repo.AdminGuests)
+		mux.Get("/guests/{id}", handlers.Repo.AdminShowGuest)
+		mux.Get("/merge-guest/{id}/{duplicate}/do", handlers.Repo.AdminMergeGuest)
+
+		mux.Get("/waitlist", handlers.Repo.AdminWaitlist)
+		mux.Get("/delete-waitlist-entry/{id}/do", handlers.Repo.AdminDeleteWaitlistEntry)
 
 		mux.Get("/reservations/{src}/{id}/show", handlers.Repo.AdminShowReservation)
 		mux.Post("/reservations/{src}/{id}", handlers.Repo.AdminPostShowReservation)
+		mux.Post("/reservations/{src}/{id}/stay", handlers.Repo.AdminPostReservationStay)
+		mux.Post("/reservations/{src}/{id}/notes", handlers.Repo.AdminPostReservationNote)
+		mux.Get("/delete-reservation-note/{src}/{id}/{note}/do", handlers.Repo.AdminDeleteReservationNote)
+		mux.Post("/reservations/{src}/{id}/tags", handlers.Repo.AdminPostReservationTags)
+		mux.Post("/reservations/{src}/{id}/extras", handlers.Repo.AdminPostReservationExtra)
+		mux.Get("/delete-reservation-extra/{src}/{id}/{extra}/do", handlers.Repo.AdminDeleteReservationExtra)
+		mux.Post("/reservations/{src}/{id}/vouchers", handlers.Repo.AdminPostReservationVoucher)
+		mux.Post("/reservations/{src}/{id}/folio", handlers.Repo.AdminPostFolioEntry)
+		mux.Post("/reservations/{src}/{id}/refunds", handlers.Repo.AdminPostRefund)
+		mux.Post("/reservations/{src}/{id}/invoices", handlers.Repo.AdminPostInvoice)
+		mux.Get("/reservations/{src}/{id}/invoices/{number}", handlers.Repo.AdminInvoicePDF)
+		mux.Get("/reservations/{src}/{id}/receipts/{payment}", handlers.Repo.AdminReceiptPDF)
+		mux.Post("/reservations/{src}/{id}/receipts/{payment}", handlers.Repo.AdminPostReceipt)
+
+		mux.Get("/cancellation-policies", handlers.Repo.AdminCancellationPolicies)
+		mux.Post("/cancellation-policies-rooms", handlers.Repo.AdminPostRoomCancellationPolicies)
+		mux.Get("/cancellation-policies/{id}", handlers.Repo.AdminShowCancellationPolicy)
+		mux.Post("/cancellation-policies/{id}", handlers.Repo.AdminPostCancellationPolicy)
+		mux.Get("/delete-cancellation-policy/{id}/do", handlers.Repo.AdminDeleteCancellationPolicy)
+
+		mux.Get("/tax-rules", handlers.Repo.AdminTaxRules)
+		mux.Get("/tax-rules/{id}", handlers.Repo.AdminShowTaxRule)
+		mux.Post("/tax-rules/{id}", handlers.Repo.AdminPostTaxRule)
+		mux.Get("/delete-tax-rule/{id}/do", handlers.Repo.AdminDeleteTaxRule)
+
+		mux.Get("/promo-codes", handlers.Repo.AdminPromoCodes)
+		mux.Get("/promo-codes/{id}", handlers.Repo.AdminShowPromoCode)
+		mux.Post("/promo-codes/{id}", handlers.Repo.AdminPostPromoCode)
+		mux.Get("/delete-promo-code/{id}/do", handlers.Repo.AdminDeletePromoCode)
+
+		mux.Get("/price-rules", handlers.Repo.AdminPriceRules)
+		mux.Get("/price-rules/{id}", handlers.Repo.AdminShowPriceRule)
+		mux.Post("/price-rules/{id}", handlers.Repo.AdminPostPriceRule)
+		mux.Get("/delete-price-rule/{id}/do", handlers.Repo.AdminDeletePriceRule)
+
+		mux.Get("/rate-plans", handlers.Repo.AdminRatePlans)
+		mux.Get("/rate-plans/{id}", handlers.Repo.AdminShowRatePlan)
+		mux.Post("/rate-plans/{id}", handlers.Repo.AdminPostRatePlan)
+		mux.Get("/delete-rate-plan/{id}/do", handlers.Repo.AdminDeleteRatePlan)
+
+		mux.Get("/extras", handlers.Repo.AdminExtras)
+		mux.Get("/extras/{id}", handlers.Repo.AdminShowExtra)
+		mux.Post("/extras/{id}", handlers.Repo.AdminPostExtra)
+		mux.Get("/delete-extra/{id}/do", handlers.Repo.AdminDeleteExtra)
+
+		mux.Get("/gift-vouchers", handlers.Repo.AdminGiftVouchers)
+		mux.Get("/gift-vouchers/{id}", handlers.Repo.AdminShowGiftVoucher)
+		mux.Post("/gift-vouchers/{id}", handlers.Repo.AdminPostGiftVoucher)
+
+		mux.Get("/exchange-rates", handlers.Repo.AdminExchangeRates)
+		mux.Get("/exchange-rates/{id}", handlers.Repo.AdminShowExchangeRate)
+		mux.Post("/exchange-rates/{id}", handlers.Repo.AdminPostExchangeRate)
+		mux.Get("/delete-exchange-rate/{id}/do", handlers.Repo.AdminDeleteExchangeRate)
 	})
 
 	return mux
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/base64"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -48,6 +49,10 @@ func sendMsg(m models.MailData) {
 		email.SetBody(mail.TextHTML, msgToSend)
 	}
 
+	for _, a := range m.Attachments {
+		email.AddAttachmentBase64(base64.StdEncoding.EncodeToString(a.Data), a.Name)
+	}
+
 	err = email.Send(client)
 	if err != nil {
 		log.Println(err)
@@ -5,7 +5,10 @@ import (
 	"log"
 
 	"github.com/alexedwards/scs/v2"
+	"github.com/yalagtyarzh/leafsite/internal/currency"
 	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/payments"
+	"github.com/yalagtyarzh/leafsite/internal/urlsigner"
 )
 
 //AppConfig holds the application config
@@ -17,4 +20,11 @@ type AppConfig struct {
 	InProduction  bool
 	Session       *scs.SessionManager
 	MailChan      chan models.MailData
+	URL           string
+	Signer        *urlsigner.Signer
+	Payments      payments.Provider
+	Deposit       payments.DepositRule
+	Company       models.BillingDetails
+	Currency      string
+	ExchangeRates *currency.Rates
 }
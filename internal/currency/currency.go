@@ -0,0 +1,110 @@
+package currency
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+//Scale is what exchange rates are multiplied by to keep them in whole numbers, rates have six decimals
+const Scale = 1000000
+
+//ValidCode returns true for a currency code of three capital letters, like EUR
+func ValidCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+//ParseRate reads a rate like 1.0825 into millionths
+func ParseRate(s string) (int, error) {
+	whole, fraction, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if whole == "" || len(whole) > 9 || len(fraction) > 6 || strings.Trim(whole+fraction, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+
+	units, err := strconv.Atoi(whole)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+
+	millionths, _ := strconv.Atoi((fraction + "000000")[:6])
+
+	return units*Scale + millionths, nil
+}
+
+//FormatRate formats a rate in millionths as a decimal number without trailing zeros
+func FormatRate(rate int) string {
+	s := fmt.Sprintf("%d.%06d", rate/Scale, rate%Scale)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+//Convert returns an amount in minor units of the base currency in minor units of the currency of a rate,
+//rounded to the nearest unit
+func Convert(amount int, r models.ExchangeRate) int {
+	converted := amount * r.Rate
+	if converted < 0 {
+		return -((-converted + Scale/2) / Scale)
+	}
+
+	return (converted + Scale/2) / Scale
+}
+
+//Rates holds the exchange rates prices can be shown in, for use by concurrent requests. It is loaded from the
+//database on start and again whenever staff change the rates
+type Rates struct {
+	mu    sync.RWMutex
+	rates map[string]models.ExchangeRate
+}
+
+//NewRates creates an empty set of rates
+func NewRates() *Rates {
+	return &Rates{rates: make(map[string]models.ExchangeRate)}
+}
+
+//Set replaces all rates
+func (r *Rates) Set(rates []models.ExchangeRate) {
+	m := make(map[string]models.ExchangeRate, len(rates))
+	for _, x := range rates {
+		m[x.Currency] = x
+	}
+
+	r.mu.Lock()
+	r.rates = m
+	r.mu.Unlock()
+}
+
+//Get returns the rate of a currency, false if there is none
+func (r *Rates) Get(code string) (models.ExchangeRate, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	x, ok := r.rates[code]
+	return x, ok
+}
+
+//Codes returns the currencies there are rates for, in alphabetical order
+func (r *Rates) Codes() []string {
+	r.mu.RLock()
+	codes := make([]string, 0, len(r.rates))
+	for code := range r.rates {
+		codes = append(codes, code)
+	}
+	r.mu.RUnlock()
+
+	sort.Strings(codes)
+
+	return codes
+}
@@ -0,0 +1,89 @@
+package currency
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestValidCode(t *testing.T) {
+	for code, expected := range map[string]bool{"EUR": true, "usd": false, "EURO": false, "E1R": false, "": false} {
+		if ValidCode(code) != expected {
+			t.Errorf("expected %q to be valid %t", code, expected)
+		}
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	var theTests = []struct {
+		input    string
+		expected int
+		isError  bool
+	}{
+		{"1.0825", 1082500, false},
+		{" 90 ", 90000000, false},
+		{"0.000001", 1, false},
+		{"1.0000001", 0, true},
+		{"-1", 0, true},
+		{"1,08", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range theTests {
+		rate, err := ParseRate(tt.input)
+		if tt.isError != (err != nil) {
+			t.Errorf("failed %q: expected error %t, but got %v", tt.input, tt.isError, err)
+		}
+
+		if rate != tt.expected {
+			t.Errorf("failed %q: expected %d, but got %d", tt.input, tt.expected, rate)
+		}
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	for rate, expected := range map[int]string{1082500: "1.0825", 90000000: "90", 1: "0.000001"} {
+		if got := FormatRate(rate); got != expected {
+			t.Errorf("expected %d as %q, but got %q", rate, expected, got)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	usd := models.ExchangeRate{Currency: "USD", Rate: 1082500}
+
+	var theTests = []struct {
+		amount   int
+		expected int
+	}{
+		{10000, 10825},
+		{1, 1},
+		{-10000, -10825},
+		{0, 0},
+	}
+
+	for _, tt := range theTests {
+		if got := Convert(tt.amount, usd); got != tt.expected {
+			t.Errorf("expected %d to convert to %d, but got %d", tt.amount, tt.expected, got)
+		}
+	}
+}
+
+func TestRates(t *testing.T) {
+	rates := NewRates()
+	rates.Set([]models.ExchangeRate{{Currency: "USD", Rate: 1082500}, {Currency: "GBP", Rate: 855000}})
+
+	if codes := rates.Codes(); !reflect.DeepEqual(codes, []string{"GBP", "USD"}) {
+		t.Errorf("expected GBP and USD, but got %v", codes)
+	}
+
+	if x, ok := rates.Get("USD"); !ok || x.Rate != 1082500 {
+		t.Errorf("expected the USD rate, but got %v", x)
+	}
+
+	rates.Set(nil)
+	if _, ok := rates.Get("USD"); ok {
+		t.Error("expected no rates after replacing them")
+	}
+}
@@ -3,6 +3,7 @@ package forms
 import (
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/asaskevich/govalidator"
@@ -64,3 +65,19 @@ func (f *Form) IsEmail(field string) {
 		f.Errors.Add(field, "Invalid email address")
 	}
 }
+
+//IsInt checks for a whole number within the given bounds
+func (f *Form) IsInt(field string, min, max int) bool {
+	x, err := strconv.Atoi(f.Get(field))
+	if err != nil {
+		f.Errors.Add(field, "This field must be a whole number")
+		return false
+	}
+
+	if x < min || x > max {
+		f.Errors.Add(field, fmt.Sprintf("This field must be between %d and %d", min, max))
+		return false
+	}
+
+	return true
+}
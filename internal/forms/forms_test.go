@@ -133,3 +133,40 @@ func TestForm_IsEmail(t *testing.T) {
 		t.Error("got valid for invalid email address")
 	}
 }
+
+func TestForm_IsInt(t *testing.T) {
+	postedData := url.Values{}
+	form := New(postedData)
+
+	form.IsInt("x", 0, 10)
+	if form.Valid() {
+		t.Error("form shows valid number for non-existent field")
+	}
+
+	postedData = url.Values{}
+	postedData.Add("number", "abc")
+	form = New(postedData)
+
+	form.IsInt("number", 0, 10)
+	if form.Valid() {
+		t.Error("got valid for a value that is not a number")
+	}
+
+	postedData = url.Values{}
+	postedData.Add("number", "11")
+	form = New(postedData)
+
+	form.IsInt("number", 0, 10)
+	if form.Valid() {
+		t.Error("got valid for a number out of bounds")
+	}
+
+	postedData = url.Values{}
+	postedData.Add("number", "7")
+	form = New(postedData)
+
+	form.IsInt("number", 0, 10)
+	if !form.Valid() {
+		t.Error("got invalid for a number within bounds")
+	}
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/forms"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/pricing"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//cancellationPolicyFor returns the cancellation policy of a reservation, the one of its rate plan or else of
+//its room, or an empty policy when there is none
+func (m *Repository) cancellationPolicyFor(res models.Reservation) (models.CancellationPolicy, error) {
+	room := res.Room
+	if res.RatePlanID > 0 {
+		plan, err := m.DB.GetRatePlanByID(res.RatePlanID)
+		if err != nil {
+			return models.CancellationPolicy{}, err
+		}
+		room = plan.ApplyTo(room)
+	}
+
+	if room.CancellationPolicyID == 0 {
+		return models.CancellationPolicy{}, nil
+	}
+
+	return m.DB.GetCancellationPolicyByID(room.CancellationPolicyID)
+}
+
+//AdminCancelReservation cancels a reservation, keeping the record and charging the policy fee
+func (m *Repository) AdminCancelReservation(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	src := chi.URLParam(r, "src")
+
+	year := r.URL.Query().Get("y")
+	month := r.URL.Query().Get("m")
+
+	redirectURL := fmt.Sprintf("/admin/reservations-%s", src)
+	if year != "" {
+		redirectURL = fmt.Sprintf("/admin/reservations-calendar?y=%s&m=%s", year, month)
+	}
+
+	res, err := m.DB.GetReservationByID(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if res.Status == models.ReservationCancelled {
+		m.App.Session.Put(r.Context(), "error", "Reservation is already cancelled")
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	policy, err := m.cancellationPolicyFor(res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	fee := pricing.CancellationFee(policy, res, time.Now())
+
+	err = m.DB.CancelReservation(res.ID, fee)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.notifyWaitlist(res.RoomID, res.StartDate, res.EndDate)
+
+	res.Status = models.ReservationCancelled
+	res.CancellationFee = fee
+
+	//whatever was paid beyond the fee is owed back to the guest
+	folio, err := m.folio(res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	credit := 0
+	if folio.Balance() < 0 {
+		credit = -folio.Balance()
+	}
+
+	htmlMessage := fmt.Sprintf(`
+		<strong>Reservation Cancelled</strong><br>
+		Dear %s: <br>
+		Your reservation from %s to %s has been cancelled.<br>
+		Cancellation fee: %s<br>
+		Refund: %s
+	`, res.FirstName, res.StartDate.Format("2006-01-02"), res.EndDate.Format("2006-01-02"),
+		render.Money(fee), render.Money(credit))
+
+	m.App.MailChan <- models.MailData{
+		To:       res.Email,
+		From:     "me@here.com",
+		Subject:  "Reservation Cancelled",
+		Content:  htmlMessage,
+		Template: "basic.html",
+	}
+
+	htmlMessage = fmt.Sprintf(`
+		<strong>Cancellation Notification</strong><br>
+		Reservation %d for %s from %s to %s has been cancelled with a fee of %s.
+	`, res.ID, res.Room.RoomName, res.StartDate.Format("2006-01-02"), res.EndDate.Format("2006-01-02"),
+		render.Money(fee))
+
+	m.App.MailChan <- models.MailData{
+		To:       "me@here.com",
+		From:     "me@here.com",
+		Subject:  "Cancellation Notification",
+		Content:  htmlMessage,
+		Template: "basic.html",
+	}
+
+	if credit > 0 {
+		m.App.Session.Put(r.Context(), "flash",
+			fmt.Sprintf("Reservation cancelled, refund %s from its folio", render.Money(credit)))
+	} else {
+		m.App.Session.Put(r.Context(), "flash", "Reservation cancelled")
+	}
+
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+}
+
+//AdminCancellationPolicies shows all cancellation policies and the policy of every room
+func (m *Repository) AdminCancellationPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := m.DB.AllCancellationPolicies()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	rooms, err := m.DB.AllRooms()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["policies"] = policies
+	data["rooms"] = rooms
+
+	render.Template(w, r, "admin-cancellation-policies.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+//AdminShowCancellationPolicy shows the form for a new or existing cancellation policy
+func (m *Repository) AdminShowCancellationPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	policy := models.CancellationPolicy{
+		FeeType: models.CancellationFeePercent,
+	}
+
+	if id > 0 {
+		policy, err = m.DB.GetCancellationPolicyByID(id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	data := make(map[string]interface{})
+	data["policy"] = policy
+
+	render.Template(w, r, "admin-cancellation-policy-show.page.tmpl", &models.TemplateData{
+		Data: data,
+		Form: forms.New(nil),
+	})
+}
+
+//AdminPostCancellationPolicy handles the posting of a cancellation policy form
+func (m *Repository) AdminPostCancellationPolicy(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("name", "free_days_before", "fee_type")
+	form.IsInt("free_days_before", 0, 365)
+	form.IsInt("fee_percent", 0, 100)
+
+	feeType := r.Form.Get("fee_type")
+	if feeType != models.CancellationFeePercent && feeType != models.CancellationFeeFirstNight {
+		form.Errors.Add("fee_type", "Unknown fee type")
+	}
+
+	freeDaysBefore, _ := strconv.Atoi(r.Form.Get("free_days_before"))
+	feePercent, _ := strconv.Atoi(r.Form.Get("fee_percent"))
+
+	policy := models.CancellationPolicy{
+		ID:             id,
+		Name:           r.Form.Get("name"),
+		FreeDaysBefore: freeDaysBefore,
+		FeeType:        feeType,
+		FeePercent:     feePercent,
+	}
+
+	if !form.Valid() {
+		data := make(map[string]interface{})
+		data["policy"] = policy
+
+		render.Template(w, r, "admin-cancellation-policy-show.page.tmpl", &models.TemplateData{
+			Data: data,
+			Form: form,
+		})
+		return
+	}
+
+	if id > 0 {
+		err = m.DB.UpdateCancellationPolicy(policy)
+	} else {
+		_, err = m.DB.InsertCancellationPolicy(policy)
+	}
+
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/cancellation-policies", http.StatusSeeOther)
+}
+
+//AdminDeleteCancellationPolicy deletes a cancellation policy
+func (m *Repository) AdminDeleteCancellationPolicy(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeleteCancellationPolicy(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Cancellation policy deleted")
+	http.Redirect(w, r, "/admin/cancellation-policies", http.StatusSeeOther)
+}
+
+//AdminPostRoomCancellationPolicies saves the cancellation policy chosen for every room
+func (m *Repository) AdminPostRoomCancellationPolicies(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	rooms, err := m.DB.AllRooms()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	for _, x := range rooms {
+		policyID, err := strconv.Atoi(r.Form.Get(fmt.Sprintf("policy_%d", x.ID)))
+		if err != nil {
+			continue
+		}
+
+		err = m.DB.UpdateCancellationPolicyForRoom(x.ID, policyID)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/cancellation-policies", http.StatusSeeOther)
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRepository_AdminCancelReservation(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		queryParams        string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name:               "cancel-from-all",
+			id:                 "1",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations-all",
+		},
+		{
+			name:               "cancel-back-to-cal",
+			id:                 "1",
+			queryParams:        "?y=2021&m=12",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations-calendar?y=2021&m=12",
+		},
+		{
+			name:               "already-cancelled",
+			id:                 "100",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations-all",
+		},
+		{
+			name:               "cancel-error",
+			id:                 "1000",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "reservation-not-found",
+			id:                 "1001",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/cancel-reservation/all/"+tt.id+"/do"+tt.queryParams, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "all", "id": tt.id})
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminCancelReservation)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
+
+func TestRepository_AdminCancellationPolicies(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+	}{
+		{
+			name:               "policies",
+			url:                "/admin/cancellation-policies",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "new-policy",
+			url:                "/admin/cancellation-policies/0",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "existing-policy",
+			url:                "/admin/cancellation-policies/1",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "missing-policy",
+			url:                "/admin/cancellation-policies/101",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "invalid-policy-id",
+			url:                "/admin/cancellation-policies/invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "delete-policy",
+			url:                "/admin/delete-cancellation-policy/1/do",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	routes := getRoutes()
+	ts := httptest.NewServer(routes)
+	defer ts.Close()
+
+	for _, tt := range theTests {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected %d but got %d", tt.name, tt.expectedStatusCode, resp.StatusCode)
+		}
+	}
+}
+
+func TestRepository_AdminPostCancellationPolicy(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name: "valid-new-policy",
+			id:   "0",
+			postedData: url.Values{
+				"name":             {"Strict"},
+				"free_days_before": {"14"},
+				"fee_type":         {"percent"},
+				"fee_percent":      {"50"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/cancellation-policies",
+		},
+		{
+			name: "valid-existing-policy",
+			id:   "1",
+			postedData: url.Values{
+				"name":             {"Flexible"},
+				"free_days_before": {"2"},
+				"fee_type":         {"first_night"},
+				"fee_percent":      {"0"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/cancellation-policies",
+		},
+		{
+			name: "invalid-fee-type",
+			id:   "0",
+			postedData: url.Values{
+				"name":             {"Strict"},
+				"free_days_before": {"14"},
+				"fee_type":         {"everything"},
+				"fee_percent":      {"50"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-percentage",
+			id:   "0",
+			postedData: url.Values{
+				"name":             {"Strict"},
+				"free_days_before": {"14"},
+				"fee_type":         {"percent"},
+				"fee_percent":      {"150"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "invalid-id",
+			id:                 "invalid",
+			postedData:         url.Values{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/cancellation-policies/"+tt.id, strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostCancellationPolicy)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
+
+func TestRepository_AdminPostRoomCancellationPolicies(t *testing.T) {
+	postedData := url.Values{
+		"policy_1": {"1"},
+		"policy_2": {"0"},
+	}
+
+	req, _ := http.NewRequest("POST", "/admin/cancellation-policies-rooms", strings.NewReader(postedData.Encode()))
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	handler := http.HandlerFunc(Repo.AdminPostRoomCancellationPolicies)
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("expected code %d, but got %d", http.StatusSeeOther, rr.Code)
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/currency"
+	"github.com/yalagtyarzh/leafsite/internal/forms"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//LoadExchangeRates reads the exchange rates of the display currencies into the app config
+func (m *Repository) LoadExchangeRates() error {
+	rates, err := m.DB.ExchangeRates()
+	if err != nil {
+		return err
+	}
+
+	m.App.ExchangeRates.Set(rates)
+
+	return nil
+}
+
+//SetCurrency chooses the currency prices are also shown in for the session, and goes back to the page it was
+//chosen on. Charges are always made in the base currency
+func (m *Repository) SetCurrency(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	code := r.Form.Get("currency")
+	if _, ok := m.App.ExchangeRates.Get(code); ok {
+		m.App.Session.Put(r.Context(), "currency", code)
+	} else {
+		m.App.Session.Remove(r.Context(), "currency")
+	}
+
+	//only the path of the referring page is used, so this can't redirect to another site. A path starting
+	//with // or /\ is read by browsers as another host, so those fall back to the home page
+	back := "/"
+	if ref, err := url.Parse(r.Referer()); err == nil && strings.HasPrefix(ref.Path, "/") &&
+		!strings.HasPrefix(ref.Path, "//") && !strings.HasPrefix(ref.Path, "/\\") {
+		back = ref.Path
+		if ref.RawQuery != "" {
+			back += "?" + ref.RawQuery
+		}
+	}
+
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+//AdminExchangeRates shows the exchange rates of the display currencies
+func (m *Repository) AdminExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := m.DB.ExchangeRates()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["rates"] = rates
+
+	render.Template(w, r, "admin-exchange-rates.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+//renderExchangeRate renders the form of an exchange rate
+func (m *Repository) renderExchangeRate(w http.ResponseWriter, r *http.Request, x models.ExchangeRate,
+	form *forms.Form) {
+	stringMap := make(map[string]string)
+	if x.Rate > 0 {
+		stringMap["rate"] = currency.FormatRate(x.Rate)
+	}
+
+	data := make(map[string]interface{})
+	data["rate"] = x
+
+	render.Template(w, r, "admin-exchange-rate-show.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+		Form:      form,
+	})
+}
+
+//AdminShowExchangeRate shows the form for a new or existing exchange rate
+func (m *Repository) AdminShowExchangeRate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var x models.ExchangeRate
+
+	if id > 0 {
+		x, err = m.DB.GetExchangeRateByID(id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	m.renderExchangeRate(w, r, x, forms.New(nil))
+}
+
+//AdminPostExchangeRate handles the posting of an exchange rate form, the rate is how much of the currency one
+//unit of the base currency buys
+func (m *Repository) AdminPostExchangeRate(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("currency", "rate")
+
+	x := models.ExchangeRate{
+		ID:       id,
+		Currency: strings.ToUpper(strings.TrimSpace(form.Get("currency"))),
+	}
+
+	if !currency.ValidCode(x.Currency) {
+		form.Errors.Add("currency", "Enter a three letter currency code, like EUR")
+	} else if x.Currency == m.App.Currency {
+		form.Errors.Add("currency", "Prices are already in "+m.App.Currency)
+	}
+
+	x.Rate, err = currency.ParseRate(form.Get("rate"))
+	if err != nil || x.Rate == 0 {
+		form.Errors.Add("rate", "Invalid rate")
+	}
+
+	if !form.Valid() {
+		m.renderExchangeRate(w, r, x, form)
+		return
+	}
+
+	if id > 0 {
+		err = m.DB.UpdateExchangeRate(x)
+	} else {
+		_, err = m.DB.InsertExchangeRate(x)
+	}
+
+	if err == nil {
+		err = m.LoadExchangeRates()
+	}
+
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/exchange-rates", http.StatusSeeOther)
+}
+
+//AdminDeleteExchangeRate deletes an exchange rate, prices are no longer shown in its currency
+func (m *Repository) AdminDeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeleteExchangeRate(id)
+	if err == nil {
+		err = m.LoadExchangeRates()
+	}
+
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Exchange rate deleted")
+	http.Redirect(w, r, "/admin/exchange-rates", http.StatusSeeOther)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRepository_SetCurrency(t *testing.T) {
+	var theTests = []struct {
+		name             string
+		currency         string
+		referer          string
+		expectedCurrency string
+		expectedLocation string
+	}{
+		{"known-currency", "EUR", "http://localhost:8080/choose-room/1?plan=2", "EUR", "/choose-room/1?plan=2"},
+		{"base-currency", "", "http://localhost:8080/make-reservation", "", "/make-reservation"},
+		{"unknown-currency", "JPY", "", "", "/"},
+		{"other-site", "GBP", "http://evil.example.com", "GBP", "/"},
+		{"protocol-relative", "GBP", "http://localhost:8080//evil.example", "GBP", "/"},
+		{"backslash", "GBP", "http://localhost:8080/\\evil.example", "GBP", "/"},
+	}
+
+	for _, tt := range theTests {
+		postedData := url.Values{"currency": {tt.currency}}
+		req, _ := http.NewRequest("POST", "/currency", strings.NewReader(postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if tt.referer != "" {
+			req.Header.Set("Referer", tt.referer)
+		}
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.SetCurrency)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusSeeOther {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, http.StatusSeeOther, rr.Code)
+		}
+
+		actualLoc, _ := rr.Result().Location()
+		if actualLoc.String() != tt.expectedLocation {
+			t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+		}
+
+		if c := session.GetString(ctx, "currency"); c != tt.expectedCurrency {
+			t.Errorf("failed %s: expected currency %q, but got %q", tt.name, tt.expectedCurrency, c)
+		}
+	}
+}
+
+func TestRepository_AdminExchangeRates(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{"rates", "/admin/exchange-rates", http.StatusOK, "0.92"},
+		{"new-rate", "/admin/exchange-rates/0", http.StatusOK, "one USD buys"},
+		{"existing-rate", "/admin/exchange-rates/1", http.StatusOK, "EUR"},
+		{"missing-rate", "/admin/exchange-rates/101", http.StatusInternalServerError, ""},
+		{"invalid-rate-id", "/admin/exchange-rates/invalid", http.StatusBadRequest, ""},
+		{"delete-rate", "/admin/delete-exchange-rate/1/do", http.StatusOK, ""},
+		{"delete-rate-error", "/admin/delete-exchange-rate/101/do", http.StatusInternalServerError, ""},
+	}
+
+	routes := getRoutes()
+	ts := httptest.NewServer(routes)
+	defer ts.Close()
+
+	for _, tt := range theTests {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected %d but got %d", tt.name, tt.expectedStatusCode, resp.StatusCode)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if tt.expectedBody != "" && !strings.Contains(string(body), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+	}
+}
+
+func TestRepository_AdminPostExchangeRate(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{"valid-new-rate", "0", url.Values{"currency": {"chf"}, "rate": {"0.8851"}}, http.StatusSeeOther, "/admin/exchange-rates"},
+		{"valid-existing-rate", "1", url.Values{"currency": {"EUR"}, "rate": {"0.93"}}, http.StatusSeeOther, "/admin/exchange-rates"},
+		{"missing-fields", "0", url.Values{}, http.StatusOK, ""},
+		{"invalid-currency", "0", url.Values{"currency": {"EURO"}, "rate": {"0.93"}}, http.StatusOK, ""},
+		{"base-currency", "0", url.Values{"currency": {"USD"}, "rate": {"1"}}, http.StatusOK, ""},
+		{"invalid-rate", "0", url.Values{"currency": {"EUR"}, "rate": {"0.9.3"}}, http.StatusOK, ""},
+		{"zero-rate", "0", url.Values{"currency": {"EUR"}, "rate": {"0"}}, http.StatusOK, ""},
+		{"database-error", "0", url.Values{"currency": {"XXX"}, "rate": {"1"}}, http.StatusInternalServerError, ""},
+		{"invalid-id", "invalid", url.Values{}, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/exchange-rates/"+tt.id, strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostExchangeRate)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//AdminDashboard shows today's front desk figures, the occupancy ahead and the booking trend
+func (m *Repository) AdminDashboard(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	counts, err := m.DB.DashboardCounts(today)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	rooms, err := m.DB.AllRooms()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	occupied, err := m.DB.OccupiedRoomsByNight(today, today.AddDate(0, 0, 90))
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	bookings, err := m.DB.ReservationsCreatedByDay(today.AddDate(0, 0, -29), today.AddDate(0, 0, 1))
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	next30 := occupied
+	if len(next30) > 30 {
+		next30 = next30[:30]
+	}
+
+	intMap := make(map[string]int)
+	intMap["occupancy_30"] = occupancyPercent(next30, len(rooms))
+	intMap["occupancy_90"] = occupancyPercent(occupied, len(rooms))
+
+	var occupancyLabels []string
+	var occupancyValues []int
+	for _, x := range occupied {
+		occupancyLabels = append(occupancyLabels, x.Date.Format("Jan 2"))
+		occupancyValues = append(occupancyValues, occupancyPercent([]models.DailyCount{x}, len(rooms)))
+	}
+
+	var bookingLabels []string
+	var bookingValues []int
+	for _, x := range bookings {
+		bookingLabels = append(bookingLabels, x.Date.Format("Jan 2"))
+		bookingValues = append(bookingValues, x.Count)
+	}
+
+	data := make(map[string]interface{})
+	data["counts"] = counts
+	data["occupancy_labels"] = occupancyLabels
+	data["occupancy_values"] = occupancyValues
+	data["booking_labels"] = bookingLabels
+	data["booking_values"] = bookingValues
+
+	render.Template(w, r, "admin-dashboard.page.tmpl", &models.TemplateData{
+		IntMap: intMap,
+		Data:   data,
+	})
+}
+
+//occupancyPercent returns the share of room nights taken in percent, rounded down
+func occupancyPercent(nights []models.DailyCount, rooms int) int {
+	if rooms == 0 || len(nights) == 0 {
+		return 0
+	}
+
+	var taken int
+	for _, x := range nights {
+		taken += x.Count
+	}
+
+	return taken * 100 / (rooms * len(nights))
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestOccupancyPercent(t *testing.T) {
+	nights := []models.DailyCount{{Count: 2}, {Count: 1}, {Count: 0}, {Count: 2}}
+
+	var theTests = []struct {
+		name     string
+		nights   []models.DailyCount
+		rooms    int
+		expected int
+	}{
+		{"half", nights, 2, 62},
+		{"full", nights[:1], 2, 100},
+		{"empty", nights[2:3], 2, 0},
+		{"no-rooms", nights, 0, 0},
+		{"no-nights", nil, 2, 0},
+	}
+
+	for _, tt := range theTests {
+		if got := occupancyPercent(tt.nights, tt.rooms); got != tt.expected {
+			t.Errorf("failed %s: expected %d, but got %d", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//exportFlushRows is how many CSV rows are buffered before they are sent to the client
+const exportFlushRows = 100
+
+//exportRow is a row of an export, encoded by its json tags or as a CSV record
+type exportRow interface {
+	csvRecord() []string
+}
+
+//exportWriter streams rows to the client as CSV or as a JSON array. Nothing is sent before the first row,
+//so that a failing query can still be answered with an error page
+type exportWriter struct {
+	w        http.ResponseWriter
+	format   string
+	filename string
+	header   []string
+	csv      *csv.Writer
+	rows     int
+}
+
+//newExportWriter returns a writer for the format, or false if the format is unknown
+func newExportWriter(w http.ResponseWriter, format, name string, header []string) (*exportWriter, bool) {
+	if format != "csv" && format != "json" {
+		return nil, false
+	}
+
+	return &exportWriter{
+		w:        w,
+		format:   format,
+		filename: fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102"), format),
+		header:   header,
+	}, true
+}
+
+func (e *exportWriter) start() error {
+	e.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.filename))
+
+	if e.format == "json" {
+		e.w.Header().Set("Content-Type", "application/json")
+		_, err := e.w.Write([]byte("["))
+		return err
+	}
+
+	e.w.Header().Set("Content-Type", "text/csv")
+	e.csv = csv.NewWriter(e.w)
+	return e.csv.Write(e.header)
+}
+
+//write sends one row
+func (e *exportWriter) write(row exportRow) error {
+	if e.rows == 0 {
+		err := e.start()
+		if err != nil {
+			return err
+		}
+	}
+	e.rows++
+
+	if e.format == "json" {
+		out, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+
+		if e.rows > 1 {
+			out = append([]byte(","), out...)
+		}
+
+		_, err = e.w.Write(out)
+		return err
+	}
+
+	err := e.csv.Write(row.csvRecord())
+	if err != nil {
+		return err
+	}
+
+	if e.rows%exportFlushRows == 0 {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+
+	return nil
+}
+
+//finish completes the export, which is empty if no row was written
+func (e *exportWriter) finish() error {
+	if e.rows == 0 {
+		err := e.start()
+		if err != nil {
+			return err
+		}
+	}
+
+	if e.format == "json" {
+		_, err := e.w.Write([]byte("]"))
+		return err
+	}
+
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+//csvCell returns a value typed in by a guest or staff as a CSV cell. Spreadsheets run a cell starting with
+//=, +, -, @, a tab or a carriage return as a formula, so those are prefixed with a quote to be read as text
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+
+	return s
+}
+
+//reservationExport is a reservation as exported, money is in minor units in JSON
+type reservationExport struct {
+	ID         int    `json:"id"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Email      string `json:"email"`
+	Phone      string `json:"phone"`
+	Room       string `json:"room"`
+	StartDate  string `json:"start_date"`
+	EndDate    string `json:"end_date"`
+	Status     string `json:"status"`
+	TotalPrice int    `json:"total_price"`
+	Processed  bool   `json:"processed"`
+	CreatedAt  string `json:"created_at"`
+}
+
+var reservationExportHeader = []string{
+	"id", "first_name", "last_name", "email", "phone", "room", "start_date", "end_date", "status",
+	"total_price", "processed", "created_at",
+}
+
+func (x reservationExport) csvRecord() []string {
+	return []string{
+		strconv.Itoa(x.ID),
+		csvCell(x.FirstName),
+		csvCell(x.LastName),
+		csvCell(x.Email),
+		csvCell(x.Phone),
+		csvCell(x.Room),
+		x.StartDate,
+		x.EndDate,
+		x.Status,
+		render.Money(x.TotalPrice),
+		strconv.FormatBool(x.Processed),
+		x.CreatedAt,
+	}
+}
+
+//guestExport is a guest contact as exported
+type guestExport struct {
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	Reservations int    `json:"reservations"`
+	LastStay     string `json:"last_stay"`
+}
+
+var guestExportHeader = []string{"first_name", "last_name", "email", "phone", "reservations", "last_stay"}
+
+func (x guestExport) csvRecord() []string {
+	return []string{
+		csvCell(x.FirstName),
+		csvCell(x.LastName),
+		csvCell(x.Email),
+		csvCell(x.Phone),
+		strconv.Itoa(x.Reservations),
+		x.LastStay,
+	}
+}
+
+//reservationFilter reads a reservation filter from the query
+func reservationFilter(r *http.Request) (models.ReservationFilter, error) {
+	var f models.ReservationFilter
+	var err error
+
+	layout := "2006-01-02"
+
+	if s := r.URL.Query().Get("start"); s != "" {
+		f.Start, err = time.Parse(layout, s)
+		if err != nil {
+			return f, err
+		}
+	}
+
+	if e := r.URL.Query().Get("end"); e != "" {
+		f.End, err = time.Parse(layout, e)
+		if err != nil {
+			return f, err
+		}
+	}
+
+	if id := r.URL.Query().Get("room_id"); id != "" && id != "0" {
+		f.RoomID, err = strconv.Atoi(id)
+		if err != nil {
+			return f, err
+		}
+	}
+
+	f.Status = r.URL.Query().Get("status")
+	switch f.Status {
+	case "", models.ReservationConfirmed, models.ReservationCancelled, models.ReservationNoShow:
+	default:
+		return f, fmt.Errorf("unknown status %s", f.Status)
+	}
+
+	switch p := r.URL.Query().Get("processed"); p {
+	case "":
+	case "new":
+		f.Processed = models.ProcessedNew
+	case "done":
+		f.Processed = models.ProcessedDone
+	default:
+		return f, fmt.Errorf("unknown processed filter %s", p)
+	}
+
+	f.Search = strings.TrimSpace(r.URL.Query().Get("q"))
+	f.Tag = r.URL.Query().Get("tag")
+
+	return f, nil
+}
+
+//AdminExportReservations streams the reservations matching the filter of the query as CSV or JSON
+func (m *Repository) AdminExportReservations(w http.ResponseWriter, r *http.Request) {
+	f, err := reservationFilter(r)
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	ew, ok := newExportWriter(w, r.URL.Query().Get("format"), "reservations", reservationExportHeader)
+	if !ok {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = m.DB.EachReservation(f, func(res models.Reservation) error {
+		return ew.write(reservationExport{
+			ID:         res.ID,
+			FirstName:  res.FirstName,
+			LastName:   res.LastName,
+			Email:      res.Email,
+			Phone:      res.Phone,
+			Room:       res.Room.RoomName,
+			StartDate:  res.StartDate.Format("2006-01-02"),
+			EndDate:    res.EndDate.Format("2006-01-02"),
+			Status:     res.Status,
+			TotalPrice: res.TotalPrice,
+			Processed:  res.Processed == 1,
+			CreatedAt:  res.CreatedAt.Format(time.RFC3339),
+		})
+	})
+	m.finishExport(w, ew, err)
+}
+
+//AdminExportGuests streams the contact details of all guests as CSV or JSON
+func (m *Repository) AdminExportGuests(w http.ResponseWriter, r *http.Request) {
+	ew, ok := newExportWriter(w, r.URL.Query().Get("format"), "guests", guestExportHeader)
+	if !ok {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err := m.DB.EachGuestContact(func(g models.GuestContact) error {
+		return ew.write(guestExport{
+			FirstName:    g.FirstName,
+			LastName:     g.LastName,
+			Email:        g.Email,
+			Phone:        g.Phone,
+			Reservations: g.Reservations,
+			LastStay:     g.LastStay.Format("2006-01-02"),
+		})
+	})
+	m.finishExport(w, ew, err)
+}
+
+//finishExport completes an export, answering with an error page if the query failed before any row was sent.
+//Errors after that can only be logged, since the response is already under way
+func (m *Repository) finishExport(w http.ResponseWriter, ew *exportWriter, err error) {
+	if err != nil && ew.rows == 0 {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+
+	err = ew.finish()
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRepository_AdminExportReservations(t *testing.T) {
+	var theTests = []struct {
+		name                string
+		queryParams         string
+		expectedStatusCode  int
+		expectedContentType string
+		expectedRows        int
+	}{
+		{"csv", "?format=csv", http.StatusOK, "text/csv", 2},
+		{"json", "?format=json", http.StatusOK, "application/json", 2},
+		{"filtered", "?format=csv&start=2050-01-01&end=2050-02-01&room_id=1&status=confirmed", http.StatusOK, "text/csv", 2},
+		{"unknown-format", "?format=xml", http.StatusBadRequest, "", 0},
+		{"invalid-date", "?format=csv&start=invalid", http.StatusBadRequest, "", 0},
+		{"invalid-room", "?format=csv&room_id=invalid", http.StatusBadRequest, "", 0},
+		{"unknown-status", "?format=csv&status=invalid", http.StatusBadRequest, "", 0},
+		{"searched", "?format=csv&processed=new&q=smith", http.StatusOK, "text/csv", 2},
+		{"unknown-processed", "?format=csv&processed=invalid", http.StatusBadRequest, "", 0},
+		{"query-error", "?format=csv&room_id=3", http.StatusInternalServerError, "", 0},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/export/reservations"+tt.queryParams, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminExportReservations)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedContentType == "" {
+			continue
+		}
+
+		if rr.Header().Get("Content-Type") != tt.expectedContentType {
+			t.Errorf("failed %s: expected content type %s, but got %s", tt.name, tt.expectedContentType, rr.Header().Get("Content-Type"))
+		}
+
+		var rows int
+		if tt.expectedContentType == "application/json" {
+			var out []reservationExport
+			err := json.Unmarshal(rr.Body.Bytes(), &out)
+			if err != nil {
+				t.Errorf("failed %s: invalid JSON %s", tt.name, rr.Body.String())
+			}
+			rows = len(out)
+		} else {
+			rows = len(strings.Split(strings.TrimSpace(rr.Body.String()), "\n")) - 1
+		}
+
+		if rows != tt.expectedRows {
+			t.Errorf("failed %s: expected %d rows, but got %d", tt.name, tt.expectedRows, rows)
+		}
+	}
+}
+
+func TestRepository_AdminExportGuests(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		format             string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{"csv", "csv", http.StatusOK, "first_name,last_name,email,phone,reservations,last_stay\nJohn,Smith,john@smith.com,,2,"},
+		{"json", "json", http.StatusOK, `[{"first_name":"John","last_name":"Smith","email":"john@smith.com"`},
+		{"unknown-format", "xml", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/export/guests?format="+tt.format, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminExportGuests)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if !strings.HasPrefix(rr.Body.String(), tt.expectedBody) {
+			t.Errorf("failed %s: expected body to start with %s, but got %s", tt.name, tt.expectedBody, rr.Body.String())
+		}
+	}
+}
+
+func TestCSVCell(t *testing.T) {
+	var theTests = []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"plain", "John", "John"},
+		{"empty", "", ""},
+		{"formula", "=HYPERLINK(\"http://evil.example\")", "'=HYPERLINK(\"http://evil.example\")"},
+		{"plus", "+1 555 0100", "'+1 555 0100"},
+		{"minus", "-2+3", "'-2+3"},
+		{"at", "@SUM(A1)", "'@SUM(A1)"},
+		{"tab", "\t=1", "'\t=1"},
+		{"carriage-return", "\r=1", "'\r=1"},
+		{"inside", "Smith=1", "Smith=1"},
+	}
+
+	for _, tt := range theTests {
+		if got := csvCell(tt.value); got != tt.expected {
+			t.Errorf("failed %s: expected %q, but got %q", tt.name, tt.expected, got)
+		}
+	}
+
+	record := guestExport{FirstName: "=cmd", LastName: "Smith", Email: "@x", Phone: "+1"}.csvRecord()
+	if record[0] != "'=cmd" || record[1] != "Smith" || record[2] != "'@x" || record[3] != "'+1" {
+		t.Errorf("guest fields not escaped: %q", record)
+	}
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/forms"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/pricing"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+	"github.com/yalagtyarzh/leafsite/internal/repository"
+)
+
+//maxExtraUnits is the most units of one extra that can be booked with a reservation
+const maxExtraUnits = 20
+
+//bookExtras sets the extras chosen on a reservation form, where the quantity of every extra of the catalog
+//is posted as extra_<id>. Invalid quantities are reported as form errors
+func bookExtras(form *forms.Form, res *models.Reservation, catalog []models.Extra) {
+	res.Extras = nil
+
+	for _, e := range catalog {
+		field := fmt.Sprintf("extra_%d", e.ID)
+		if form.Get(field) == "" || !form.IsInt(field, 0, maxExtraUnits) {
+			continue
+		}
+
+		quantity, _ := strconv.Atoi(form.Get(field))
+		if quantity == 0 {
+			continue
+		}
+
+		res.Extras = append(res.Extras, models.ReservationExtra{
+			ExtraID:  e.ID,
+			Name:     e.Name,
+			Quantity: quantity,
+			Amount:   pricing.ExtraPrice(e, *res, quantity),
+		})
+	}
+}
+
+//repriceExtras prices the extras booked with a reservation again for its current stay, after its dates or
+//guests change. Extras deleted from the catalog keep what they were booked for
+func repriceExtras(res *models.Reservation, catalog []models.Extra) {
+	byID := make(map[int]models.Extra, len(catalog))
+	for _, e := range catalog {
+		byID[e.ID] = e
+	}
+
+	for i, x := range res.Extras {
+		if e, ok := byID[x.ExtraID]; ok {
+			res.Extras[i].Amount = pricing.ExtraPrice(e, *res, x.Quantity)
+		}
+	}
+}
+
+//extraQuantities returns the units of every extra booked with a reservation, by extra id
+func extraQuantities(res models.Reservation) map[int]int {
+	quantities := make(map[int]int)
+	for _, x := range res.Extras {
+		quantities[x.ExtraID] += x.Quantity
+	}
+
+	return quantities
+}
+
+//AdminExtras shows the catalog of extras
+func (m *Repository) AdminExtras(w http.ResponseWriter, r *http.Request) {
+	extras, err := m.DB.Extras()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["extras"] = extras
+
+	render.Template(w, r, "admin-extras.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+//renderExtra renders the form of an extra
+func (m *Repository) renderExtra(w http.ResponseWriter, r *http.Request, e models.Extra, form *forms.Form) {
+	stringMap := make(map[string]string)
+	stringMap["price"] = render.Money(e.Price)
+
+	data := make(map[string]interface{})
+	data["extra"] = e
+	data["kinds"] = models.ExtraKinds
+
+	render.Template(w, r, "admin-extra-show.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+		Form:      form,
+	})
+}
+
+//AdminShowExtra shows the form for a new or existing extra
+func (m *Repository) AdminShowExtra(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	e := models.Extra{
+		Kind: models.ExtraPerStay,
+	}
+
+	if id > 0 {
+		e, err = m.DB.GetExtraByID(id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	m.renderExtra(w, r, e, forms.New(nil))
+}
+
+//AdminPostExtra handles the posting of an extra form
+func (m *Repository) AdminPostExtra(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("name", "kind", "price")
+	if form.Get("inventory") != "" {
+		form.IsInt("inventory", 0, 100000)
+	}
+
+	inventory, _ := strconv.Atoi(form.Get("inventory"))
+
+	e := models.Extra{
+		ID:          id,
+		Name:        strings.TrimSpace(form.Get("name")),
+		Description: strings.TrimSpace(form.Get("description")),
+		Kind:        form.Get("kind"),
+		Inventory:   inventory,
+	}
+
+	if !isOneOf(e.Kind, models.ExtraKinds) {
+		form.Errors.Add("kind", "Unknown kind of price")
+	}
+
+	e.Price, err = parseMoney(form.Get("price"))
+	if err != nil {
+		form.Errors.Add("price", "Invalid price")
+	}
+
+	if !form.Valid() {
+		m.renderExtra(w, r, e, form)
+		return
+	}
+
+	if id > 0 {
+		err = m.DB.UpdateExtra(e)
+	} else {
+		_, err = m.DB.InsertExtra(e)
+	}
+
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/extras", http.StatusSeeOther)
+}
+
+//AdminDeleteExtra deletes an extra, reservations keep the extras they booked
+func (m *Repository) AdminDeleteExtra(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeleteExtra(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Extra deleted")
+	http.Redirect(w, r, "/admin/extras", http.StatusSeeOther)
+}
+
+//AdminPostReservationExtra books an extra with a reservation, adding it to the total
+func (m *Repository) AdminPostReservationExtra(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	showURL := showReservationURL(chi.URLParam(r, "src"), id, r.Form.Get("year"), r.Form.Get("month"))
+
+	extraID, err := strconv.Atoi(r.Form.Get("extra_id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	quantity, err := strconv.Atoi(r.Form.Get("quantity"))
+	if err != nil || quantity < 1 || quantity > maxExtraUnits {
+		m.App.Session.Put(r.Context(), "error", "Invalid quantity")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	res, err := m.DB.GetReservationByID(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if res.Status == models.ReservationCancelled {
+		m.App.Session.Put(r.Context(), "error", "Reservation is cancelled")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	e, err := m.DB.GetExtraByID(extraID)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	err = m.DB.AddReservationExtra(id, models.ReservationExtra{
+		ExtraID:  e.ID,
+		Name:     e.Name,
+		Quantity: quantity,
+		Amount:   pricing.ExtraPrice(e, res, quantity),
+	})
+	if errors.Is(err, repository.ErrExtraSoldOut) {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Not enough %s left for these nights", e.Name))
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Extra added")
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
+
+//AdminDeleteReservationExtra removes an extra from a reservation, taking it off the total
+func (m *Repository) AdminDeleteReservationExtra(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	extraID, err := strconv.Atoi(chi.URLParam(r, "extra"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = m.DB.DeleteReservationExtra(extraID, id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Extra removed")
+	showURL := showReservationURL(chi.URLParam(r, "src"), id, r.URL.Query().Get("y"), r.URL.Query().Get("m"))
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/forms"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestBookExtras(t *testing.T) {
+	catalog := []models.Extra{
+		{ID: 1, Name: "Breakfast", Kind: models.ExtraPerGuestNight, Price: 1500},
+		{ID: 2, Name: "Parking", Kind: models.ExtraPerNight, Price: 1000, Inventory: 5},
+		{ID: 3, Name: "Flowers", Kind: models.ExtraPerStay, Price: 3000},
+	}
+
+	res := models.Reservation{
+		StartDate: time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2050, 1, 3, 0, 0, 0, 0, time.UTC),
+		Guests:    2,
+		Extras:    []models.ReservationExtra{{ExtraID: 3, Name: "Flowers", Quantity: 1, Amount: 3000}},
+	}
+
+	form := forms.New(url.Values{"extra_1": {"1"}, "extra_2": {"0"}})
+	bookExtras(form, &res, catalog)
+
+	if !form.Valid() {
+		t.Fatalf("expected a valid form, but got %v", form.Errors)
+	}
+
+	if len(res.Extras) != 1 || res.Extras[0].Name != "Breakfast" || res.Extras[0].Amount != 6000 {
+		t.Errorf("expected only breakfast for 2 guests and 2 nights, but got %v", res.Extras)
+	}
+
+	form = forms.New(url.Values{"extra_1": {"x"}, "extra_2": {"21"}})
+	bookExtras(form, &res, catalog)
+
+	if form.Errors.Get("extra_1") == "" || form.Errors.Get("extra_2") == "" {
+		t.Errorf("expected errors for invalid quantities, but got %v", form.Errors)
+	}
+
+	if len(res.Extras) != 0 {
+		t.Errorf("expected no extras, but got %v", res.Extras)
+	}
+}
+
+func TestRepriceExtras(t *testing.T) {
+	catalog := []models.Extra{
+		{ID: 1, Name: "Breakfast", Kind: models.ExtraPerGuestNight, Price: 1500},
+		{ID: 3, Name: "Flowers", Kind: models.ExtraPerStay, Price: 3000},
+	}
+
+	res := models.Reservation{
+		StartDate: time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2050, 1, 4, 0, 0, 0, 0, time.UTC),
+		Guests:    2,
+		Extras: []models.ReservationExtra{
+			{ExtraID: 1, Name: "Breakfast", Quantity: 1, Amount: 6000},
+			{ExtraID: 3, Name: "Flowers", Quantity: 1, Amount: 3000},
+			{Name: "Late checkout", Quantity: 1, Amount: 2000},
+		},
+	}
+
+	repriceExtras(&res, catalog)
+
+	if res.Extras[0].Amount != 9000 {
+		t.Errorf("expected breakfast for 2 guests and 3 nights to cost 9000, but got %d", res.Extras[0].Amount)
+	}
+
+	if res.Extras[1].Amount != 3000 || res.Extras[2].Amount != 2000 {
+		t.Errorf("expected the other extras to keep their amounts, but got %v", res.Extras[1:])
+	}
+}
+
+func TestRepository_AdminExtras(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+	}{
+		{
+			name:               "extras",
+			url:                "/admin/extras",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "new-extra",
+			url:                "/admin/extras/0",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "existing-extra",
+			url:                "/admin/extras/1",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "missing-extra",
+			url:                "/admin/extras/101",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "invalid-extra-id",
+			url:                "/admin/extras/invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "delete-extra",
+			url:                "/admin/delete-extra/1/do",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "delete-extra-error",
+			url:                "/admin/delete-extra/101/do",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	routes := getRoutes()
+	ts := httptest.NewServer(routes)
+	defer ts.Close()
+
+	for _, tt := range theTests {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected %d but got %d", tt.name, tt.expectedStatusCode, resp.StatusCode)
+		}
+	}
+}
+
+func TestRepository_AdminPostExtra(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name: "valid-new-extra",
+			id:   "0",
+			postedData: url.Values{
+				"name":        {"Parking"},
+				"description": {"One space in the garage"},
+				"kind":        {models.ExtraPerNight},
+				"price":       {"10"},
+				"inventory":   {"5"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/extras",
+		},
+		{
+			name: "valid-existing-extra",
+			id:   "1",
+			postedData: url.Values{
+				"name":  {"Breakfast"},
+				"kind":  {models.ExtraPerGuestNight},
+				"price": {"15"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/extras",
+		},
+		{
+			name:               "missing-fields",
+			id:                 "0",
+			postedData:         url.Values{},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "unknown-kind",
+			id:   "0",
+			postedData: url.Values{
+				"name":  {"Breakfast"},
+				"kind":  {"per_week"},
+				"price": {"15"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-price",
+			id:   "0",
+			postedData: url.Values{
+				"name":  {"Breakfast"},
+				"kind":  {models.ExtraPerStay},
+				"price": {"cheap"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-inventory",
+			id:   "0",
+			postedData: url.Values{
+				"name":      {"Parking"},
+				"kind":      {models.ExtraPerNight},
+				"price":     {"10"},
+				"inventory": {"-1"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "database-error",
+			id:   "0",
+			postedData: url.Values{
+				"name":  {"error"},
+				"kind":  {models.ExtraPerStay},
+				"price": {"10"},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "invalid-id",
+			id:                 "invalid",
+			postedData:         url.Values{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/extras/"+tt.id, strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostExtra)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
+
+func TestRepository_AdminPostReservationExtra(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+		expectedError      string
+	}{
+		{"valid", "1", url.Values{"extra_id": {"2"}, "quantity": {"1"}}, http.StatusSeeOther, "/admin/reservations/all/1/show", ""},
+		{"from-cal", "1", url.Values{"extra_id": {"2"}, "quantity": {"2"}, "year": {"2050"}, "month": {"01"}}, http.StatusSeeOther, "/admin/reservations/all/1/show?y=2050&m=01", ""},
+		{"sold-out", "1", url.Values{"extra_id": {"2"}, "quantity": {"6"}}, http.StatusSeeOther, "/admin/reservations/all/1/show", "Not enough Parking left for these nights"},
+		{"invalid-quantity", "1", url.Values{"extra_id": {"2"}, "quantity": {"0"}}, http.StatusSeeOther, "/admin/reservations/all/1/show", "Invalid quantity"},
+		{"cancelled", "100", url.Values{"extra_id": {"2"}, "quantity": {"1"}}, http.StatusSeeOther, "/admin/reservations/all/100/show", "Reservation is cancelled"},
+		{"invalid-extra", "1", url.Values{"extra_id": {"x"}, "quantity": {"1"}}, http.StatusBadRequest, "", ""},
+		{"missing-extra", "1", url.Values{"extra_id": {"101"}, "quantity": {"1"}}, http.StatusInternalServerError, "", ""},
+		{"invalid-id", "x", url.Values{"extra_id": {"2"}, "quantity": {"1"}}, http.StatusBadRequest, "", ""},
+		{"database-error", "1000", url.Values{"extra_id": {"2"}, "quantity": {"1"}}, http.StatusInternalServerError, "", ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/reservations/all/"+tt.id+"/extras", strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "all", "id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostReservationExtra)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+
+		if msg := session.PopString(ctx, "error"); msg != tt.expectedError {
+			t.Errorf("failed %s: expected error %q, but got %q", tt.name, tt.expectedError, msg)
+		}
+	}
+}
+
+func TestRepository_AdminDeleteReservationExtra(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		extra              string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{"valid", "1", "1", http.StatusSeeOther, "/admin/reservations/new/1/show"},
+		{"invalid-extra", "1", "x", http.StatusBadRequest, ""},
+		{"invalid-id", "x", "1", http.StatusBadRequest, ""},
+		{"database-error", "1000", "1", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/delete-reservation-extra/new/"+tt.id+"/"+tt.extra+"/do", nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "new", "id": tt.id, "extra": tt.extra})
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminDeleteReservationExtra)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
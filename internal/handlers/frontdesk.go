@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//today returns the current date at midnight UTC, the way dates are stored
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+//frontDeskURL returns the link to the front desk boards of a day
+func frontDeskURL(day string) string {
+	if day == "" {
+		return "/admin/front-desk"
+	}
+
+	return fmt.Sprintf("/admin/front-desk?d=%s", url.QueryEscape(day))
+}
+
+//AdminFrontDesk shows the arrivals and departures of a day, today unless the query says otherwise
+func (m *Repository) AdminFrontDesk(w http.ResponseWriter, r *http.Request) {
+	day := today()
+
+	if d := r.URL.Query().Get("d"); d != "" {
+		var err error
+		day, err = time.Parse("2006-01-02", d)
+		if err != nil {
+			m.App.Session.Put(r.Context(), "error", "Invalid date")
+			http.Redirect(w, r, frontDeskURL(""), http.StatusSeeOther)
+			return
+		}
+	}
+
+	arrivals, err := m.DB.ArrivalsForDay(day)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	departures, err := m.DB.DeparturesForDay(day)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	stringMap := make(map[string]string)
+	stringMap["day"] = day.Format("2006-01-02")
+	stringMap["previous"] = day.AddDate(0, 0, -1).Format("2006-01-02")
+	stringMap["next"] = day.AddDate(0, 0, 1).Format("2006-01-02")
+
+	data := make(map[string]interface{})
+	data["arrivals"] = arrivals
+	data["departures"] = departures
+
+	render.Template(w, r, "admin-front-desk.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+	})
+}
+
+//frontDeskReservation returns the reservation a front desk action is for and the link back to the boards,
+//or false if it answered the request already
+func (m *Repository) frontDeskReservation(w http.ResponseWriter, r *http.Request) (models.Reservation, string, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return models.Reservation{}, "", false
+	}
+
+	res, err := m.DB.GetReservationByID(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return models.Reservation{}, "", false
+	}
+
+	return res, frontDeskURL(r.URL.Query().Get("d")), true
+}
+
+//AdminCheckIn checks a guest in now
+func (m *Repository) AdminCheckIn(w http.ResponseWriter, r *http.Request) {
+	res, back, ok := m.frontDeskReservation(w, r)
+	if !ok {
+		return
+	}
+
+	if res.StartDate.After(today()) {
+		m.App.Session.Put(r.Context(), "error", "Guests can't check in before the arrival day")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	done, err := m.DB.CheckIn(res.ID, time.Now())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if !done {
+		m.App.Session.Put(r.Context(), "error", "Reservation can't be checked in")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("%s %s checked in", res.FirstName, res.LastName))
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+//AdminCheckOut checks a guest out now
+func (m *Repository) AdminCheckOut(w http.ResponseWriter, r *http.Request) {
+	res, back, ok := m.frontDeskReservation(w, r)
+	if !ok {
+		return
+	}
+
+	done, err := m.DB.CheckOut(res.ID, time.Now())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if !done {
+		m.App.Session.Put(r.Context(), "error", "Reservation can't be checked out")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("%s %s checked out", res.FirstName, res.LastName))
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+//AdminMarkNoShow marks a reservation whose guest didn't arrive as a no-show
+func (m *Repository) AdminMarkNoShow(w http.ResponseWriter, r *http.Request) {
+	res, back, ok := m.frontDeskReservation(w, r)
+	if !ok {
+		return
+	}
+
+	if res.StartDate.After(today()) {
+		m.App.Session.Put(r.Context(), "error", "Reservations can't be marked as no-shows before the arrival day")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	done, err := m.DB.MarkNoShow(res.ID)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if !done {
+		m.App.Session.Put(r.Context(), "error", "Reservation can't be marked as a no-show")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Reservation marked as a no-show")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+//MarkNoShows marks the confirmed reservations arriving on the day before now that weren't checked in as
+//no-shows, and returns how many there were. It is run repeatedly, so marking the same day again is harmless
+func (m *Repository) MarkNoShows(now time.Time) (int64, error) {
+	arrival := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+
+	return m.DB.MarkNoShows(arrival)
+}
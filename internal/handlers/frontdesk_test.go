@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepository_AdminFrontDesk(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{"today", "/admin/front-desk", http.StatusOK, "Check In"},
+		{"other-day", "/admin/front-desk?d=2050-01-01", http.StatusOK, "Check Out"},
+		{"invalid-day", "/admin/front-desk?d=invalid", http.StatusSeeOther, ""},
+		{"database-error", "/admin/front-desk?d=2040-01-01", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminFrontDesk)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+	}
+}
+
+func TestRepository_FrontDeskActions(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		handler            http.HandlerFunc
+		id                 string
+		expectedStatusCode int
+		expectedError      bool
+	}{
+		{"check-in", Repo.AdminCheckIn, "3", http.StatusSeeOther, false},
+		{"check-in-before-arrival", Repo.AdminCheckIn, "1", http.StatusSeeOther, true},
+		{"check-in-twice", Repo.AdminCheckIn, "4", http.StatusSeeOther, true},
+		{"check-in-invalid-id", Repo.AdminCheckIn, "x", http.StatusBadRequest, false},
+		{"check-in-unknown", Repo.AdminCheckIn, "1001", http.StatusInternalServerError, false},
+		{"check-out", Repo.AdminCheckOut, "4", http.StatusSeeOther, false},
+		{"check-out-not-checked-in", Repo.AdminCheckOut, "3", http.StatusSeeOther, true},
+		{"check-out-database-error", Repo.AdminCheckOut, "1000", http.StatusInternalServerError, false},
+		{"no-show", Repo.AdminMarkNoShow, "3", http.StatusSeeOther, false},
+		{"no-show-before-arrival", Repo.AdminMarkNoShow, "1", http.StatusSeeOther, true},
+		{"no-show-checked-in", Repo.AdminMarkNoShow, "4", http.StatusSeeOther, true},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/front-desk-action/"+tt.id+"/do?d=2050-01-01", nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		rr := httptest.NewRecorder()
+
+		tt.handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if rr.Code != http.StatusSeeOther {
+			continue
+		}
+
+		actualLoc, _ := rr.Result().Location()
+		if actualLoc.String() != "/admin/front-desk?d=2050-01-01" {
+			t.Errorf("failed %s: expected location /admin/front-desk?d=2050-01-01, but got location %s", tt.name, actualLoc.String())
+		}
+
+		if hasError := session.Exists(req.Context(), "error"); hasError != tt.expectedError {
+			t.Errorf("failed %s: expected error %t, but got %t", tt.name, tt.expectedError, hasError)
+		}
+	}
+}
+
+func TestRepository_MarkNoShows(t *testing.T) {
+	var theTests = []struct {
+		name          string
+		now           time.Time
+		expectedCount int64
+		expectedError bool
+	}{
+		{"day-after-arrival", time.Date(2050, 1, 2, 0, 30, 0, 0, time.UTC), 1, false},
+		{"late-on-day-after-arrival", time.Date(2050, 1, 2, 23, 30, 0, 0, time.UTC), 1, false},
+		{"arrival-day", time.Date(2050, 1, 1, 23, 30, 0, 0, time.UTC), 0, false},
+		{"older-arrivals", time.Date(2050, 1, 3, 0, 30, 0, 0, time.UTC), 0, false},
+		{"database-error", time.Date(2040, 1, 2, 0, 30, 0, 0, time.UTC), 0, true},
+	}
+
+	for _, tt := range theTests {
+		marked, err := Repo.MarkNoShows(tt.now)
+		if (err != nil) != tt.expectedError {
+			t.Errorf("failed %s: expected error %t, but got %v", tt.name, tt.expectedError, err)
+		}
+
+		if marked != tt.expectedCount {
+			t.Errorf("failed %s: expected %d no-shows, but got %d", tt.name, tt.expectedCount, marked)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//guestList is a page of the admin guest list
+type guestList struct {
+	listQuery
+	Guests []models.Guest
+}
+
+//AdminGuests shows a page of the guests matching the search of the query
+func (m *Repository) AdminGuests(w http.ResponseWriter, r *http.Request) {
+	p := listPage(r, guestListPageSize, "last_name")
+
+	guests, total, err := m.DB.SearchGuests(strings.TrimSpace(r.URL.Query().Get("q")), p)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["list"] = guestList{
+		listQuery: listQuery{Path: r.URL.Path, Query: r.URL.Query(), Page: p, Total: total},
+		Guests:    guests,
+	}
+
+	render.Template(w, r, "admin-guests.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+//AdminShowGuest shows the profile of a guest with the stay history and possible duplicates
+func (m *Repository) AdminShowGuest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	guest, err := m.DB.GetGuestByID(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	reservations, err := m.DB.ReservationsForGuest(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	duplicates, err := m.DB.PossibleDuplicateGuests(guest)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["guest"] = guest
+	data["reservations"] = reservations
+	data["duplicates"] = duplicates
+
+	render.Template(w, r, "admin-guest-show.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+//AdminMergeGuest merges a duplicate guest into another, moving over all reservations
+func (m *Repository) AdminMergeGuest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	duplicateID, err := strconv.Atoi(chi.URLParam(r, "duplicate"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	showURL := fmt.Sprintf("/admin/guests/%d", id)
+
+	if id == duplicateID {
+		m.App.Session.Put(r.Context(), "error", "Can't merge a guest into itself")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	err = m.DB.MergeGuests(id, duplicateID)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Guests merged")
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRepository_AdminGuests(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{"all", "/admin/guests", http.StatusOK, "2 guests"},
+		{"searched", "/admin/guests?q=smith&sort=lifetime_value&dir=desc", http.StatusOK, "Smith, John"},
+		{"database-error", "/admin/guests?q=error", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminGuests)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+	}
+}
+
+func TestRepository_AdminShowGuest(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{"with-duplicates", "1", http.StatusOK, "Possible Duplicates"},
+		{"without-duplicates", "2", http.StatusOK, "Stay History"},
+		{"invalid-id", "x", http.StatusBadRequest, ""},
+		{"not-found", "3", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/guests/"+tt.id, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminShowGuest)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+	}
+}
+
+func TestRepository_AdminMergeGuest(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		duplicate          string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{"valid", "1", "2", http.StatusSeeOther, "/admin/guests/1"},
+		{"same-guest", "1", "1", http.StatusSeeOther, "/admin/guests/1"},
+		{"invalid-duplicate", "1", "x", http.StatusBadRequest, ""},
+		{"database-error", "1", "3", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/merge-guest/"+tt.id+"/"+tt.duplicate+"/do", nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id, "duplicate": tt.duplicate})
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminMergeGuest)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,6 +16,8 @@ import (
 	"github.com/yalagtyarzh/leafsite/internal/forms"
 	"github.com/yalagtyarzh/leafsite/internal/helpers"
 	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/payments"
+	"github.com/yalagtyarzh/leafsite/internal/pricing"
 	"github.com/yalagtyarzh/leafsite/internal/render"
 	"github.com/yalagtyarzh/leafsite/internal/repository"
 	"github.com/yalagtyarzh/leafsite/internal/repository/dbrepo"
@@ -78,6 +81,22 @@ func (m *Repository) Reservation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	res.Room.RoomName = room.RoomName
+	if res.Guests < 1 {
+		res.Guests = 1
+	}
+
+	room, err = m.applyRatePlan(&res, room, res.RatePlanID)
+	if err == nil {
+		err = m.rateStay(&res, room, models.RoomRestriction{})
+	}
+	if err == nil {
+		err = m.priceStay(&res, room, models.PromoCode{})
+	}
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't calculate the price")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
 
 	m.App.Session.Put(r.Context(), "reservation", res)
 
@@ -87,9 +106,20 @@ func (m *Repository) Reservation(w http.ResponseWriter, r *http.Request) {
 	stringMap := make(map[string]string)
 	stringMap["start_date"] = sd
 	stringMap["end_date"] = ed
+	stringMap["hold_until"] = m.App.Session.GetString(r.Context(), "hold_until")
+
+	extras, err := m.DB.Extras()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't get extras")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
 
 	data := make(map[string]interface{})
 	data["reservation"] = res
+	data["room"] = room
+	data["extras"] = extras
+	data["quantities"] = extraQuantities(res)
 
 	render.Template(w, r, "make-reservation.page.tmpl", &models.TemplateData{
 		Form:      forms.New(nil),
@@ -140,6 +170,27 @@ func (m *Repository) PostReservation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var planID int
+	if r.Form.Get("rate_plan_id") != "" {
+		planID, err = strconv.Atoi(r.Form.Get("rate_plan_id"))
+		if err != nil {
+			m.App.Session.Put(r.Context(), "error", "Invalid data")
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+	}
+
+	form := forms.New(r.PostForm)
+
+	form.Required("first_name", "last_name", "email")
+	form.MinLength("first_name", 3)
+	form.IsEmail("email")
+
+	guests := 1
+	if r.Form.Get("guests") != "" && form.IsInt("guests", 1, maxGuests) {
+		guests, _ = strconv.Atoi(r.Form.Get("guests"))
+	}
+
 	reservation := models.Reservation{
 		FirstName: r.Form.Get("first_name"),
 		LastName:  r.Form.Get("last_name"),
@@ -149,21 +200,86 @@ func (m *Repository) PostReservation(w http.ResponseWriter, r *http.Request) {
 		EndDate:   endDate,
 		RoomID:    roomID,
 		Room:      room,
+		Guests:    guests,
 	}
 
-	form := forms.New(r.PostForm)
+	room, err = m.applyRatePlan(&reservation, room, planID)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid data")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
 
-	form.Required("first_name", "last_name", "email")
-	form.MinLength("first_name", 3)
-	form.IsEmail("email")
+	extras, err := m.DB.Extras()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't get extras")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	bookExtras(form, &reservation, extras)
+
+	var promo models.PromoCode
+	if code := strings.TrimSpace(r.Form.Get("promo_code")); code != "" {
+		var found bool
+		promo, found, err = m.DB.GetPromoCodeByCode(code)
+		if err != nil {
+			m.App.Session.Put(r.Context(), "error", "Can't check the promo code")
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		if !found {
+			form.Errors.Add("promo_code", "Sorry, we don't know this code")
+		} else if err = pricing.CheckPromoCode(promo, reservation, time.Now()); err != nil {
+			form.Errors.Add("promo_code", "Sorry, "+err.Error())
+			promo = models.PromoCode{}
+		}
+	}
+
+	var voucher models.GiftVoucher
+	if code := strings.TrimSpace(r.Form.Get("voucher_code")); code != "" {
+		var problem string
+		voucher, problem, err = m.findVoucher(code)
+		if err != nil {
+			m.App.Session.Put(r.Context(), "error", "Can't check the gift voucher")
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		if problem != "" {
+			form.Errors.Add("voucher_code", problem)
+		}
+	}
+
+	//the rates quoted on the form are honoured, as occupancy may have changed while it was filled in
+	quote, ok := m.App.Session.Get(r.Context(), "reservation").(models.Reservation)
+	if ok && quote.RoomID == roomID && quote.RatePlanID == planID && quote.StartDate.Equal(startDate) &&
+		quote.EndDate.Equal(endDate) {
+		reservation.Adjustments = quote.Adjustments
+	} else {
+		err = m.rateStay(&reservation, room, models.RoomRestriction{})
+	}
+	if err == nil {
+		err = m.priceStay(&reservation, room, promo)
+	}
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't calculate the price")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
 
 	if !form.Valid() {
 		data := make(map[string]interface{})
 		data["reservation"] = reservation
+		data["room"] = room
+		data["extras"] = extras
+		data["quantities"] = extraQuantities(reservation)
 
 		stringMap := make(map[string]string)
 		stringMap["start_date"] = sd
 		stringMap["end_date"] = ed
+		stringMap["hold_until"] = m.App.Session.GetString(r.Context(), "hold_until")
 
 		render.Template(w, r, "make-reservation.page.tmpl", &models.TemplateData{
 			Form:      form,
@@ -173,7 +289,49 @@ func (m *Repository) PostReservation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	held, err := m.sessionHoldIsActive(r, reservation)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't check availability")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	//without a live hold on this room and these dates the room may have been sold while the form was filled in
+	if !held {
+		available, err := m.DB.SearchAvailabilityByDatesByRoomID(startDate, endDate, roomID)
+		if err != nil {
+			m.App.Session.Put(r.Context(), "error", "Can't check availability")
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		if !available {
+			m.App.Session.Put(r.Context(), "error", "Sorry, the room is no longer available for these dates")
+			http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+			return
+		}
+	} else {
+		//a room held from a waitlist email is booked through its offer
+		reservation.WaitlistEntryID = m.App.Session.GetInt(r.Context(), "waitlist_id")
+	}
+
 	newReservationID, err := m.DB.InsertReservation(reservation)
+	if errors.Is(err, repository.ErrPromoCodeUsedUp) {
+		m.App.Session.Put(r.Context(), "error", "Sorry, the promo code has just been used up")
+		http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
+		return
+	}
+	if errors.Is(err, repository.ErrWaitlistOfferUsed) {
+		m.releaseHold(r)
+		m.App.Session.Put(r.Context(), "error", "Sorry, this waitlist offer is no longer open")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if errors.Is(err, repository.ErrExtraSoldOut) {
+		m.App.Session.Put(r.Context(), "error", "Sorry, not enough of an extra you chose is left for these dates")
+		http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
+		return
+	}
 	if err != nil {
 		m.App.Session.Put(r.Context(), "error", "Can't insert reservation into database")
 		http.Redirect(w, r, "/", http.StatusSeeOther)
@@ -195,6 +353,39 @@ func (m *Repository) PostReservation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	m.releaseHold(r)
+
+	reservation.ID = newReservationID
+
+	owed := reservation.TotalPrice
+	if voucher.ID > 0 {
+		paid := payments.VoucherAmount(voucher, owed)
+		_, err = m.DB.RedeemGiftVoucher(voucher.ID, reservation.ID, paid)
+		if err != nil {
+			//the room is booked either way, the voucher can still be redeemed at the desk
+			m.App.ErrorLog.Println(err)
+			m.App.Session.Put(r.Context(), "warning", "Sorry, we couldn't redeem your gift voucher, please bring it along")
+		} else {
+			owed -= paid
+			m.App.Session.Put(r.Context(), "voucher_paid", paid)
+		}
+	}
+
+	deposit := m.App.Deposit.Amount(reservation, time.Now())
+	if deposit > owed {
+		deposit = owed
+	}
+	if deposit > 0 && m.App.Payments != nil {
+		e, err := m.requestPayment(reservation, deposit, "Deposit")
+		if err != nil {
+			//the room is booked either way, staff can ask for the deposit later
+			m.App.ErrorLog.Println(err)
+		} else {
+			m.App.Session.Put(r.Context(), "deposit", deposit)
+			m.App.Session.Put(r.Context(), "payment_url", e.CheckoutURL)
+		}
+	}
+
 	htmlMessage := fmt.Sprintf(`
 		<strong>Reservation Confirmation</strong><br>
 		Dear %s: <br>
@@ -283,14 +474,22 @@ func (m *Repository) PostAvailability(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(rooms) == 0 {
-		//no availability
+		//no availability, offer the waitlist instead
 		m.App.Session.Put(r.Context(), "error", "No availability")
-		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		http.Redirect(w, r, fmt.Sprintf("/waitlist?s=%s&e=%s", start, end), http.StatusSeeOther)
+		return
+	}
+
+	plans, err := m.ratePlansByRoom()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't get rate plans for rooms")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
 	data := make(map[string]interface{})
 	data["rooms"] = rooms
+	data["plans"] = plans
 
 	res := models.Reservation{
 		StartDate: startDate,
@@ -383,6 +582,92 @@ func (m *Repository) AvailabilityJSON(w http.ResponseWriter, r *http.Request) {
 	w.Write(out)
 }
 
+//maxCalendarNights is the longest range served by AvailabilityCalendarJSON
+const maxCalendarNights = 366
+
+type calendarNight struct {
+	Date      string `json:"date"`
+	Available bool   `json:"available"`
+	Price     int    `json:"price,omitempty"`
+}
+
+type calendarResponse struct {
+	OK        bool            `json:"ok"`
+	Message   string          `json:"message"`
+	RoomID    string          `json:"room_id"`
+	StartDate string          `json:"start_date"`
+	EndDate   string          `json:"end_date"`
+	Nights    []calendarNight `json:"nights"`
+}
+
+//AvailabilityCalendarJSON sends per-night availability of a room for a date range as JSON
+func (m *Repository) AvailabilityCalendarJSON(w http.ResponseWriter, r *http.Request) {
+	sd := r.URL.Query().Get("start")
+	ed := r.URL.Query().Get("end")
+
+	layout := "2006-01-02"
+	startDate, err := time.Parse(layout, sd)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	endDate, err := time.Parse(layout, ed)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if !endDate.After(startDate) || endDate.After(startDate.AddDate(0, 0, maxCalendarNights)) {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	roomID, err := strconv.Atoi(r.URL.Query().Get("room_id"))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	nights, err := m.DB.AvailabilityCalendarByRoomID(roomID, startDate, endDate)
+	if err != nil {
+		resp := calendarResponse{
+			OK:      false,
+			Message: "Error connecting to database",
+		}
+
+		out, _ := json.MarshalIndent(resp, "", "     ")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(out)
+		return
+	}
+
+	resp := calendarResponse{
+		OK:        true,
+		StartDate: sd,
+		EndDate:   ed,
+		RoomID:    strconv.Itoa(roomID),
+		Nights:    []calendarNight{},
+	}
+
+	for _, n := range nights {
+		resp.Nights = append(resp.Nights, calendarNight{
+			Date:      n.Date.Format(layout),
+			Available: n.Available,
+			Price:     n.Price,
+		})
+	}
+
+	out, err := json.MarshalIndent(resp, "", "     ")
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
 //Contact renders the contact page
 func (m *Repository) Contact(w http.ResponseWriter, r *http.Request) {
 	render.Template(w, r, "contact.page.tmpl", &models.TemplateData{})
@@ -408,16 +693,23 @@ func (m *Repository) ReservationSummary(w http.ResponseWriter, r *http.Request)
 	stringMap := make(map[string]string)
 	stringMap["start_date"] = sd
 	stringMap["end_date"] = ed
+	stringMap["payment_url"] = m.App.Session.PopString(r.Context(), "payment_url")
+
+	intMap := make(map[string]int)
+	intMap["deposit"] = m.App.Session.PopInt(r.Context(), "deposit")
+	intMap["voucher_paid"] = m.App.Session.PopInt(r.Context(), "voucher_paid")
 
 	render.Template(w, r, "reservation-summary.page.tmpl", &models.TemplateData{
 		Data:      data,
 		StringMap: stringMap,
+		IntMap:    intMap,
 	})
 }
 
-//ChooseRoom displays list of available rooms
+//ChooseRoom takes the room, and the rate plan when one is given, chosen from the available rooms
 func (m *Repository) ChooseRoom(w http.ResponseWriter, r *http.Request) {
-	exploded := strings.Split(r.RequestURI, "/")
+	path, _, _ := strings.Cut(r.RequestURI, "?")
+	exploded := strings.Split(path, "/")
 	roomID, err := strconv.Atoi(exploded[2])
 	if err != nil {
 		m.App.Session.Put(r.Context(), "error", "Missing url parameter")
@@ -433,6 +725,36 @@ func (m *Repository) ChooseRoom(w http.ResponseWriter, r *http.Request) {
 	}
 
 	res.RoomID = roomID
+	res.RatePlanID, res.RatePlan = 0, ""
+
+	if r.URL.Query().Get("plan") != "" {
+		planID, err := strconv.Atoi(r.URL.Query().Get("plan"))
+		if err != nil {
+			m.App.Session.Put(r.Context(), "error", "Invalid rate plan")
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		_, err = m.applyRatePlan(&res, models.Room{}, planID)
+		if err != nil {
+			m.App.Session.Put(r.Context(), "error", "Invalid rate plan")
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+	}
+
+	held, err := m.placeHold(r, res)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't hold room")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if !held {
+		m.App.Session.Put(r.Context(), "error", "Sorry, this room has just been taken")
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
 
 	m.App.Session.Put(r.Context(), "reservation", res)
 
@@ -477,6 +799,19 @@ func (m *Repository) BookRoom(w http.ResponseWriter, r *http.Request) {
 	res.StartDate = startDate
 	res.EndDate = endDate
 
+	held, err := m.placeHold(r, res)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't hold room")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if !held {
+		m.App.Session.Put(r.Context(), "error", "Sorry, this room has just been taken")
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
+
 	m.App.Session.Put(r.Context(), "reservation", res)
 
 	http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
@@ -532,40 +867,14 @@ func (m *Repository) Logout(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
 }
 
-func (m *Repository) AdminDashboard(w http.ResponseWriter, r *http.Request) {
-	render.Template(w, r, "admin-dashboard.page.tmpl", &models.TemplateData{})
-}
-
-//AdminNewReservations shows all new reservations in admin tool
+//AdminNewReservations shows a page of the new reservations in admin tool
 func (m *Repository) AdminNewReservations(w http.ResponseWriter, r *http.Request) {
-	reservations, err := m.DB.AllNewReservations()
-	if err != nil {
-		helpers.ServerError(w, err)
-		return
-	}
-
-	data := make(map[string]interface{})
-	data["reservations"] = reservations
-
-	render.Template(w, r, "admin-new-reservations.page.tmpl", &models.TemplateData{
-		Data: data,
-	})
+	m.reservationList(w, r, "new", "admin-new-reservations.page.tmpl", models.ProcessedNew)
 }
 
-//AdminAllReservations shows all reservations in admin tool
+//AdminAllReservations shows a page of all reservations in admin tool
 func (m *Repository) AdminAllReservations(w http.ResponseWriter, r *http.Request) {
-	reservations, err := m.DB.AllReservations()
-	if err != nil {
-		helpers.ServerError(w, err)
-		return
-	}
-
-	data := make(map[string]interface{})
-	data["reservations"] = reservations
-
-	render.Template(w, r, "admin-all-reservations.page.tmpl", &models.TemplateData{
-		Data: data,
-	})
+	m.reservationList(w, r, "all", "admin-all-reservations.page.tmpl", models.ProcessedAny)
 }
 
 //AdminShowReservation shows the reservation in admin tool
@@ -595,11 +904,80 @@ func (m *Repository) AdminShowReservation(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	policy, err := m.cancellationPolicyFor(res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	fee := pricing.CancellationFee(policy, res, time.Now())
+
+	rooms, err := m.DB.AllRooms()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	notes, err := m.DB.NotesForReservation(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	tags, err := m.DB.AllTags()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	folio, err := m.folio(res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	issued, err := m.DB.InvoicesForReservation(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	extras, err := m.DB.Extras()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	stringMap["tags"] = strings.Join(res.Tags, ", ")
+	if m.App.Payments != nil {
+		stringMap["online_payments"] = "1"
+	}
+
+	intMap := make(map[string]int)
+	intMap["cancellation_fee"] = fee
+	intMap["refund"] = pricing.Refund(res, fee)
+	if folio.Balance() < 0 {
+		intMap["credit"] = -folio.Balance()
+	}
+	for _, e := range folio.Entries {
+		if e.Kind == models.FolioPayment {
+			intMap["refundable"] += folio.Refundable(e.ID)
+		}
+	}
+
 	data := make(map[string]interface{})
 	data["reservation"] = res
+	data["policy"] = policy
+	data["rooms"] = rooms
+	data["notes"] = notes
+	data["tags"] = tags
+	data["folio"] = folio
+	data["invoices"] = issued
+	data["extras"] = extras
 
 	render.Template(w, r, "admin-reservations-show.page.tmpl", &models.TemplateData{
 		StringMap: stringMap,
+		IntMap:    intMap,
 		Data:      data,
 		Form:      forms.New(nil),
 	})
@@ -703,10 +1081,14 @@ func (m *Repository) AdminReservationsCalendar(w http.ResponseWriter, r *http.Re
 	for _, x := range rooms {
 		reservationMap := make(map[string]int)
 		blockMap := make(map[string]int)
+		holdMap := make(map[string]int)
+		maintenanceMap := make(map[string]int)
 
 		for d := firstOfMonth; !d.After(lastOfMonth); d = d.AddDate(0, 0, 1) {
 			reservationMap[d.Format("2006-01-2")] = 0
 			blockMap[d.Format("2006-01-2")] = 0
+			holdMap[d.Format("2006-01-2")] = 0
+			maintenanceMap[d.Format("2006-01-2")] = 0
 		}
 
 		restrictions, err := m.DB.GetRestrictionsForRoomByDate(x.ID, firstOfMonth, lastOfMonth)
@@ -720,12 +1102,22 @@ func (m *Repository) AdminReservationsCalendar(w http.ResponseWriter, r *http.Re
 				for d := y.StartDate; !d.After(y.EndDate); d = d.AddDate(0, 0, 1) {
 					reservationMap[d.Format("2006-01-2")] = y.ReservationID
 				}
+			} else if y.RestrictionID == models.RestrictionHold {
+				for d := y.StartDate; !d.After(y.EndDate); d = d.AddDate(0, 0, 1) {
+					holdMap[d.Format("2006-01-2")] = y.ID
+				}
+			} else if y.RestrictionID == models.RestrictionMaintenance {
+				for d := y.StartDate; !d.After(y.EndDate); d = d.AddDate(0, 0, 1) {
+					maintenanceMap[d.Format("2006-01-2")] = y.ID
+				}
 			} else {
 				blockMap[y.StartDate.Format("2006-01-2")] = y.ID
 			}
 		}
 		data[fmt.Sprintf("reservation_map_%d", x.ID)] = reservationMap
 		data[fmt.Sprintf("block_map_%d", x.ID)] = blockMap
+		data[fmt.Sprintf("hold_map_%d", x.ID)] = holdMap
+		data[fmt.Sprintf("maintenance_map_%d", x.ID)] = maintenanceMap
 
 		m.App.Session.Put(r.Context(), fmt.Sprintf("block_map_%d", x.ID), blockMap)
 	}
@@ -762,11 +1154,19 @@ func (m *Repository) AdminProcessReservation(w http.ResponseWriter, r *http.Requ
 func (m *Repository) AdminDeleteReservation(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
 	src := chi.URLParam(r, "src")
-	err := m.DB.DeleteReservation(id)
+
+	res, err := m.DB.GetReservationByID(id)
 	if err != nil {
 		log.Println(err)
 	}
 
+	err = m.DB.DeleteReservation(id)
+	if err != nil {
+		log.Println(err)
+	} else if res.ID > 0 && res.Status != models.ReservationCancelled {
+		m.notifyWaitlist(res.RoomID, res.StartDate, res.EndDate)
+	}
+
 	year := r.URL.Query().Get("y")
 	month := r.URL.Query().Get("m")
 
@@ -798,6 +1198,8 @@ func (m *Repository) AdminPostReservationsCalendar(w http.ResponseWriter, r *htt
 
 	form := forms.New(r.PostForm)
 
+	var freed []models.RoomRestriction
+
 	for _, x := range rooms {
 		curMap := m.App.Session.Get(r.Context(), fmt.Sprintf("block_map_%d", x.ID)).(map[string]int)
 		for name, value := range curMap {
@@ -807,7 +1209,11 @@ func (m *Repository) AdminPostReservationsCalendar(w http.ResponseWriter, r *htt
 						err := m.DB.DeleteBlockByID(value)
 						if err != nil {
 							log.Println(err)
+							continue
 						}
+
+						t, _ := time.Parse("2006-01-2", name)
+						freed = append(freed, models.RoomRestriction{RoomID: x.ID, StartDate: t, EndDate: t})
 					}
 				}
 			}
@@ -827,6 +1233,10 @@ func (m *Repository) AdminPostReservationsCalendar(w http.ResponseWriter, r *htt
 		}
 	}
 
+	for _, x := range freed {
+		m.notifyWaitlist(x.RoomID, x.StartDate, x.EndDate)
+	}
+
 	m.App.Session.Put(r.Context(), "flash", "Changes saved")
 	http.Redirect(w, r, fmt.Sprintf("/admin/reservations-calendar?y=%d&m=%d", year, month), http.StatusSeeOther)
 }
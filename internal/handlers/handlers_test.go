@@ -100,6 +100,24 @@ func TestHandlers(t *testing.T) {
 			method:             "GET",
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name:               "waitlist",
+			url:                "/waitlist?s=2050-01-01&e=2050-01-02",
+			method:             "GET",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "import",
+			url:                "/admin/import",
+			method:             "GET",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "admin waitlist",
+			url:                "/admin/waitlist",
+			method:             "GET",
+			expectedStatusCode: http.StatusOK,
+		},
 	}
 	routes := getRoutes()
 	ts := httptest.NewServer(routes)
@@ -254,10 +272,161 @@ func TestRepository_PostReservation(t *testing.T) {
 		email              string
 		phone              string
 		roomID             string
+		guests             string
+		promoCode          string
+		voucherCode        string
+		ratePlanID         string
+		extras             url.Values
+		holdID             int
+		waitlistID         int
 		expectedStatusCode int
+		expectedLocation   string
 	}{
 		{
 			name:               "Ok",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/reservation-summary",
+		},
+		{
+			name:               "Missing post body",
+			expectedStatusCode: http.StatusSeeOther,
+		},
+		{
+			name:               "With guests",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			guests:             "3",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/reservation-summary",
+		},
+		{
+			name:               "Invalid guests",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			guests:             "0",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "With promo code",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			promoCode:          "summer",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/reservation-summary",
+		},
+		{
+			name:               "Unknown promo code",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			promoCode:          "NOTHING",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Expired promo code",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			promoCode:          "EXPIRED",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Promo code lookup error",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			promoCode:          "ERROR",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "Promo code used up meanwhile",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			promoCode:          "RACE",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/make-reservation",
+		},
+		{
+			name:               "With rate plan",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			ratePlanID:         "1",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/reservation-summary",
+		},
+		{
+			name:               "Rate plan of another room",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			ratePlanID:         "50",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "Invalid rate plan",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			ratePlanID:         "flexible",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "Room no longer available",
 			startDate:          "2030-01-01",
 			endDate:            "2030-01-02",
 			firstName:          "Alister",
@@ -266,10 +435,174 @@ func TestRepository_PostReservation(t *testing.T) {
 			phone:              "7777777777",
 			roomID:             "1",
 			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/search-availability",
 		},
 		{
-			name:               "Missing post body",
+			name:               "Room is held",
+			startDate:          "2030-01-01",
+			endDate:            "2030-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			holdID:             1,
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/reservation-summary",
+		},
+		{
+			name:               "Waitlist offer no longer open",
+			startDate:          "2030-01-01",
+			endDate:            "2030-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			holdID:             1,
+			waitlistID:         3,
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "Hold on another room",
+			startDate:          "2030-01-01",
+			endDate:            "2030-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "2",
+			holdID:             1,
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/search-availability",
+		},
+		{
+			name:               "Hold on other dates",
+			startDate:          "2030-01-01",
+			endDate:            "2030-01-05",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			holdID:             1,
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/search-availability",
+		},
+		{
+			name:               "Hold check error",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			holdID:             1000,
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "With gift voucher",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			voucherCode:        "GIFT-50",
 			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/reservation-summary",
+		},
+		{
+			name:               "Voucher used up meanwhile",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			voucherCode:        "RACE",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/reservation-summary",
+		},
+		{
+			name:               "Unknown gift voucher",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			voucherCode:        "NOTHING",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Expired gift voucher",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			voucherCode:        "EXPIRED",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Gift voucher error",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			voucherCode:        "ERROR",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "With extras",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			extras:             url.Values{"extra_1": {"1"}, "extra_2": {"2"}},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/reservation-summary",
+		},
+		{
+			name:               "Invalid extra quantity",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			extras:             url.Values{"extra_1": {"x"}},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Extra sold out",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
+			firstName:          "Alister",
+			lastName:           "Azimuth",
+			email:              "silhouetteAG@gmail.com",
+			phone:              "7777777777",
+			roomID:             "1",
+			extras:             url.Values{"extra_2": {"6"}},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/make-reservation",
 		},
 		{
 			name:               "Invalid start date",
@@ -317,8 +650,8 @@ func TestRepository_PostReservation(t *testing.T) {
 		},
 		{
 			name:               "Failure to insert reservation",
-			startDate:          "2030-01-01",
-			endDate:            "2030-01-02",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
 			firstName:          "Alister",
 			lastName:           "Azimuth",
 			email:              "silhouetteAG@gmail.com",
@@ -328,8 +661,8 @@ func TestRepository_PostReservation(t *testing.T) {
 		},
 		{
 			name:               "Failure to insert restriction",
-			startDate:          "2030-01-01",
-			endDate:            "2030-01-02",
+			startDate:          "2029-01-01",
+			endDate:            "2029-01-02",
 			firstName:          "Alister",
 			lastName:           "Azimuth",
 			email:              "silhouetteAG@gmail.com",
@@ -353,6 +686,21 @@ func TestRepository_PostReservation(t *testing.T) {
 			postedData.Add("email", tt.email)
 			postedData.Add("phone", tt.phone)
 			postedData.Add("room_id", tt.roomID)
+			if tt.guests != "" {
+				postedData.Add("guests", tt.guests)
+			}
+			if tt.promoCode != "" {
+				postedData.Add("promo_code", tt.promoCode)
+			}
+			if tt.voucherCode != "" {
+				postedData.Add("voucher_code", tt.voucherCode)
+			}
+			if tt.ratePlanID != "" {
+				postedData.Add("rate_plan_id", tt.ratePlanID)
+			}
+			for field, values := range tt.extras {
+				postedData[field] = values
+			}
 
 			req, _ = http.NewRequest("POST", "/make-reservation", strings.NewReader(postedData.Encode()))
 		}
@@ -362,6 +710,13 @@ func TestRepository_PostReservation(t *testing.T) {
 
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
+		if tt.holdID > 0 {
+			session.Put(ctx, "hold_id", tt.holdID)
+		}
+		if tt.waitlistID > 0 {
+			session.Put(ctx, "waitlist_id", tt.waitlistID)
+		}
+
 		rr := httptest.NewRecorder()
 
 		handler := http.HandlerFunc(Repo.PostReservation)
@@ -371,6 +726,13 @@ func TestRepository_PostReservation(t *testing.T) {
 		if rr.Code != tt.expectedStatusCode {
 			t.Errorf("PostReservation failed \"%s\" test: got %d, wanted %d", tt.name, rr.Code, tt.expectedStatusCode)
 		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("PostReservation failed \"%s\" test: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
 	}
 }
 
@@ -447,46 +809,186 @@ func TestRepository_AvailabilityJSON(t *testing.T) {
 	}
 }
 
+func TestRepository_AvailabilityCalendarJSON(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		queryParams        string
+		expectedStatusCode int
+		expectedOK         bool
+		expectedNights     int
+		expectedAvailable  int
+	}{
+		{
+			name:               "available",
+			queryParams:        "?room_id=1&start=2029-01-01&end=2029-01-08",
+			expectedStatusCode: http.StatusOK,
+			expectedOK:         true,
+			expectedNights:     7,
+			expectedAvailable:  7,
+		},
+		{
+			name:               "partially-available",
+			queryParams:        "?room_id=1&start=2029-12-30&end=2030-01-03",
+			expectedStatusCode: http.StatusOK,
+			expectedOK:         true,
+			expectedNights:     4,
+			expectedAvailable:  2,
+		},
+		{
+			name:               "database-error",
+			queryParams:        "?room_id=3&start=2029-01-01&end=2029-01-08",
+			expectedStatusCode: http.StatusOK,
+			expectedOK:         false,
+		},
+		{
+			name:               "invalid-start-date",
+			queryParams:        "?room_id=1&start=invalid&end=2029-01-08",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "invalid-end-date",
+			queryParams:        "?room_id=1&start=2029-01-01&end=invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "end-before-start",
+			queryParams:        "?room_id=1&start=2029-01-08&end=2029-01-01",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "range-too-long",
+			queryParams:        "?room_id=1&start=2029-01-01&end=2031-01-01",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "invalid-room-id",
+			queryParams:        "?room_id=invalid&start=2029-01-01&end=2029-01-08",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/availability-calendar"+tt.queryParams, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AvailabilityCalendarJSON)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+			continue
+		}
+
+		if rr.Code != http.StatusOK {
+			continue
+		}
+
+		var j calendarResponse
+		err := json.Unmarshal(rr.Body.Bytes(), &j)
+		if err != nil {
+			t.Errorf("failed %s: can't parse json", tt.name)
+			continue
+		}
+
+		available := 0
+		for _, n := range j.Nights {
+			if n.Available {
+				available++
+			}
+		}
+
+		if j.OK != tt.expectedOK || len(j.Nights) != tt.expectedNights || available != tt.expectedAvailable {
+			t.Errorf("failed %s: got ok %t with %d nights (%d available), expected ok %t with %d nights (%d available)",
+				tt.name, j.OK, len(j.Nights), available, tt.expectedOK, tt.expectedNights, tt.expectedAvailable)
+		}
+	}
+}
+
 func TestRepository_ChooseRoom(t *testing.T) {
 	var theTests = []struct {
 		name               string
 		roomID             string
+		inSession          bool
+		sessionPlanID      int
 		expectedStatusCode int
+		expectedLocation   string
+		expectedPlanID     int
+		expectedPlan       string
 	}{
 		{
 			name:               "Ok",
 			roomID:             "1",
+			inSession:          true,
 			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/make-reservation",
 		},
 		{
 			name:               "Reservation not in session",
 			roomID:             "1",
 			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
 		},
 		{
 			name:               "Missing url parameter",
 			roomID:             "ei",
 			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
 		},
-	}
-
-	reservation := models.Reservation{
-		RoomID: 1,
-		Room: models.Room{
-			ID:       1,
-			RoomName: "General's Quarters",
+		{
+			name:               "Rate plan",
+			roomID:             "1?plan=1",
+			inSession:          true,
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/make-reservation",
+			expectedPlanID:     1,
+			expectedPlan:       "Non-refundable",
+		},
+		{
+			name:               "Rate plan dropped",
+			roomID:             "1",
+			inSession:          true,
+			sessionPlanID:      1,
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/make-reservation",
+		},
+		{
+			name:               "Rate plan of another room",
+			roomID:             "1?plan=50",
+			inSession:          true,
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "Invalid rate plan",
+			roomID:             "1?plan=flexible",
+			inSession:          true,
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
 		},
 	}
 
 	for _, tt := range theTests {
-		req, _ := http.NewRequest("GET", "/choose-room/1", nil)
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/choose-room/%s", tt.roomID), nil)
 		ctx := getCtx(req)
 		req = req.WithContext(ctx)
 		req.RequestURI = fmt.Sprintf("/choose-room/%s", tt.roomID)
 
 		rr := httptest.NewRecorder()
 
-		if tt.name == "Ok" {
+		if tt.inSession {
+			reservation := models.Reservation{
+				RoomID: 1,
+				Room: models.Room{
+					ID:       1,
+					RoomName: "General's Quarters",
+				},
+			}
+			if tt.sessionPlanID > 0 {
+				reservation.RatePlanID, reservation.RatePlan = tt.sessionPlanID, "Non-refundable"
+			}
 			session.Put(ctx, "reservation", reservation)
 		}
 
@@ -495,7 +997,29 @@ func TestRepository_ChooseRoom(t *testing.T) {
 		handler.ServeHTTP(rr, req)
 
 		if rr.Code != tt.expectedStatusCode {
-			t.Errorf("ChooseRoom handler returned wrong response code: got %d, wanted %d", rr.Code, tt.expectedStatusCode)
+			t.Errorf("failed %s: ChooseRoom handler returned wrong response code: got %d, wanted %d", tt.name, rr.Code, tt.expectedStatusCode)
+		}
+
+		actualLoc, _ := rr.Result().Location()
+		if actualLoc.String() != tt.expectedLocation {
+			t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+		}
+
+		res, ok := session.Get(ctx, "reservation").(models.Reservation)
+		if !tt.inSession {
+			if ok {
+				t.Errorf("failed %s: expected no reservation in session", tt.name)
+			}
+			continue
+		}
+
+		if !ok {
+			t.Errorf("failed %s: expected a reservation in session", tt.name)
+			continue
+		}
+
+		if res.RatePlanID != tt.expectedPlanID || res.RatePlan != tt.expectedPlan {
+			t.Errorf("failed %s: expected rate plan %d %q in session, but got %d %q", tt.name, tt.expectedPlanID, tt.expectedPlan, res.RatePlanID, res.RatePlan)
 		}
 	}
 }
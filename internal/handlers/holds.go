@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+//holdLifetime is how long a chosen room is kept for a guest filling in the reservation form
+const holdLifetime = 15 * time.Minute
+
+//placeHold replaces the hold of the session with a hold on the room and dates of res,
+//returns false if the room has been taken in the meantime
+func (m *Repository) placeHold(r *http.Request, res models.Reservation) (bool, error) {
+	m.releaseHold(r)
+
+	expires := time.Now().Add(holdLifetime)
+
+	id, held, err := m.DB.InsertHold(models.RoomRestriction{
+		StartDate:     res.StartDate,
+		EndDate:       res.EndDate,
+		RoomID:        res.RoomID,
+		RestrictionID: models.RestrictionHold,
+		ExpiresAt:     expires,
+	})
+	if err != nil || !held {
+		return held, err
+	}
+
+	m.App.Session.Put(r.Context(), "hold_id", id)
+	m.App.Session.Put(r.Context(), "hold_until", expires.Format("15:04"))
+
+	return true, nil
+}
+
+//sessionHoldIsActive returns true if the session holds the room and dates of res and the hold has not
+//expired yet. A hold on another room or other dates doesn't count
+func (m *Repository) sessionHoldIsActive(r *http.Request, res models.Reservation) (bool, error) {
+	id := m.App.Session.GetInt(r.Context(), "hold_id")
+	if id == 0 {
+		return false, nil
+	}
+
+	return m.DB.HoldIsActive(id, models.RoomRestriction{
+		RoomID:    res.RoomID,
+		StartDate: res.StartDate,
+		EndDate:   res.EndDate,
+	})
+}
+
+//releaseHold releases the hold of the session, if there is one, along with the waitlist offer it was placed
+//for
+func (m *Repository) releaseHold(r *http.Request) {
+	id := m.App.Session.PopInt(r.Context(), "hold_id")
+	m.App.Session.Remove(r.Context(), "hold_until")
+	m.App.Session.Remove(r.Context(), "waitlist_id")
+	if id == 0 {
+		return
+	}
+
+	err := m.DB.DeleteHoldByID(id)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+	}
+}
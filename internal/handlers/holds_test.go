@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestRepository_ChooseRoomHold(t *testing.T) {
+	layout := "2006-01-02"
+	available, _ := time.Parse(layout, "2029-01-01")
+	taken, _ := time.Parse(layout, "2030-01-01")
+
+	var theTests = []struct {
+		name             string
+		roomID           string
+		startDate        time.Time
+		expectedLocation string
+		expectedHold     int
+	}{
+		{
+			name:             "hold-placed",
+			roomID:           "1",
+			startDate:        available,
+			expectedLocation: "/make-reservation",
+			expectedHold:     1,
+		},
+		{
+			name:             "room-taken",
+			roomID:           "1",
+			startDate:        taken,
+			expectedLocation: "/search-availability",
+		},
+		{
+			name:             "hold-error",
+			roomID:           "1000",
+			startDate:        available,
+			expectedLocation: "/",
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/choose-room/"+tt.roomID, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.RequestURI = "/choose-room/" + tt.roomID
+
+		session.Put(ctx, "reservation", models.Reservation{
+			StartDate: tt.startDate,
+			EndDate:   tt.startDate.AddDate(0, 0, 2),
+		})
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.ChooseRoom)
+		handler.ServeHTTP(rr, req)
+
+		actualLoc, _ := rr.Result().Location()
+		if actualLoc.String() != tt.expectedLocation {
+			t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+		}
+
+		if hold := session.GetInt(ctx, "hold_id"); hold != tt.expectedHold {
+			t.Errorf("failed %s: expected hold %d in session, but got %d", tt.name, tt.expectedHold, hold)
+		}
+	}
+}
+
+func TestRepository_BookRoomHold(t *testing.T) {
+	var theTests = []struct {
+		name             string
+		queryParams      string
+		expectedLocation string
+	}{
+		{
+			name:             "hold-placed",
+			queryParams:      "?id=1&s=2029-01-01&e=2029-01-02",
+			expectedLocation: "/make-reservation",
+		},
+		{
+			name:             "room-taken",
+			queryParams:      "?id=1&s=2030-01-01&e=2030-01-02",
+			expectedLocation: "/search-availability",
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/book-room"+tt.queryParams, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.BookRoom)
+		handler.ServeHTTP(rr, req)
+
+		actualLoc, _ := rr.Result().Location()
+		if actualLoc.String() != tt.expectedLocation {
+			t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+		}
+	}
+}
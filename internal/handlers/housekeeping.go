@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//housekeepingURL returns the link to the housekeeping board of a day
+func housekeepingURL(day string) string {
+	if day == "" {
+		return "/admin/housekeeping"
+	}
+
+	return fmt.Sprintf("/admin/housekeeping?d=%s", url.QueryEscape(day))
+}
+
+//AdminHousekeeping shows the rooms to clean on a day, today unless the query says otherwise, and the
+//status of all other rooms
+func (m *Repository) AdminHousekeeping(w http.ResponseWriter, r *http.Request) {
+	day := today()
+
+	if d := r.URL.Query().Get("d"); d != "" {
+		var err error
+		day, err = time.Parse("2006-01-02", d)
+		if err != nil {
+			m.App.Session.Put(r.Context(), "error", "Invalid date")
+			http.Redirect(w, r, housekeepingURL(""), http.StatusSeeOther)
+			return
+		}
+	}
+
+	board, err := m.DB.HousekeepingBoard(day)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	var toClean, others []models.HousekeepingRoom
+	for _, x := range board {
+		if x.ToClean() {
+			toClean = append(toClean, x)
+		} else {
+			others = append(others, x)
+		}
+	}
+
+	stringMap := make(map[string]string)
+	stringMap["day"] = day.Format("2006-01-02")
+
+	data := make(map[string]interface{})
+	data["to_clean"] = toClean
+	data["others"] = others
+	data["statuses"] = models.HousekeepingStatuses
+
+	render.Template(w, r, "admin-housekeeping.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+	})
+}
+
+//AdminPostHousekeeping sets the housekeeping status of a room
+func (m *Repository) AdminPostHousekeeping(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	roomID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	back := housekeepingURL(r.Form.Get("d"))
+
+	status := r.Form.Get("status")
+
+	if !isOneOf(status, models.HousekeepingStatuses) {
+		m.App.Session.Put(r.Context(), "error", "Invalid housekeeping status")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	err = m.DB.UpdateRoomHousekeepingStatus(roomID, status)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Room status saved")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRepository_AdminHousekeeping(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{"today", "/admin/housekeeping", http.StatusOK, "Departure"},
+		{"other-day", "/admin/housekeeping?d=2050-01-01", http.StatusOK, "out_of_order"},
+		{"invalid-day", "/admin/housekeeping?d=invalid", http.StatusSeeOther, ""},
+		{"database-error", "/admin/housekeeping?d=2040-01-01", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminHousekeeping)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+	}
+}
+
+func TestRepository_AdminPostHousekeeping(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{"clean", "1", url.Values{"status": {"clean"}}, http.StatusSeeOther, "/admin/housekeeping"},
+		{"out-of-order", "1", url.Values{"status": {"out_of_order"}, "d": {"2050-01-01"}}, http.StatusSeeOther, "/admin/housekeeping?d=2050-01-01"},
+		{"invalid-status", "1", url.Values{"status": {"sparkling"}}, http.StatusSeeOther, "/admin/housekeeping"},
+		{"invalid-room", "x", url.Values{"status": {"clean"}}, http.StatusBadRequest, ""},
+		{"database-error", "3", url.Values{"status": {"clean"}}, http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/housekeeping/"+tt.id, strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostHousekeeping)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
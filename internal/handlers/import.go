@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/yalagtyarzh/leafsite/internal/importer"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//maxImportSize is the largest CSV file accepted by the importer
+const maxImportSize = 10 << 20
+
+//AdminImport shows the form to import reservations from CSV
+func (m *Repository) AdminImport(w http.ResponseWriter, r *http.Request) {
+	stringMap := make(map[string]string)
+	stringMap["date_layout"] = importer.DefaultDateLayout
+	stringMap["dry_run"] = "1"
+
+	data := make(map[string]interface{})
+	data["fields"] = importer.Fields
+
+	render.Template(w, r, "admin-import.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+	})
+}
+
+//AdminPostImport validates an uploaded CSV file of reservations and imports it unless it is a dry run
+func (m *Repository) AdminPostImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	err := r.ParseMultipartForm(maxImportSize)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't read uploaded file")
+		http.Redirect(w, r, "/admin/import", http.StatusSeeOther)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Choose a CSV file to import")
+		http.Redirect(w, r, "/admin/import", http.StatusSeeOther)
+		return
+	}
+	defer file.Close()
+
+	stringMap := make(map[string]string)
+	mapping := make(importer.Mapping)
+	for _, f := range importer.Fields {
+		column := r.Form.Get(fmt.Sprintf("map_%s", f))
+		stringMap[fmt.Sprintf("map_%s", f)] = column
+		mapping[f] = column
+	}
+
+	opts := importer.Options{
+		Mapping:    mapping,
+		DateLayout: r.Form.Get("date_layout"),
+		DryRun:     r.Form.Get("dry_run") != "",
+	}
+
+	stringMap["date_layout"] = opts.DateLayout
+	if opts.DryRun {
+		stringMap["dry_run"] = "1"
+	}
+
+	data := make(map[string]interface{})
+	data["fields"] = importer.Fields
+
+	report, err := importer.Import(m.DB, file, opts)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Import failed: %s", err))
+
+		render.Template(w, r, "admin-import.page.tmpl", &models.TemplateData{
+			StringMap: stringMap,
+			Data:      data,
+		})
+		return
+	}
+
+	if report.Imported > 0 {
+		m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Imported %d reservations", report.Imported))
+		http.Redirect(w, r, "/admin/reservations-all", http.StatusSeeOther)
+		return
+	}
+
+	data["report"] = report
+
+	render.Template(w, r, "admin-import.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+	})
+}
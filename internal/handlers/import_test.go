@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRepository_AdminPostImport(t *testing.T) {
+	header := "first_name,last_name,email,start_date,end_date,room\n"
+
+	var theTests = []struct {
+		name               string
+		file               string
+		dryRun             bool
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name:               "dry-run",
+			file:               header + "John,Smith,john@smith.com,2029-01-01,2029-01-03,1\n",
+			dryRun:             true,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "import",
+			file:               header + "John,Smith,john@smith.com,2029-01-01,2029-01-03,General's Quarters\n",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations-all",
+		},
+		{
+			name:               "invalid-rows",
+			file:               header + "John,Smith,john,2029-01-01,2029-01-03,1\n",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "conflict-with-restriction",
+			file:               header + "John,Smith,john@smith.com,2030-01-01,2030-01-03,1\n",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "missing-column",
+			file:               "first_name,last_name\nJohn,Smith\n",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "taken-while-importing",
+			file:               header + "Taken,Smith,john@smith.com,2029-01-01,2029-01-03,1\n",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "no-file",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/import",
+		},
+	}
+
+	for _, tt := range theTests {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+
+		if tt.file != "" {
+			fw, _ := mw.CreateFormFile("file", "reservations.csv")
+			fw.Write([]byte(tt.file))
+		}
+
+		if tt.dryRun {
+			mw.WriteField("dry_run", "1")
+		}
+		mw.WriteField("date_layout", "2006-01-02")
+		mw.Close()
+
+		req, _ := http.NewRequest("POST", "/admin/import", &body)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostImport)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
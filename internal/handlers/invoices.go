@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/invoices"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+	"github.com/yalagtyarzh/leafsite/internal/urlsigner"
+)
+
+//documentsLinkLifetime is how long the links to invoices and receipts sent to guests work
+const documentsLinkLifetime = 90 * 24 * time.Hour
+
+//guestDocument is an invoice or receipt listed on the documents page of a reservation
+type guestDocument struct {
+	Name   string
+	Date   time.Time
+	Amount int
+	Link   string
+}
+
+//writePDF sends a PDF document to be shown in the browser or saved under filename
+func writePDF(w http.ResponseWriter, filename string, data []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+//documentsLink returns a signed link to the page where the guest of a reservation gets its invoices and
+//receipts
+func (m *Repository) documentsLink(resID int) string {
+	return m.App.Signer.Sign(fmt.Sprintf("%s/reservations/%d/documents", m.App.URL, resID),
+		time.Now().Add(documentsLinkLifetime))
+}
+
+//verifyGuestLink checks the signature of a link sent to a guest, sending the guest home with an error if it
+//isn't valid
+func (m *Repository) verifyGuestLink(w http.ResponseWriter, r *http.Request) bool {
+	err := m.App.Signer.Verify(r.RequestURI)
+	if err == urlsigner.ErrExpired {
+		m.App.Session.Put(r.Context(), "error", "This link has expired, please contact us for a new one")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return false
+	}
+
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid link")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return false
+	}
+
+	return true
+}
+
+//reservationInvoice returns the invoice with the number in the URL, false if it wasn't issued for the
+//reservation
+func (m *Repository) reservationInvoice(r *http.Request, resID int) (models.Invoice, bool, error) {
+	number, err := strconv.Atoi(chi.URLParam(r, "number"))
+	if err != nil {
+		return models.Invoice{}, false, nil
+	}
+
+	inv, err := m.DB.GetInvoiceByNumber(number)
+	if err != nil {
+		return inv, false, err
+	}
+
+	return inv, inv.ReservationID == resID, nil
+}
+
+//reservationReceipt returns the reservation and the succeeded payment with the id in the URL, false if the
+//reservation has no such payment
+func (m *Repository) reservationReceipt(r *http.Request, resID int) (models.Reservation, models.FolioEntry, bool, error) {
+	res, err := m.DB.GetReservationByID(resID)
+	if err != nil {
+		return res, models.FolioEntry{}, false, err
+	}
+
+	folio, err := m.folio(res)
+	if err != nil {
+		return res, models.FolioEntry{}, false, err
+	}
+
+	paymentID, _ := strconv.Atoi(chi.URLParam(r, "payment"))
+	payment, ok := succeededPayment(folio, paymentID)
+
+	return res, payment, ok, nil
+}
+
+//AdminPostInvoice issues an invoice for what is charged on the folio of a reservation, to the guest or the
+//company the guest travels for, and emails it to the guest if asked to
+func (m *Repository) AdminPostInvoice(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	showURL := showReservationURL(chi.URLParam(r, "src"), id, r.Form.Get("year"), r.Form.Get("month"))
+
+	res, err := m.DB.GetReservationByID(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	folio, err := m.folio(res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if folio.Charged() <= 0 {
+		m.App.Session.Put(r.Context(), "error", "There is nothing to invoice")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	buyer := models.BillingDetails{
+		Name:      strings.TrimSpace(r.Form.Get("buyer_name")),
+		Company:   strings.TrimSpace(r.Form.Get("buyer_company")),
+		Address:   strings.TrimSpace(r.Form.Get("buyer_address")),
+		VATNumber: strings.TrimSpace(r.Form.Get("buyer_vat_number")),
+	}
+	if buyer.Name == "" {
+		buyer.Name = fmt.Sprintf("%s %s", res.FirstName, res.LastName)
+	}
+
+	inv, err := m.DB.InsertInvoice(invoices.New(folio, m.App.Company, buyer))
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	flash := fmt.Sprintf("Invoice %s issued", inv.Code())
+
+	if r.Form.Get("send") != "" {
+		htmlMessage := fmt.Sprintf(`
+			<strong>Invoice %s</strong><br>
+			Dear %s: <br>
+			Please find attached the invoice for your stay from %s to %s.<br>
+			You can also <a href="%s">download your invoices and receipts</a>.
+		`, inv.Code(), res.FirstName, res.StartDate.Format("2006-01-02"), res.EndDate.Format("2006-01-02"),
+			m.documentsLink(res.ID))
+
+		m.App.MailChan <- models.MailData{
+			To:       res.Email,
+			From:     "me@here.com",
+			Subject:  fmt.Sprintf("Invoice %s", inv.Code()),
+			Content:  htmlMessage,
+			Template: "basic.html",
+			Attachments: []models.MailAttachment{
+				{Name: inv.Code() + ".pdf", Data: invoices.Render(inv)},
+			},
+		}
+
+		flash = fmt.Sprintf("%s and sent to %s", flash, res.Email)
+	}
+
+	m.App.Session.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
+
+//writeInvoice sends the invoice of the reservation in the URL
+func (m *Repository) writeInvoice(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	inv, ok, err := m.reservationInvoice(r, id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if !ok {
+		helpers.ClientError(w, http.StatusNotFound)
+		return
+	}
+
+	writePDF(w, inv.Code()+".pdf", invoices.Render(inv))
+}
+
+//writeReceipt sends the receipt of a payment of the reservation in the URL
+func (m *Repository) writeReceipt(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	res, payment, ok, err := m.reservationReceipt(r, id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if !ok {
+		helpers.ClientError(w, http.StatusNotFound)
+		return
+	}
+
+	writePDF(w, invoices.ReceiptCode(payment)+".pdf", invoices.RenderReceipt(m.App.Company, res, payment))
+}
+
+//AdminInvoicePDF downloads an invoice of a reservation
+func (m *Repository) AdminInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	m.writeInvoice(w, r)
+}
+
+//AdminReceiptPDF downloads the receipt of a payment of a reservation
+func (m *Repository) AdminReceiptPDF(w http.ResponseWriter, r *http.Request) {
+	m.writeReceipt(w, r)
+}
+
+//AdminPostReceipt emails the receipt of a payment to the guest of a reservation
+func (m *Repository) AdminPostReceipt(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	showURL := showReservationURL(chi.URLParam(r, "src"), id, r.Form.Get("year"), r.Form.Get("month"))
+
+	res, payment, ok, err := m.reservationReceipt(r, id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if !ok {
+		m.App.Session.Put(r.Context(), "error", "Receipts are only given for succeeded payments")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	code := invoices.ReceiptCode(payment)
+
+	htmlMessage := fmt.Sprintf(`
+		<strong>Receipt %s</strong><br>
+		Dear %s: <br>
+		Thank you for your payment of %s for your stay from %s to %s, please find the receipt attached.<br>
+		You can also <a href="%s">download your invoices and receipts</a>.
+	`, code, res.FirstName, render.Price(payment.Amount, m.App.Currency), res.StartDate.Format("2006-01-02"),
+		res.EndDate.Format("2006-01-02"), m.documentsLink(res.ID))
+
+	m.App.MailChan <- models.MailData{
+		To:       res.Email,
+		From:     "me@here.com",
+		Subject:  fmt.Sprintf("Receipt %s", code),
+		Content:  htmlMessage,
+		Template: "basic.html",
+		Attachments: []models.MailAttachment{
+			{Name: code + ".pdf", Data: invoices.RenderReceipt(m.App.Company, res, payment)},
+		},
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Receipt %s sent to %s", code, res.Email))
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
+
+//GuestDocuments lists the invoices and receipts of a reservation for its guest, who gets there with a
+//signed link from an email
+func (m *Repository) GuestDocuments(w http.ResponseWriter, r *http.Request) {
+	if !m.verifyGuestLink(w, r) {
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	res, err := m.DB.GetReservationByID(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	folio, err := m.folio(res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	issued, err := m.DB.InvoicesForReservation(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	//the documents are linked for as long as the link to this page works
+	expires, _ := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	sign := func(format string, args ...interface{}) string {
+		return m.App.Signer.Sign(fmt.Sprintf(format, args...), time.Unix(expires, 0))
+	}
+
+	var documents []guestDocument
+	for _, inv := range issued {
+		documents = append(documents, guestDocument{
+			Name:   "Invoice " + inv.Code(),
+			Date:   inv.IssuedAt,
+			Amount: inv.Total,
+			Link:   sign("/reservations/%d/invoices/%d", id, inv.Number),
+		})
+	}
+	for _, e := range folio.Entries {
+		if e.Kind == models.FolioPayment && e.Status == models.PaymentSucceeded {
+			documents = append(documents, guestDocument{
+				Name:   "Receipt " + invoices.ReceiptCode(e),
+				Date:   e.CreatedAt,
+				Amount: e.Amount,
+				Link:   sign("/reservations/%d/receipts/%d", id, e.ID),
+			})
+		}
+	}
+
+	data := make(map[string]interface{})
+	data["reservation"] = res
+	data["documents"] = documents
+
+	render.Template(w, r, "guest-documents.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+//GuestInvoicePDF downloads an invoice of a reservation from a signed link
+func (m *Repository) GuestInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	if !m.verifyGuestLink(w, r) {
+		return
+	}
+
+	m.writeInvoice(w, r)
+}
+
+//GuestReceiptPDF downloads the receipt of a payment of a reservation from a signed link
+func (m *Repository) GuestReceiptPDF(w http.ResponseWriter, r *http.Request) {
+	if !m.verifyGuestLink(w, r) {
+		return
+	}
+
+	m.writeReceipt(w, r)
+}
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepository_AdminPostInvoice(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedSession    string
+	}{
+		{"to-guest", "1", url.Values{}, http.StatusSeeOther, "flash"},
+		{"to-company", "1", url.Values{"buyer_name": {"John Smith"}, "buyer_company": {"Acme"}, "buyer_address": {"1 Main Street\nSpringfield"}, "buyer_vat_number": {"GB999"}}, http.StatusSeeOther, "flash"},
+		{"send", "1", url.Values{"send": {"1"}}, http.StatusSeeOther, "flash"},
+		{"insert-error", "1", url.Values{"buyer_name": {"error"}}, http.StatusInternalServerError, ""},
+		{"invalid-id", "x", url.Values{}, http.StatusBadRequest, ""},
+		{"reservation-not-found", "1001", url.Values{}, http.StatusInternalServerError, ""},
+		{"folio-error", "999", url.Values{}, http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/reservations/all/"+tt.id+"/invoices", strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "all", "id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostInvoice)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedStatusCode == http.StatusSeeOther {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != "/admin/reservations/all/"+tt.id+"/show" {
+				t.Errorf("failed %s: expected location /admin/reservations/all/%s/show, but got %s", tt.name, tt.id, actualLoc.String())
+			}
+		}
+
+		if tt.expectedSession != "" && !session.Exists(req.Context(), tt.expectedSession) {
+			t.Errorf("failed %s: expected %s in session", tt.name, tt.expectedSession)
+		}
+
+		if tt.name == "send" && !strings.Contains(session.GetString(req.Context(), "flash"), "sent to") {
+			t.Errorf("failed %s: expected the flash to say the invoice was sent", tt.name)
+		}
+	}
+}
+
+func TestRepository_AdminInvoicePDF(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		number             string
+		expectedStatusCode int
+	}{
+		{"invoice", "1", "1", http.StatusOK},
+		{"other-reservation", "2", "1", http.StatusNotFound},
+		{"invalid-number", "1", "x", http.StatusNotFound},
+		{"invalid-id", "x", "1", http.StatusBadRequest},
+		{"invoice-error", "1", "1001", http.StatusInternalServerError},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/reservations/all/"+tt.id+"/invoices/"+tt.number, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "all", "id": tt.id, "number": tt.number})
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminInvoicePDF)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedStatusCode == http.StatusOK {
+			if rr.Header().Get("Content-Type") != "application/pdf" {
+				t.Errorf("failed %s: expected a PDF, but got %s", tt.name, rr.Header().Get("Content-Type"))
+			}
+
+			if !strings.Contains(rr.Header().Get("Content-Disposition"), "INV-000001.pdf") {
+				t.Errorf("failed %s: expected the invoice number as filename, but got %s", tt.name, rr.Header().Get("Content-Disposition"))
+			}
+
+			if !strings.HasPrefix(rr.Body.String(), "%PDF-") || !strings.Contains(rr.Body.String(), "INV-000001") {
+				t.Errorf("failed %s: expected the invoice as PDF", tt.name)
+			}
+		}
+	}
+}
+
+func TestRepository_AdminReceiptPDF(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		payment            string
+		expectedStatusCode int
+	}{
+		{"online-payment", "1", "1", http.StatusOK},
+		{"manual-payment", "1", "4", http.StatusOK},
+		{"pending-payment", "1", "3", http.StatusNotFound},
+		{"charge", "1", "2", http.StatusNotFound},
+		{"refund", "1", "5", http.StatusNotFound},
+		{"invalid-id", "x", "1", http.StatusBadRequest},
+		{"reservation-not-found", "1001", "1", http.StatusInternalServerError},
+		{"folio-error", "999", "1", http.StatusInternalServerError},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/reservations/all/"+tt.id+"/receipts/"+tt.payment, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "all", "id": tt.id, "payment": tt.payment})
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminReceiptPDF)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedStatusCode == http.StatusOK && !strings.Contains(rr.Body.String(), "RCT-00000"+tt.payment) {
+			t.Errorf("failed %s: expected the receipt of payment %s", tt.name, tt.payment)
+		}
+	}
+}
+
+func TestRepository_AdminPostReceipt(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		payment            string
+		expectedStatusCode int
+		expectedSession    string
+	}{
+		{"sent", "1", "1", http.StatusSeeOther, "flash"},
+		{"pending-payment", "1", "3", http.StatusSeeOther, "error"},
+		{"invalid-id", "x", "1", http.StatusBadRequest, ""},
+		{"reservation-not-found", "1001", "1", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/reservations/all/"+tt.id+"/receipts/"+tt.payment, strings.NewReader(""))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "all", "id": tt.id, "payment": tt.payment})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostReceipt)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedStatusCode == http.StatusSeeOther {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != "/admin/reservations/all/"+tt.id+"/show" {
+				t.Errorf("failed %s: expected location /admin/reservations/all/%s/show, but got %s", tt.name, tt.id, actualLoc.String())
+			}
+		}
+
+		if tt.expectedSession != "" && !session.Exists(req.Context(), tt.expectedSession) {
+			t.Errorf("failed %s: expected %s in session", tt.name, tt.expectedSession)
+		}
+	}
+}
+
+func TestRepository_GuestDocuments(t *testing.T) {
+	valid := time.Now().Add(time.Hour)
+
+	var theTests = []struct {
+		name               string
+		id                 string
+		requestURI         string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{"valid-link", "1", testApp.Signer.Sign("/reservations/1/documents", valid), http.StatusOK, ""},
+		{"expired-link", "1", testApp.Signer.Sign("/reservations/1/documents", time.Now().Add(-time.Minute)), http.StatusSeeOther, "/"},
+		{"unsigned-link", "1", "/reservations/1/documents", http.StatusSeeOther, "/"},
+		{"tampered-link", "2", strings.Replace(testApp.Signer.Sign("/reservations/1/documents", valid), "/1/", "/2/", 1), http.StatusSeeOther, "/"},
+		{"reservation-not-found", "1001", testApp.Signer.Sign("/reservations/1001/documents", valid), http.StatusInternalServerError, ""},
+		{"folio-error", "999", testApp.Signer.Sign("/reservations/999/documents", valid), http.StatusInternalServerError, ""},
+		{"invoices-error", "998", testApp.Signer.Sign("/reservations/998/documents", valid), http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", tt.requestURI, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+		req.RequestURI = tt.requestURI
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.GuestDocuments)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+
+			if !session.Exists(req.Context(), "error") {
+				t.Errorf("failed %s: expected error in session", tt.name)
+			}
+		}
+
+		if tt.expectedStatusCode == http.StatusOK {
+			body := rr.Body.String()
+			for _, s := range []string{"Invoice INV-000001", "Receipt RCT-000001", "Receipt RCT-000004", "/reservations/1/invoices/1?expires="} {
+				if !strings.Contains(body, s) {
+					t.Errorf("failed %s: expected %s on the page", tt.name, s)
+				}
+			}
+
+			if strings.Contains(body, "RCT-000003") {
+				t.Errorf("failed %s: pending payments should have no receipt", tt.name)
+			}
+		}
+	}
+}
+
+func TestRepository_GuestInvoicePDF(t *testing.T) {
+	valid := time.Now().Add(time.Hour)
+
+	var theTests = []struct {
+		name               string
+		id                 string
+		requestURI         string
+		expectedStatusCode int
+	}{
+		{"valid-link", "1", testApp.Signer.Sign("/reservations/1/invoices/1", valid), http.StatusOK},
+		{"expired-link", "1", testApp.Signer.Sign("/reservations/1/invoices/1", time.Now().Add(-time.Minute)), http.StatusSeeOther},
+		{"tampered-link", "1", strings.Replace(testApp.Signer.Sign("/reservations/1/invoices/1", valid), "/invoices/1", "/invoices/2", 1), http.StatusSeeOther},
+		{"other-reservation", "2", testApp.Signer.Sign("/reservations/2/invoices/1", valid), http.StatusNotFound},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", tt.requestURI, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id, "number": "1"})
+		req.RequestURI = tt.requestURI
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.GuestInvoicePDF)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedStatusCode == http.StatusOK && rr.Header().Get("Content-Type") != "application/pdf" {
+			t.Errorf("failed %s: expected a PDF, but got %s", tt.name, rr.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestRepository_GuestReceiptPDF(t *testing.T) {
+	valid := time.Now().Add(time.Hour)
+
+	var theTests = []struct {
+		name               string
+		payment            string
+		requestURI         string
+		expectedStatusCode int
+	}{
+		{"valid-link", "1", testApp.Signer.Sign("/reservations/1/receipts/1", valid), http.StatusOK},
+		{"unsigned-link", "1", "/reservations/1/receipts/1", http.StatusSeeOther},
+		{"pending-payment", "3", testApp.Signer.Sign("/reservations/1/receipts/3", valid), http.StatusNotFound},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", tt.requestURI, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": "1", "payment": tt.payment})
+		req.RequestURI = tt.requestURI
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.GuestReceiptPDF)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+	}
+}
+
+func TestRepository_AdminShowReservationInvoices(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{"invoice", "/admin/reservations/all/1/show", http.StatusOK, "/admin/reservations/all/1/invoices/1\">INV-000001</a>"},
+		{"receipt", "/admin/reservations/all/1/show", http.StatusOK, "/admin/reservations/all/1/receipts/1\">receipt</a>"},
+		{"issue-form", "/admin/reservations/all/1/show", http.StatusOK, "Issue Invoice"},
+		{"invoices-error", "/admin/reservations/all/998/show", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.RequestURI = tt.url
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminShowReservation)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+	}
+}
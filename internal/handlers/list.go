@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//reservationListPageSize is how many reservations a list shows per page
+const reservationListPageSize = 25
+
+//guestListPageSize is how many guests a list shows per page
+const guestListPageSize = 50
+
+//listQuery is the query a page of an admin list was read from, so that links to other pages and sort orders
+//keep the filters
+type listQuery struct {
+	Path  string
+	Query url.Values
+	Page  models.Page
+	Total int
+}
+
+//reservationList is a page of an admin reservation list
+type reservationList struct {
+	listQuery
+	Src          string
+	Reservations []models.Reservation
+	Rooms        []models.Room
+	Tags         []string
+}
+
+//Get returns a value of the query
+func (l listQuery) Get(key string) string {
+	return l.Query.Get(key)
+}
+
+//Pages returns the number of pages
+func (l listQuery) Pages() int {
+	if l.Total == 0 {
+		return 1
+	}
+
+	return (l.Total + l.Page.Size - 1) / l.Page.Size
+}
+
+//URL returns the link to the list with some query values replaced
+func (l listQuery) URL(pairs ...string) string {
+	q := make(url.Values)
+	for k, v := range l.Query {
+		q[k] = v
+	}
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			q.Del(pairs[i])
+		} else {
+			q.Set(pairs[i], pairs[i+1])
+		}
+	}
+
+	if len(q) == 0 {
+		return l.Path
+	}
+
+	return l.Path + "?" + q.Encode()
+}
+
+//PageURL returns the link to a page of the list
+func (l listQuery) PageURL(n int) string {
+	if n <= 1 {
+		return l.URL("page", "")
+	}
+
+	return l.URL("page", strconv.Itoa(n))
+}
+
+//SortURL returns the link sorting the list by a column, reversing the order if it is sorted by it already
+func (l listQuery) SortURL(column string) string {
+	dir := ""
+	if l.Page.Sort == column && !l.Page.Desc {
+		dir = "desc"
+	}
+
+	return l.URL("sort", column, "dir", dir, "page", "")
+}
+
+//SortMark returns an arrow showing the order of the column the list is sorted by
+func (l listQuery) SortMark(column string) string {
+	if l.Page.Sort != column {
+		return ""
+	}
+
+	if l.Page.Desc {
+		return "▼"
+	}
+
+	return "▲"
+}
+
+//listPage reads the page and sort order of a list from the query, sorting by column unless told otherwise
+func listPage(r *http.Request, size int, column string) models.Page {
+	p := models.Page{
+		Number: 1,
+		Size:   size,
+		Sort:   r.URL.Query().Get("sort"),
+		Desc:   r.URL.Query().Get("dir") == "desc",
+	}
+
+	if n, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && n > 1 {
+		p.Number = n
+	}
+
+	if p.Sort == "" {
+		p.Sort = column
+	}
+
+	return p
+}
+
+//reservationList shows a page of the reservations matching the filter of the query
+func (m *Repository) reservationList(w http.ResponseWriter, r *http.Request, src, tmpl string, processed int) {
+	f, err := reservationFilter(r)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid filter")
+		http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+		return
+	}
+
+	if processed != models.ProcessedAny {
+		f.Processed = processed
+	}
+
+	p := listPage(r, reservationListPageSize, "start_date")
+
+	reservations, total, err := m.DB.SearchReservations(f, p)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	rooms, err := m.DB.AllRooms()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	tags, err := m.DB.AllTags()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["list"] = reservationList{
+		listQuery:    listQuery{Path: r.URL.Path, Query: r.URL.Query(), Page: p, Total: total},
+		Src:          src,
+		Reservations: reservations,
+		Rooms:        rooms,
+		Tags:         tags,
+	}
+
+	render.Template(w, r, tmpl, &models.TemplateData{
+		Data: data,
+	})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestRepository_AdminAllReservations(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+		expectedLocation   string
+		expectedBody       string
+	}{
+		{"first-page", "/admin/reservations-all", http.StatusOK, "", "Page 1 of 3"},
+		{"second-page", "/admin/reservations-all?page=2&sort=last_name&dir=desc", http.StatusOK, "", "Page 2 of 3"},
+		{"filtered", "/admin/reservations-all?start=2050-01-01&end=2050-02-01&room_id=1&status=confirmed&processed=done&q=smith&tag=VIP", http.StatusOK, "", "60 reservations"},
+		{"invalid-filter", "/admin/reservations-all?start=invalid", http.StatusSeeOther, "/admin/reservations-all", ""},
+		{"query-error", "/admin/reservations-all?room_id=3", http.StatusInternalServerError, "", ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminAllReservations)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+
+		if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+	}
+}
+
+func TestListQuery_URLs(t *testing.T) {
+	q, _ := url.ParseQuery("q=smith&sort=last_name&page=2")
+	l := listQuery{
+		Path:  "/admin/reservations-all",
+		Query: q,
+		Page:  models.Page{Number: 2, Size: 25, Sort: "last_name"},
+		Total: 51,
+	}
+
+	if l.Pages() != 3 {
+		t.Errorf("expected 3 pages, but got %d", l.Pages())
+	}
+
+	var theTests = []struct {
+		name     string
+		got      string
+		expected string
+	}{
+		{"first-page", l.PageURL(1), "/admin/reservations-all?q=smith&sort=last_name"},
+		{"next-page", l.PageURL(3), "/admin/reservations-all?page=3&q=smith&sort=last_name"},
+		{"reverse-sort", l.SortURL("last_name"), "/admin/reservations-all?dir=desc&q=smith&sort=last_name"},
+		{"other-sort", l.SortURL("room"), "/admin/reservations-all?q=smith&sort=room"},
+	}
+
+	for _, tt := range theTests {
+		if tt.got != tt.expected {
+			t.Errorf("failed %s: expected %s, but got %s", tt.name, tt.expected, tt.got)
+		}
+	}
+
+	if l.SortMark("last_name") != "▲" || l.SortMark("room") != "" {
+		t.Error("wrong sort marks")
+	}
+}
+
+func TestListPage(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/admin/reservations-all?page=-1", nil)
+	p := listPage(req, reservationListPageSize, "start_date")
+	if p.Number != 1 || p.Sort != "start_date" || p.Desc {
+		t.Errorf("expected first page by arrival, but got %+v", p)
+	}
+
+	req, _ = http.NewRequest("GET", "/admin/reservations-all?page=4&sort=room&dir=desc", nil)
+	p = listPage(req, reservationListPageSize, "start_date")
+	if p.Number != 4 || p.Sort != "room" || !p.Desc {
+		t.Errorf("expected fourth page by room descending, but got %+v", p)
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/forms"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//isOneOf returns true if s is one of values
+func isOneOf(s string, values []string) bool {
+	for _, v := range values {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+//AdminMaintenance shows the maintenance tickets with the status of the query, or the ones not closed yet
+func (m *Repository) AdminMaintenance(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !isOneOf(status, models.MaintenanceStatuses) {
+		m.App.Session.Put(r.Context(), "error", "Unknown status")
+		http.Redirect(w, r, "/admin/maintenance", http.StatusSeeOther)
+		return
+	}
+
+	tickets, err := m.DB.MaintenanceTickets(status)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	stringMap := make(map[string]string)
+	stringMap["status"] = status
+
+	data := make(map[string]interface{})
+	data["tickets"] = tickets
+	data["statuses"] = models.MaintenanceStatuses
+
+	render.Template(w, r, "admin-maintenance.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+	})
+}
+
+//renderMaintenanceTicket shows the form of a maintenance ticket with the rooms and users to choose from
+func (m *Repository) renderMaintenanceTicket(w http.ResponseWriter, r *http.Request, t models.MaintenanceTicket, form *forms.Form) {
+	rooms, err := m.DB.AllRooms()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	users, err := m.DB.ListUsers()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	stringMap := make(map[string]string)
+	if t.Offline() {
+		stringMap["offline_start"] = t.OfflineStart.Format("2006-01-02")
+		stringMap["offline_end"] = t.OfflineEnd.Format("2006-01-02")
+	} else if form.Has("offline_start") || form.Has("offline_end") {
+		stringMap["offline_start"] = form.Get("offline_start")
+		stringMap["offline_end"] = form.Get("offline_end")
+	}
+
+	data := make(map[string]interface{})
+	data["ticket"] = t
+	data["rooms"] = rooms
+	data["users"] = users
+	data["priorities"] = models.MaintenancePriorities
+	data["statuses"] = models.MaintenanceStatuses
+
+	render.Template(w, r, "admin-maintenance-show.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+		Form:      form,
+	})
+}
+
+//AdminShowMaintenanceTicket shows the form for a new or existing maintenance ticket
+func (m *Repository) AdminShowMaintenanceTicket(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	t := models.MaintenanceTicket{
+		Priority: models.MaintenanceNormal,
+		Status:   models.MaintenanceOpen,
+	}
+
+	if id > 0 {
+		t, err = m.DB.GetMaintenanceTicketByID(id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	m.renderMaintenanceTicket(w, r, t, forms.New(nil))
+}
+
+//AdminPostMaintenanceTicket handles the posting of a maintenance ticket form
+func (m *Repository) AdminPostMaintenanceTicket(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("room_id", "title", "priority", "status")
+
+	roomID, err := strconv.Atoi(r.Form.Get("room_id"))
+	if err != nil || roomID < 1 {
+		form.Errors.Add("room_id", "Choose a room")
+	}
+
+	assigneeID, _ := strconv.Atoi(r.Form.Get("assignee_id"))
+
+	t := models.MaintenanceTicket{
+		ID:          id,
+		RoomID:      roomID,
+		Title:       strings.TrimSpace(r.Form.Get("title")),
+		Description: strings.TrimSpace(r.Form.Get("description")),
+		Priority:    r.Form.Get("priority"),
+		Status:      r.Form.Get("status"),
+		AssigneeID:  assigneeID,
+	}
+
+	if !isOneOf(t.Priority, models.MaintenancePriorities) {
+		form.Errors.Add("priority", "Unknown priority")
+	}
+
+	if !isOneOf(t.Status, models.MaintenanceStatuses) {
+		form.Errors.Add("status", "Unknown status")
+	}
+
+	offlineStart, offlineEnd := r.Form.Get("offline_start"), r.Form.Get("offline_end")
+	if offlineStart != "" || offlineEnd != "" {
+		start, startErr := time.Parse("2006-01-02", offlineStart)
+		end, endErr := time.Parse("2006-01-02", offlineEnd)
+
+		switch {
+		case startErr != nil:
+			form.Errors.Add("offline_start", "Invalid date")
+		case endErr != nil:
+			form.Errors.Add("offline_end", "Invalid date")
+		case end.Before(start):
+			form.Errors.Add("offline_end", "Room can't go back online before it is taken offline")
+		default:
+			t.OfflineStart = start
+			t.OfflineEnd = end
+		}
+	}
+
+	if !form.Valid() {
+		m.renderMaintenanceTicket(w, r, t, form)
+		return
+	}
+
+	ok := true
+	if id > 0 {
+		ok, err = m.DB.UpdateMaintenanceTicket(t)
+	} else {
+		_, ok, err = m.DB.InsertMaintenanceTicket(t)
+	}
+
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if !ok {
+		form.Errors.Add("offline_start", "Room is booked or blocked during those dates")
+		m.renderMaintenanceTicket(w, r, t, form)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/maintenance", http.StatusSeeOther)
+}
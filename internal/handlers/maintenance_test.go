@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRepository_AdminMaintenance(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{"not-closed", "/admin/maintenance", http.StatusOK, "Leaking tap"},
+		{"closed", "/admin/maintenance?status=closed", http.StatusOK, "General&#39;s Quarters"},
+		{"unknown-status", "/admin/maintenance?status=lost", http.StatusSeeOther, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminMaintenance)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+	}
+}
+
+func TestRepository_AdminShowMaintenanceTicket(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{"new", "0", http.StatusOK, "Take Room Offline"},
+		{"offline", "1", http.StatusOK, "2050-01-03"},
+		{"closed", "2", http.StatusOK, "closed"},
+		{"invalid-id", "x", http.StatusBadRequest, ""},
+		{"database-error", "3", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/maintenance/"+tt.id, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminShowMaintenanceTicket)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+	}
+}
+
+func TestRepository_AdminPostMaintenanceTicket(t *testing.T) {
+	ticket := func(pairs ...string) url.Values {
+		v := url.Values{
+			"room_id":  {"1"},
+			"title":    {"Broken window"},
+			"priority": {"urgent"},
+			"status":   {"open"},
+		}
+		for i := 0; i+1 < len(pairs); i += 2 {
+			v.Set(pairs[i], pairs[i+1])
+		}
+		return v
+	}
+
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+		expectedBody       string
+	}{
+		{"new", "0", ticket(), http.StatusSeeOther, "/admin/maintenance", ""},
+		{"new-offline", "0", ticket("offline_start", "2050-01-01", "offline_end", "2050-01-03"), http.StatusSeeOther, "/admin/maintenance", ""},
+		{"update", "1", ticket("assignee_id", "1"), http.StatusSeeOther, "/admin/maintenance", ""},
+		{"close", "1", ticket("status", "closed", "room_id", "2", "offline_start", "2050-01-01", "offline_end", "2050-01-03"), http.StatusSeeOther, "/admin/maintenance", ""},
+		{"room-unavailable", "0", ticket("room_id", "2", "offline_start", "2050-01-01", "offline_end", "2050-01-03"), http.StatusOK, "", "Room is booked or blocked during those dates"},
+		{"missing-title", "0", ticket("title", ""), http.StatusOK, "", "This field cannot be blank"},
+		{"invalid-room", "0", ticket("room_id", "x"), http.StatusOK, "", "Choose a room"},
+		{"unknown-priority", "0", ticket("priority", "whenever"), http.StatusOK, "", "Unknown priority"},
+		{"unknown-status", "0", ticket("status", "lost"), http.StatusOK, "", "Unknown status"},
+		{"invalid-start", "0", ticket("offline_start", "x", "offline_end", "2050-01-03"), http.StatusOK, "", "Invalid date"},
+		{"missing-end", "0", ticket("offline_start", "2050-01-01"), http.StatusOK, "", "Invalid date"},
+		{"end-before-start", "0", ticket("offline_start", "2050-01-03", "offline_end", "2050-01-02"), http.StatusOK, "", "Room can&#39;t go back online before"},
+		{"invalid-id", "x", ticket(), http.StatusBadRequest, "", ""},
+		{"database-error", "1", ticket("title", "error"), http.StatusInternalServerError, "", ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/maintenance/"+tt.id, strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostMaintenanceTicket)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+
+		if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+	}
+}
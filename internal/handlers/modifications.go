@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+	"github.com/yalagtyarzh/leafsite/internal/repository"
+)
+
+//AdminPostReservationStay changes the dates and room of a reservation if the room is available for them
+func (m *Repository) AdminPostReservationStay(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	src := chi.URLParam(r, "src")
+	showURL := fmt.Sprintf("/admin/reservations/%s/%d/show", src, id)
+	if year := r.Form.Get("year"); year != "" {
+		showURL = fmt.Sprintf("%s?y=%s&m=%s", showURL, year, r.Form.Get("month"))
+	}
+
+	res, err := m.DB.GetReservationByID(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if res.Status == models.ReservationCancelled {
+		m.App.Session.Put(r.Context(), "error", "Cancelled reservations can't be changed")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	layout := "2006-01-02"
+
+	startDate, err := time.Parse(layout, r.Form.Get("start_date"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid arrival date")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	endDate, err := time.Parse(layout, r.Form.Get("end_date"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid departure date")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	if !endDate.After(startDate) {
+		m.App.Session.Put(r.Context(), "error", "Departure must be after arrival")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	roomID, err := strconv.Atoi(r.Form.Get("room_id"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid room")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	room, err := m.DB.GetRoomByID(roomID)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid room")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	if startDate.Equal(res.StartDate) && endDate.Equal(res.EndDate) && roomID == res.RoomID {
+		m.App.Session.Put(r.Context(), "warning", "Nothing to change")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	freed := models.RoomRestriction{
+		RoomID:    res.RoomID,
+		StartDate: res.StartDate,
+		EndDate:   res.EndDate,
+	}
+
+	//a plan is only kept in the room it was sold for, elsewhere the stay goes back to the room price
+	planID := res.RatePlanID
+	if roomID != res.RoomID {
+		planID = 0
+	}
+
+	res.StartDate = startDate
+	res.EndDate = endDate
+	res.RoomID = roomID
+	res.Room = room
+
+	room, err = m.applyRatePlan(&res, room, planID)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	//a redeemed code keeps its discount, without checking its conditions again
+	var promo models.PromoCode
+	if res.PromoCodeID > 0 {
+		promo, err = m.DB.GetPromoCodeByID(res.PromoCodeID)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	extras, err := m.DB.Extras()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	//extras priced per night follow the new stay, their inventory is checked again when it is saved
+	repriceExtras(&res, extras)
+
+	err = m.rateStay(&res, room, freed)
+	if err == nil {
+		err = m.priceStay(&res, room, promo)
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	changed, err := m.DB.ChangeReservationStay(res)
+	if errors.Is(err, repository.ErrExtraSoldOut) {
+		m.App.Session.Put(r.Context(), "error", "Not enough of an extra booked is left for these nights")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if !changed {
+		m.App.Session.Put(r.Context(), "error", "Room is not available for these dates")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	m.notifyWaitlist(freed.RoomID, freed.StartDate, freed.EndDate)
+
+	htmlMessage := fmt.Sprintf(`
+		<strong>Reservation Updated</strong><br>
+		Dear %s: <br>
+		Your reservation has been changed. You are now staying in %s from %s to %s.<br>
+		Total: %s
+	`, res.FirstName, room.RoomName, startDate.Format(layout), endDate.Format(layout),
+		render.Price(res.TotalPrice, m.App.Currency))
+
+	m.App.MailChan <- models.MailData{
+		To:       res.Email,
+		From:     "me@here.com",
+		Subject:  "Reservation Updated",
+		Content:  htmlMessage,
+		Template: "basic.html",
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Reservation changed")
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
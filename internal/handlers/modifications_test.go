@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepository_AdminPostReservationStay(t *testing.T) {
+	today := time.Now().Truncate(24 * time.Hour)
+	tomorrow := today.AddDate(0, 0, 1).Format("2006-01-02")
+	inThreeDays := today.AddDate(0, 0, 3).Format("2006-01-02")
+
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+		expectedError      string
+	}{
+		{
+			name: "valid-change",
+			id:   "1",
+			postedData: url.Values{
+				"start_date": {"2029-01-01"},
+				"end_date":   {"2029-01-03"},
+				"room_id":    {"2"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations/all/1/show",
+		},
+		{
+			name: "valid-change-from-cal",
+			id:   "1",
+			postedData: url.Values{
+				"start_date": {"2029-01-01"},
+				"end_date":   {"2029-01-03"},
+				"room_id":    {"1"},
+				"year":       {"2029"},
+				"month":      {"01"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations/all/1/show?y=2029&m=01",
+		},
+		{
+			name: "not-available",
+			id:   "1",
+			postedData: url.Values{
+				"start_date": {"2030-01-01"},
+				"end_date":   {"2030-01-03"},
+				"room_id":    {"1"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations/all/1/show",
+		},
+		{
+			name: "extra-sold-out",
+			id:   "1",
+			postedData: url.Values{
+				"start_date": {"2029-02-01"},
+				"end_date":   {"2029-02-03"},
+				"room_id":    {"1"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations/all/1/show",
+			expectedError:      "Not enough of an extra booked is left for these nights",
+		},
+		{
+			name: "cancelled-reservation",
+			id:   "100",
+			postedData: url.Values{
+				"start_date": {"2029-01-01"},
+				"end_date":   {"2029-01-03"},
+				"room_id":    {"1"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations/all/100/show",
+		},
+		{
+			name: "departure-before-arrival",
+			id:   "1",
+			postedData: url.Values{
+				"start_date": {"2029-01-03"},
+				"end_date":   {"2029-01-01"},
+				"room_id":    {"1"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations/all/1/show",
+		},
+		{
+			name: "invalid-arrival",
+			id:   "1",
+			postedData: url.Values{
+				"start_date": {"invalid"},
+				"end_date":   {"2029-01-03"},
+				"room_id":    {"1"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations/all/1/show",
+		},
+		{
+			name: "invalid-room",
+			id:   "1",
+			postedData: url.Values{
+				"start_date": {"2029-01-01"},
+				"end_date":   {"2029-01-03"},
+				"room_id":    {"5"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations/all/1/show",
+		},
+		{
+			name: "nothing-changed",
+			id:   "1",
+			postedData: url.Values{
+				"start_date": {tomorrow},
+				"end_date":   {inThreeDays},
+				"room_id":    {"1"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/reservations/all/1/show",
+		},
+		{
+			name: "database-error",
+			id:   "1",
+			postedData: url.Values{
+				"start_date": {"2029-01-01"},
+				"end_date":   {"2029-01-03"},
+				"room_id":    {"1000"},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/reservations/all/"+tt.id+"/stay", strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "all", "id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostReservationStay)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+
+		if msg := session.PopString(ctx, "error"); tt.expectedError != "" && msg != tt.expectedError {
+			t.Errorf("failed %s: expected error %q, but got %q", tt.name, tt.expectedError, msg)
+		}
+	}
+}
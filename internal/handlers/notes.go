@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/pricing"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//maxTagLength is the longest tag accepted on a reservation
+const maxTagLength = 30
+
+//showReservationURL returns the link back to a reservation, keeping the calendar month it was opened from
+func showReservationURL(src string, id int, year, month string) string {
+	showURL := fmt.Sprintf("/admin/reservations/%s/%d/show", src, id)
+	if year != "" {
+		showURL = fmt.Sprintf("%s?y=%s&m=%s", showURL, year, month)
+	}
+
+	return showURL
+}
+
+//parseTags reads tags separated by commas, dropping empty ones and repeats that only differ by case
+func parseTags(s string) ([]string, error) {
+	var tags []string
+	seen := make(map[string]bool)
+
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.Join(strings.Fields(tag), " ")
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+
+		if len([]rune(tag)) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+//AdminPostReservationNote adds an internal note by the logged in user to a reservation
+func (m *Repository) AdminPostReservationNote(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	showURL := showReservationURL(chi.URLParam(r, "src"), id, r.Form.Get("year"), r.Form.Get("month"))
+
+	body := strings.TrimSpace(r.Form.Get("body"))
+	if body == "" {
+		m.App.Session.Put(r.Context(), "error", "Note can't be empty")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	err = m.DB.InsertReservationNote(models.ReservationNote{
+		ReservationID: id,
+		UserID:        m.App.Session.GetInt(r.Context(), "user_id"),
+		Body:          body,
+	})
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Note added")
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
+
+//AdminDeleteReservationNote deletes an internal note of a reservation
+func (m *Repository) AdminDeleteReservationNote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	noteID, err := strconv.Atoi(chi.URLParam(r, "note"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = m.DB.DeleteReservationNote(noteID, id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Note deleted")
+	showURL := showReservationURL(chi.URLParam(r, "src"), id, r.URL.Query().Get("y"), r.URL.Query().Get("m"))
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
+
+//AdminPostReservationTags replaces the tags of a reservation and charges its taxes again, as tags can exempt
+//it from some
+func (m *Repository) AdminPostReservationTags(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	showURL := showReservationURL(chi.URLParam(r, "src"), id, r.Form.Get("year"), r.Form.Get("month"))
+
+	tags, err := parseTags(r.Form.Get("tags"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Invalid tags: %s", err))
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	res, err := m.DB.GetReservationByID(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	//tags can exempt a stay from taxes, so the taxes are charged again for the price it was booked for
+	total := res.TotalPrice
+	res.Tags = tags
+	if res.Status != models.ReservationCancelled {
+		rules, err := m.DB.TaxRules()
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		res.Taxes, res.TotalPrice = pricing.Retax(rules, res)
+	}
+
+	err = m.DB.UpdateReservationTags(res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	flash := "Tags saved"
+	if res.TotalPrice != total {
+		flash = fmt.Sprintf("Tags saved, the total is now %s", render.Money(res.TotalPrice))
+	}
+
+	m.App.Session.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	var theTests = []struct {
+		name     string
+		input    string
+		expected []string
+		isError  bool
+	}{
+		{"empty", " , ", nil, false},
+		{"trimmed", " VIP ,late   arrival,dog", []string{"VIP", "late arrival", "dog"}, false},
+		{"repeated", "VIP, vip, Dog, dog", []string{"VIP", "Dog"}, false},
+		{"too-long", strings.Repeat("x", maxTagLength+1), nil, true},
+	}
+
+	for _, tt := range theTests {
+		tags, err := parseTags(tt.input)
+		if tt.isError {
+			if err == nil {
+				t.Errorf("failed %s: expected an error", tt.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("failed %s: %s", tt.name, err)
+		}
+
+		if !reflect.DeepEqual(tags, tt.expected) {
+			t.Errorf("failed %s: expected %v, but got %v", tt.name, tt.expected, tags)
+		}
+	}
+}
+
+func TestRepository_AdminPostReservationNote(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{"valid", "1", url.Values{"body": {"Guest brings a dog"}}, http.StatusSeeOther, "/admin/reservations/all/1/show"},
+		{"from-cal", "1", url.Values{"body": {"Late arrival"}, "year": {"2050"}, "month": {"01"}}, http.StatusSeeOther, "/admin/reservations/all/1/show?y=2050&m=01"},
+		{"empty", "1", url.Values{"body": {"  "}}, http.StatusSeeOther, "/admin/reservations/all/1/show"},
+		{"invalid-id", "x", url.Values{"body": {"Note"}}, http.StatusBadRequest, ""},
+		{"database-error", "1000", url.Values{"body": {"Note"}}, http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/reservations/all/"+tt.id+"/notes", strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "all", "id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostReservationNote)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
+
+func TestRepository_AdminDeleteReservationNote(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		note               string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{"valid", "1", "1", http.StatusSeeOther, "/admin/reservations/new/1/show"},
+		{"invalid-note", "1", "x", http.StatusBadRequest, ""},
+		{"database-error", "1000", "1", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/delete-reservation-note/new/"+tt.id+"/"+tt.note+"/do", nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "new", "id": tt.id, "note": tt.note})
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminDeleteReservationNote)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
+
+func TestRepository_AdminPostReservationTags(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		tags               string
+		expectedStatusCode int
+		expectedLocation   string
+		expectedFlash      string
+	}{
+		{"valid", "1", "VIP, dog", http.StatusSeeOther, "/admin/reservations/all/1/show", "Tags saved"},
+		{"cleared", "1", "", http.StatusSeeOther, "/admin/reservations/all/1/show", "Tags saved"},
+		{"tax-exempt", "1", "VIP, resident", http.StatusSeeOther, "/admin/reservations/all/1/show", "Tags saved, the total is now 190.00"},
+		{"cancelled", "100", "resident", http.StatusSeeOther, "/admin/reservations/all/100/show", "Tags saved"},
+		{"too-long", "1", strings.Repeat("x", maxTagLength+1), http.StatusSeeOther, "/admin/reservations/all/1/show", ""},
+		{"missing-reservation", "1001", "VIP", http.StatusInternalServerError, "", ""},
+		{"database-error", "1000", "VIP", http.StatusInternalServerError, "", ""},
+	}
+
+	for _, tt := range theTests {
+		postedData := url.Values{"tags": {tt.tags}}
+		req, _ := http.NewRequest("POST", "/admin/reservations/all/"+tt.id+"/tags", strings.NewReader(postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "all", "id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostReservationTags)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+
+		if msg := session.PopString(ctx, "flash"); msg != tt.expectedFlash {
+			t.Errorf("failed %s: expected flash %q, but got %q", tt.name, tt.expectedFlash, msg)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/payments"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//maxWebhookSize is the largest webhook payload accepted from the payment provider
+const maxWebhookSize = 64 << 10
+
+//parseMoney reads an amount like 12.50 into minor currency units
+func parseMoney(s string) (int, error) {
+	whole, fraction, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if whole == "" || len(whole) > 9 || len(fraction) > 2 || strings.Trim(whole+fraction, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	units, err := strconv.Atoi(whole)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	cents, _ := strconv.Atoi((fraction + "00")[:2])
+
+	return units*100 + cents, nil
+}
+
+//folio returns the folio of a reservation
+func (m *Repository) folio(res models.Reservation) (models.Folio, error) {
+	entries, err := m.DB.FolioEntries(res.ID)
+	if err != nil {
+		return models.Folio{}, err
+	}
+
+	return models.Folio{Reservation: res, Entries: entries}, nil
+}
+
+//requestPayment starts a payment of a reservation with the payment provider and enters it on the folio
+//as pending until the provider confirms it
+func (m *Repository) requestPayment(res models.Reservation, amount int, description string) (models.FolioEntry, error) {
+	if m.App.Payments == nil {
+		return models.FolioEntry{}, errors.New("no payment provider configured")
+	}
+
+	p, err := m.App.Payments.CreatePayment(payments.Payment{
+		ReservationID: res.ID,
+		Amount:        amount,
+		Description:   description,
+		Email:         res.Email,
+	})
+	if err != nil {
+		return models.FolioEntry{}, err
+	}
+
+	e := models.FolioEntry{
+		ReservationID: res.ID,
+		Kind:          models.FolioPayment,
+		Description:   description,
+		Amount:        amount,
+		Status:        p.Status,
+		Provider:      m.App.Payments.Name(),
+		Reference:     p.Reference,
+		CheckoutURL:   p.CheckoutURL,
+	}
+
+	e.ID, err = m.DB.InsertFolioEntry(e)
+	if err != nil {
+		return models.FolioEntry{}, err
+	}
+
+	return e, nil
+}
+
+//recordPayment records the status of a payment reported by the payment provider
+func (m *Repository) recordPayment(e payments.Event) error {
+	updated, err := m.DB.UpdatePaymentStatus(m.App.Payments.Name(), e.Reference, e.Status)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		m.App.InfoLog.Printf("payment %s is not pending, ignoring %s", e.Reference, e.Status)
+	}
+
+	return nil
+}
+
+//PaymentWebhook receives the asynchronous confirmations of payments from the payment provider
+func (m *Repository) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	if m.App.Payments == nil {
+		helpers.ClientError(w, http.StatusNotFound)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	event, err := m.App.Payments.ParseWebhook(payload, r.Header)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = m.recordPayment(event)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+//fakePayment returns the fake provider and the payment of the request, or false if it answered the
+//request already
+func (m *Repository) fakePayment(w http.ResponseWriter, r *http.Request) (*payments.Fake, payments.Payment, bool) {
+	fake, ok := m.App.Payments.(*payments.Fake)
+	if !ok {
+		helpers.ClientError(w, http.StatusNotFound)
+		return nil, payments.Payment{}, false
+	}
+
+	p, ok := fake.Payment(chi.URLParam(r, "ref"))
+	if !ok {
+		helpers.ClientError(w, http.StatusNotFound)
+		return nil, payments.Payment{}, false
+	}
+
+	return fake, p, true
+}
+
+//FakeCheckout shows the checkout page of the fake payment provider
+func (m *Repository) FakeCheckout(w http.ResponseWriter, r *http.Request) {
+	_, p, ok := m.fakePayment(w, r)
+	if !ok {
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["payment"] = p
+
+	render.Template(w, r, "fake-checkout.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+//PostFakeCheckout settles a payment with the fake payment provider, which confirms it through the webhook
+func (m *Repository) PostFakeCheckout(w http.ResponseWriter, r *http.Request) {
+	fake, p, ok := m.fakePayment(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	succeeded := r.Form.Get("outcome") == models.PaymentSucceeded
+
+	payload, header, err := fake.Complete(p.Reference, succeeded)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	event, err := fake.ParseWebhook(payload, header)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	err = m.recordPayment(event)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if succeeded {
+		m.App.Session.Put(r.Context(), "flash", "Payment received, thank you!")
+	} else {
+		m.App.Session.Put(r.Context(), "error", "Payment declined")
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+//AdminPostFolioEntry adds a charge, a payment taken at the desk or a request for an online payment to the
+//folio of a reservation
+func (m *Repository) AdminPostFolioEntry(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	showURL := showReservationURL(chi.URLParam(r, "src"), id, r.Form.Get("year"), r.Form.Get("month"))
+
+	amount, err := parseMoney(r.Form.Get("amount"))
+	if err != nil || amount == 0 {
+		m.App.Session.Put(r.Context(), "error", "Enter an amount like 12.50")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	description := strings.TrimSpace(r.Form.Get("description"))
+
+	switch r.Form.Get("kind") {
+	case models.FolioCharge, models.FolioPayment:
+		if description == "" {
+			m.App.Session.Put(r.Context(), "error", "Description can't be empty")
+			http.Redirect(w, r, showURL, http.StatusSeeOther)
+			return
+		}
+
+		e := models.FolioEntry{
+			ReservationID: id,
+			Kind:          r.Form.Get("kind"),
+			Description:   description,
+			Amount:        amount,
+			Status:        models.PaymentSucceeded,
+		}
+		if e.Kind == models.FolioPayment {
+			e.Provider = models.PaymentManual
+		}
+
+		_, err = m.DB.InsertFolioEntry(e)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		m.App.Session.Put(r.Context(), "flash", "Folio updated")
+	case "request":
+		if m.App.Payments == nil {
+			m.App.Session.Put(r.Context(), "error", "Online payments are not set up")
+			http.Redirect(w, r, showURL, http.StatusSeeOther)
+			return
+		}
+
+		res, err := m.DB.GetReservationByID(id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		if description == "" {
+			description = "Payment"
+		}
+
+		e, err := m.requestPayment(res, amount, description)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		htmlMessage := fmt.Sprintf(`
+			<strong>Payment Request</strong><br>
+			Dear %s: <br>
+			Please pay %s for your stay from %s to %s here: <a href="%s">%s</a>
+		`, res.FirstName, render.Price(amount, m.App.Currency), res.StartDate.Format("2006-01-02"),
+			res.EndDate.Format("2006-01-02"), e.CheckoutURL, e.CheckoutURL)
+
+		m.App.MailChan <- models.MailData{
+			To:       res.Email,
+			From:     "me@here.com",
+			Subject:  "Payment Request",
+			Content:  htmlMessage,
+			Template: "basic.html",
+		}
+
+		m.App.Session.Put(r.Context(), "flash", "Payment link sent to the guest")
+	default:
+		m.App.Session.Put(r.Context(), "error", "Unknown folio entry")
+	}
+
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
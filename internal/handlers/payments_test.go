@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/payments"
+)
+
+func TestParseMoney(t *testing.T) {
+	var theTests = []struct {
+		s        string
+		expected int
+		valid    bool
+	}{
+		{"12.50", 1250, true},
+		{"12.5", 1250, true},
+		{"12.05", 1205, true},
+		{"12", 1200, true},
+		{" 7. ", 700, true},
+		{"0.99", 99, true},
+		{"", 0, false},
+		{".50", 0, false},
+		{"-5", 0, false},
+		{"1.234", 0, false},
+		{"1,50", 0, false},
+		{"1.-5", 0, false},
+		{"9999999999", 0, false},
+	}
+
+	for _, tt := range theTests {
+		got, err := parseMoney(tt.s)
+		if (err == nil) != tt.valid || got != tt.expected {
+			t.Errorf("parseMoney(%q) returned %d, %v", tt.s, got, err)
+		}
+	}
+}
+
+func TestRepository_PaymentWebhook(t *testing.T) {
+	signed := func(payload string) http.Header {
+		header := make(http.Header)
+		header.Set(payments.FakeSignatureHeader, payments.Sign("secret", []byte(payload), time.Now()))
+		return header
+	}
+
+	succeeded := `{"reference":"fake_1","status":"succeeded"}`
+
+	var theTests = []struct {
+		name               string
+		payload            string
+		header             http.Header
+		expectedStatusCode int
+	}{
+		{"succeeded", succeeded, signed(succeeded), http.StatusOK},
+		{"failed", `{"reference":"fake_1","status":"failed"}`, signed(`{"reference":"fake_1","status":"failed"}`), http.StatusOK},
+		{"unsigned", succeeded, http.Header{}, http.StatusBadRequest},
+		{"tampered", `{"reference":"fake_2","status":"succeeded"}`, signed(succeeded), http.StatusBadRequest},
+		{"unknown-status", `{"reference":"fake_1","status":"lost"}`, signed(`{"reference":"fake_1","status":"lost"}`), http.StatusBadRequest},
+		{"database-error", `{"reference":"fake_error","status":"succeeded"}`, signed(`{"reference":"fake_error","status":"succeeded"}`), http.StatusInternalServerError},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/payments/webhook", strings.NewReader(tt.payload))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		for k, v := range tt.header {
+			req.Header[k] = v
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.PaymentWebhook)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+	}
+}
+
+func TestRepository_FakeCheckout(t *testing.T) {
+	p, _ := testApp.Payments.CreatePayment(payments.Payment{ReservationID: 1, Amount: 4000, Description: "Deposit"})
+	declined, _ := testApp.Payments.CreatePayment(payments.Payment{ReservationID: 1, Amount: 4000, Description: "Deposit"})
+
+	var theTests = []struct {
+		name               string
+		method             string
+		ref                string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedBody       string
+		expectedSession    string
+	}{
+		{"page", "GET", p.Reference, nil, http.StatusOK, "40.00", ""},
+		{"unknown-page", "GET", "fake_unknown", nil, http.StatusNotFound, "", ""},
+		{"pay", "POST", p.Reference, url.Values{"outcome": {"succeeded"}}, http.StatusSeeOther, "", "flash"},
+		{"settled-page", "GET", p.Reference, nil, http.StatusOK, "This payment has succeeded", ""},
+		{"decline", "POST", declined.Reference, url.Values{"outcome": {"failed"}}, http.StatusSeeOther, "", "error"},
+		{"unknown-payment", "POST", "fake_unknown", url.Values{"outcome": {"succeeded"}}, http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest(tt.method, "/payments/fake/"+tt.ref, strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"ref": tt.ref})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.FakeCheckout)
+		if tt.method == "POST" {
+			handler = Repo.PostFakeCheckout
+		}
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+
+		if tt.expectedSession != "" && !session.Exists(req.Context(), tt.expectedSession) {
+			t.Errorf("failed %s: expected %s in session", tt.name, tt.expectedSession)
+		}
+	}
+}
+
+func TestRepository_AdminPostFolioEntry(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedSession    string
+	}{
+		{"charge", "1", url.Values{"kind": {"charge"}, "description": {"Minibar"}, "amount": {"15.00"}}, http.StatusSeeOther, "flash"},
+		{"payment", "1", url.Values{"kind": {"payment"}, "description": {"Cash"}, "amount": {"20"}}, http.StatusSeeOther, "flash"},
+		{"request", "1", url.Values{"kind": {"request"}, "amount": {"175.00"}}, http.StatusSeeOther, "flash"},
+		{"missing-description", "1", url.Values{"kind": {"charge"}, "amount": {"15.00"}}, http.StatusSeeOther, "error"},
+		{"invalid-amount", "1", url.Values{"kind": {"charge"}, "description": {"Minibar"}, "amount": {"lots"}}, http.StatusSeeOther, "error"},
+		{"zero-amount", "1", url.Values{"kind": {"charge"}, "description": {"Minibar"}, "amount": {"0"}}, http.StatusSeeOther, "error"},
+		{"unknown-kind", "1", url.Values{"kind": {"gift"}, "description": {"Minibar"}, "amount": {"15.00"}}, http.StatusSeeOther, "error"},
+		{"invalid-id", "x", url.Values{"kind": {"charge"}, "description": {"Minibar"}, "amount": {"15.00"}}, http.StatusBadRequest, ""},
+		{"database-error", "1", url.Values{"kind": {"charge"}, "description": {"error"}, "amount": {"15.00"}}, http.StatusInternalServerError, ""},
+		{"request-missing-reservation", "1001", url.Values{"kind": {"request"}, "amount": {"15.00"}}, http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/reservations/all/"+tt.id+"/folio", strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "all", "id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostFolioEntry)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedStatusCode == http.StatusSeeOther {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != "/admin/reservations/all/1/show" {
+				t.Errorf("failed %s: expected location /admin/reservations/all/1/show, but got %s", tt.name, actualLoc.String())
+			}
+		}
+
+		if tt.expectedSession != "" && !session.Exists(req.Context(), tt.expectedSession) {
+			t.Errorf("failed %s: expected %s in session", tt.name, tt.expectedSession)
+		}
+	}
+}
+
+func TestRepository_AdminShowReservationFolio(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{"balance", "/admin/reservations/all/1/show", http.StatusOK, "170.00 outstanding"},
+		{"pending-payment", "/admin/reservations/all/1/show", http.StatusOK, "payment link"},
+		{"refund", "/admin/reservations/all/1/show", http.StatusOK, "Deposit, up to 35.00"},
+		{"credit-after-cancellation", "/admin/reservations/all/100/show", http.StatusOK, "value=\"30.00\""},
+		{"database-error", "/admin/reservations/all/999/show", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.RequestURI = tt.url
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminShowReservation)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+	}
+}
+
+func TestRepository_ReservationSummaryDeposit(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/reservation-summary", nil)
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+
+	session.Put(ctx, "reservation", models.Reservation{RoomID: 1, Room: models.Room{ID: 1, RoomName: "General's Quarters"}})
+	session.Put(ctx, "deposit", 4000)
+	session.Put(ctx, "payment_url", "http://localhost:8080/payments/fake/fake_1")
+
+	rr := httptest.NewRecorder()
+
+	handler := http.HandlerFunc(Repo.ReservationSummary)
+	handler.ServeHTTP(rr, req)
+
+	if !strings.Contains(rr.Body.String(), "A deposit of 40.00 USD is due") {
+		t.Error("deposit not shown on the reservation summary")
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/forms"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//AdminPromoCodes shows all promo codes with how they were used by the reservations made over a date range
+func (m *Repository) AdminPromoCodes(w http.ResponseWriter, r *http.Request) {
+	start, end, err := reportRange(r)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid date range")
+		http.Redirect(w, r, "/admin/promo-codes", http.StatusSeeOther)
+		return
+	}
+
+	usage, err := m.DB.PromoCodeUsage(start, end)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	var total models.PromoCodeUsage
+	for _, u := range usage {
+		total.Reservations += u.Reservations
+		total.Cancellations += u.Cancellations
+		total.Discount += u.Discount
+		total.Revenue += u.Revenue
+	}
+
+	stringMap := make(map[string]string)
+	stringMap["start"] = start.Format("2006-01-02")
+	stringMap["end"] = end.Format("2006-01-02")
+
+	data := make(map[string]interface{})
+	data["usage"] = usage
+	data["total"] = total
+
+	render.Template(w, r, "admin-promo-codes.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+	})
+}
+
+//renderPromoCode renders the form of a promo code
+func (m *Repository) renderPromoCode(w http.ResponseWriter, r *http.Request, p models.PromoCode, form *forms.Form) {
+	rooms, err := m.DB.AllRooms()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	stringMap := make(map[string]string)
+	if !p.ValidFrom.IsZero() {
+		stringMap["valid_from"] = p.ValidFrom.Format("2006-01-02")
+	}
+	if !p.ValidTo.IsZero() {
+		stringMap["valid_to"] = p.ValidTo.Format("2006-01-02")
+	}
+	stringMap["amount"] = render.Money(p.Amount)
+
+	selected := make(map[int]bool)
+	for _, id := range p.RoomIDs {
+		selected[id] = true
+	}
+
+	data := make(map[string]interface{})
+	data["promo"] = p
+	data["kinds"] = models.PromoKinds
+	data["rooms"] = rooms
+	data["selected"] = selected
+
+	render.Template(w, r, "admin-promo-code-show.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+		Form:      form,
+	})
+}
+
+//AdminShowPromoCode shows the form for a new or existing promo code
+func (m *Repository) AdminShowPromoCode(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	p := models.PromoCode{
+		Kind: models.PromoPercent,
+	}
+
+	if id > 0 {
+		p, err = m.DB.GetPromoCodeByID(id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	m.renderPromoCode(w, r, p, forms.New(nil))
+}
+
+//AdminPostPromoCode handles the posting of a promo code form
+func (m *Repository) AdminPostPromoCode(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("code", "kind", "amount")
+	for _, field := range []string{"min_nights", "max_uses"} {
+		if form.Get(field) != "" {
+			form.IsInt(field, 0, 100000)
+		}
+	}
+
+	minNights, _ := strconv.Atoi(form.Get("min_nights"))
+	maxUses, _ := strconv.Atoi(form.Get("max_uses"))
+
+	p := models.PromoCode{
+		ID:          id,
+		Code:        strings.ToUpper(strings.TrimSpace(form.Get("code"))),
+		Description: strings.TrimSpace(form.Get("description")),
+		Kind:        form.Get("kind"),
+		MinNights:   minNights,
+		MaxUses:     maxUses,
+	}
+
+	if strings.ContainsAny(p.Code, " \t") {
+		form.Errors.Add("code", "Codes can't contain spaces")
+	}
+
+	if !isOneOf(p.Kind, models.PromoKinds) {
+		form.Errors.Add("kind", "Unknown kind of discount")
+	}
+
+	p.Amount, err = parseMoney(form.Get("amount"))
+	if err != nil || p.Amount == 0 {
+		form.Errors.Add("amount", "Invalid discount")
+	} else if p.Kind == models.PromoPercent && p.Amount > 10000 {
+		form.Errors.Add("amount", "A discount can't be more than 100%")
+	}
+
+	p.ValidFrom = optionalDate(form, "valid_from")
+	p.ValidTo = optionalDate(form, "valid_to")
+	if !p.ValidFrom.IsZero() && !p.ValidTo.IsZero() && p.ValidTo.Before(p.ValidFrom) {
+		form.Errors.Add("valid_to", "Code must expire after it starts")
+	}
+
+	for _, s := range r.Form["room_id"] {
+		roomID, err := strconv.Atoi(s)
+		if err != nil {
+			helpers.ClientError(w, http.StatusBadRequest)
+			return
+		}
+		p.RoomIDs = append(p.RoomIDs, roomID)
+	}
+
+	if p.Code != "" {
+		existing, found, err := m.DB.GetPromoCodeByCode(p.Code)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		if found && existing.ID != id {
+			form.Errors.Add("code", "This code already exists")
+		}
+	}
+
+	if !form.Valid() {
+		m.renderPromoCode(w, r, p, form)
+		return
+	}
+
+	if id > 0 {
+		err = m.DB.UpdatePromoCode(p)
+	} else {
+		_, err = m.DB.InsertPromoCode(p)
+	}
+
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/promo-codes", http.StatusSeeOther)
+}
+
+//AdminDeletePromoCode deletes a promo code, reservations keep the code they were made with
+func (m *Repository) AdminDeletePromoCode(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeletePromoCode(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Promo code deleted")
+	http.Redirect(w, r, "/admin/promo-codes", http.StatusSeeOther)
+}
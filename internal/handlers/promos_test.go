@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRepository_AdminPromoCodes(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+	}{
+		{
+			name:               "promo-codes",
+			url:                "/admin/promo-codes",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "usage-range",
+			url:                "/admin/promo-codes?start=2050-01-01&end=2050-02-01",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "invalid-range",
+			url:                "/admin/promo-codes?start=2050-02-01&end=2050-01-01",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "usage-error",
+			url:                "/admin/promo-codes?start=2040-01-01&end=2040-02-01",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "new-promo-code",
+			url:                "/admin/promo-codes/0",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "existing-promo-code",
+			url:                "/admin/promo-codes/1",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "missing-promo-code",
+			url:                "/admin/promo-codes/101",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "invalid-promo-code-id",
+			url:                "/admin/promo-codes/invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "delete-promo-code",
+			url:                "/admin/delete-promo-code/1/do",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "delete-promo-code-error",
+			url:                "/admin/delete-promo-code/101/do",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	routes := getRoutes()
+	ts := httptest.NewServer(routes)
+	defer ts.Close()
+
+	for _, tt := range theTests {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected %d but got %d", tt.name, tt.expectedStatusCode, resp.StatusCode)
+		}
+	}
+}
+
+func TestRepository_AdminPostPromoCode(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name: "valid-new-promo-code",
+			id:   "0",
+			postedData: url.Values{
+				"code":   {"spring"},
+				"kind":   {"percent"},
+				"amount": {"15"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/promo-codes",
+		},
+		{
+			name: "valid-existing-promo-code",
+			id:   "1",
+			postedData: url.Values{
+				"code":        {"SUMMER"},
+				"description": {"Summer newsletter"},
+				"kind":        {"fixed"},
+				"amount":      {"25.00"},
+				"valid_from":  {"2050-06-01"},
+				"valid_to":    {"2050-08-31"},
+				"min_nights":  {"3"},
+				"max_uses":    {"100"},
+				"room_id":     {"1", "2"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/promo-codes",
+		},
+		{
+			name: "existing-code",
+			id:   "0",
+			postedData: url.Values{
+				"code":   {"summer"},
+				"kind":   {"percent"},
+				"amount": {"15"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "code-with-spaces",
+			id:   "0",
+			postedData: url.Values{
+				"code":   {"SUMMER SALE"},
+				"kind":   {"percent"},
+				"amount": {"15"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-kind",
+			id:   "0",
+			postedData: url.Values{
+				"code":   {"SPRING"},
+				"kind":   {"everything"},
+				"amount": {"15"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "percent-above-100",
+			id:   "0",
+			postedData: url.Values{
+				"code":   {"SPRING"},
+				"kind":   {"percent"},
+				"amount": {"150"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "zero-amount",
+			id:   "0",
+			postedData: url.Values{
+				"code":   {"SPRING"},
+				"kind":   {"fixed"},
+				"amount": {"0"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-dates",
+			id:   "0",
+			postedData: url.Values{
+				"code":       {"SPRING"},
+				"kind":       {"percent"},
+				"amount":     {"15"},
+				"valid_from": {"2050-08-31"},
+				"valid_to":   {"2050-06-01"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-max-uses",
+			id:   "0",
+			postedData: url.Values{
+				"code":     {"SPRING"},
+				"kind":     {"percent"},
+				"amount":   {"15"},
+				"max_uses": {"-1"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-room",
+			id:   "0",
+			postedData: url.Values{
+				"code":    {"SPRING"},
+				"kind":    {"percent"},
+				"amount":  {"15"},
+				"room_id": {"x"},
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "database-error",
+			id:   "0",
+			postedData: url.Values{
+				"code":   {"error"},
+				"kind":   {"percent"},
+				"amount": {"15"},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "invalid-id",
+			id:                 "invalid",
+			postedData:         url.Values{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/promo-codes/"+tt.id, strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostPromoCode)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/forms"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//errRatePlanRoom is returned when a rate plan is chosen for a room it doesn't sell
+var errRatePlanRoom = errors.New("rate plan is not for this room")
+
+//applyRatePlan sets the rate plan of a reservation, none when planID is zero, and returns its room as sold
+//under the plan
+func (m *Repository) applyRatePlan(res *models.Reservation, room models.Room, planID int) (models.Room, error) {
+	res.RatePlanID, res.RatePlan = 0, ""
+	if planID == 0 {
+		return room, nil
+	}
+
+	plan, err := m.DB.GetRatePlanByID(planID)
+	if err != nil {
+		return room, err
+	}
+
+	if plan.RoomID != res.RoomID {
+		return room, errRatePlanRoom
+	}
+
+	res.RatePlanID = plan.ID
+	res.RatePlan = plan.Name
+
+	return plan.ApplyTo(room), nil
+}
+
+//ratePlansByRoom returns the rate plans of every room, by room id
+func (m *Repository) ratePlansByRoom() (map[int][]models.RatePlan, error) {
+	plans, err := m.DB.RatePlans()
+	if err != nil {
+		return nil, err
+	}
+
+	byRoom := make(map[int][]models.RatePlan)
+	for _, p := range plans {
+		byRoom[p.RoomID] = append(byRoom[p.RoomID], p)
+	}
+
+	return byRoom, nil
+}
+
+//AdminRatePlans shows the rate plans of all rooms
+func (m *Repository) AdminRatePlans(w http.ResponseWriter, r *http.Request) {
+	plans, err := m.DB.RatePlans()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	policies, err := m.DB.AllCancellationPolicies()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	policyNames := make(map[int]string)
+	for _, p := range policies {
+		policyNames[p.ID] = p.Name
+	}
+
+	data := make(map[string]interface{})
+	data["plans"] = plans
+	data["policies"] = policyNames
+
+	render.Template(w, r, "admin-rate-plans.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+//renderRatePlan renders the form of a rate plan
+func (m *Repository) renderRatePlan(w http.ResponseWriter, r *http.Request, plan models.RatePlan, form *forms.Form) {
+	rooms, err := m.DB.AllRooms()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	policies, err := m.DB.AllCancellationPolicies()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	stringMap := make(map[string]string)
+	stringMap["price"] = render.Money(plan.Price)
+
+	data := make(map[string]interface{})
+	data["plan"] = plan
+	data["rooms"] = rooms
+	data["policies"] = policies
+
+	render.Template(w, r, "admin-rate-plan-show.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+		Form:      form,
+	})
+}
+
+//AdminShowRatePlan shows the form for a new or existing rate plan
+func (m *Repository) AdminShowRatePlan(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var plan models.RatePlan
+
+	if id > 0 {
+		plan, err = m.DB.GetRatePlanByID(id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	m.renderRatePlan(w, r, plan, forms.New(nil))
+}
+
+//AdminPostRatePlan handles the posting of a rate plan form
+func (m *Repository) AdminPostRatePlan(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("room_id", "name", "price")
+
+	roomID, err := strconv.Atoi(form.Get("room_id"))
+	if err != nil && form.Get("room_id") != "" {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	policyID, err := strconv.Atoi(form.Get("cancellation_policy_id"))
+	if err != nil && form.Get("cancellation_policy_id") != "" {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	plan := models.RatePlan{
+		ID:                   id,
+		RoomID:               roomID,
+		Name:                 strings.TrimSpace(form.Get("name")),
+		CancellationPolicyID: policyID,
+		Inclusions:           strings.TrimSpace(form.Get("inclusions")),
+	}
+
+	plan.Price, err = parseMoney(form.Get("price"))
+	if err != nil || plan.Price == 0 {
+		form.Errors.Add("price", "Invalid price")
+	}
+
+	if !form.Valid() {
+		m.renderRatePlan(w, r, plan, form)
+		return
+	}
+
+	if id > 0 {
+		err = m.DB.UpdateRatePlan(plan)
+	} else {
+		_, err = m.DB.InsertRatePlan(plan)
+	}
+
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/rate-plans", http.StatusSeeOther)
+}
+
+//AdminDeleteRatePlan deletes a rate plan, reservations keep the prices they were charged
+func (m *Repository) AdminDeleteRatePlan(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeleteRatePlan(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Rate plan deleted")
+	http.Redirect(w, r, "/admin/rate-plans", http.StatusSeeOther)
+}
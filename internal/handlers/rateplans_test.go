@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestRepository_applyRatePlan(t *testing.T) {
+	room := models.Room{ID: 1, Price: 10000, CancellationPolicyID: 1}
+	res := models.Reservation{RoomID: 1, RatePlanID: 7, RatePlan: "Old"}
+
+	priced, err := Repo.applyRatePlan(&res, room, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.RatePlanID != 1 || res.RatePlan != "Non-refundable" {
+		t.Errorf("expected the plan on the reservation, but got %d %q", res.RatePlanID, res.RatePlan)
+	}
+
+	if priced.Price != 9000 || priced.CancellationPolicyID != 2 {
+		t.Errorf("expected the room at the plan price and policy, but got %d and %d", priced.Price,
+			priced.CancellationPolicyID)
+	}
+
+	priced, err = Repo.applyRatePlan(&res, room, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.RatePlanID != 0 || res.RatePlan != "" || priced != room {
+		t.Errorf("expected no plan, but got %d %q at %d", res.RatePlanID, res.RatePlan, priced.Price)
+	}
+
+	if _, err = Repo.applyRatePlan(&res, room, 50); err != errRatePlanRoom {
+		t.Errorf("expected a plan of another room to fail, but got %v", err)
+	}
+
+	if _, err = Repo.applyRatePlan(&res, room, 101); err == nil {
+		t.Error("expected a missing plan to fail")
+	}
+}
+
+func TestRepository_cancellationPolicyFor(t *testing.T) {
+	res := models.Reservation{Room: models.Room{ID: 1, CancellationPolicyID: 1}}
+
+	p, err := Repo.cancellationPolicyFor(res)
+	if err != nil || p.ID != 1 {
+		t.Errorf("expected the policy of the room, but got %d, %v", p.ID, err)
+	}
+
+	res.RatePlanID = 1
+	p, err = Repo.cancellationPolicyFor(res)
+	if err != nil || p.ID != 2 {
+		t.Errorf("expected the policy of the plan, but got %d, %v", p.ID, err)
+	}
+
+	res.Room.CancellationPolicyID = 0
+	res.RatePlanID = 0
+	p, err = Repo.cancellationPolicyFor(res)
+	if err != nil || p.ID != 0 {
+		t.Errorf("expected no policy, but got %d, %v", p.ID, err)
+	}
+}
+
+func TestRepository_AdminRatePlans(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+	}{
+		{
+			name:               "plans",
+			url:                "/admin/rate-plans",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "new-plan",
+			url:                "/admin/rate-plans/0",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "existing-plan",
+			url:                "/admin/rate-plans/1",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "missing-plan",
+			url:                "/admin/rate-plans/101",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "invalid-plan-id",
+			url:                "/admin/rate-plans/invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "delete-plan",
+			url:                "/admin/delete-rate-plan/1/do",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "delete-plan-error",
+			url:                "/admin/delete-rate-plan/101/do",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	routes := getRoutes()
+	ts := httptest.NewServer(routes)
+	defer ts.Close()
+
+	for _, tt := range theTests {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected %d but got %d", tt.name, tt.expectedStatusCode, resp.StatusCode)
+		}
+	}
+}
+
+func TestRepository_AdminPostRatePlan(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name: "valid-new-plan",
+			id:   "0",
+			postedData: url.Values{
+				"room_id":                {"1"},
+				"name":                   {"Bed & Breakfast"},
+				"price":                  {"120"},
+				"cancellation_policy_id": {"0"},
+				"inclusions":             {"Breakfast for all guests"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/rate-plans",
+		},
+		{
+			name: "valid-existing-plan",
+			id:   "1",
+			postedData: url.Values{
+				"room_id":                {"1"},
+				"name":                   {"Non-refundable"},
+				"price":                  {"90.50"},
+				"cancellation_policy_id": {"2"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/rate-plans",
+		},
+		{
+			name: "missing-fields",
+			id:   "0",
+			postedData: url.Values{
+				"room_id": {"1"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-price",
+			id:   "0",
+			postedData: url.Values{
+				"room_id": {"1"},
+				"name":    {"Flexible"},
+				"price":   {"cheap"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "zero-price",
+			id:   "0",
+			postedData: url.Values{
+				"room_id": {"1"},
+				"name":    {"Flexible"},
+				"price":   {"0"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-room",
+			id:   "0",
+			postedData: url.Values{
+				"room_id": {"first"},
+				"name":    {"Flexible"},
+				"price":   {"100"},
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "invalid-policy",
+			id:   "0",
+			postedData: url.Values{
+				"room_id":                {"1"},
+				"name":                   {"Flexible"},
+				"price":                  {"100"},
+				"cancellation_policy_id": {"strict"},
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "database-error",
+			id:   "0",
+			postedData: url.Values{
+				"room_id": {"1"},
+				"name":    {"error"},
+				"price":   {"100"},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "invalid-id",
+			id:                 "invalid",
+			postedData:         url.Values{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/rate-plans/"+tt.id, strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostRatePlan)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/forms"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/pricing"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//rateStay sets the adjustments price rules make to a reservation in a room. The nights of own, the stay a
+//reservation is moved from, don't count towards occupancy
+func (m *Repository) rateStay(res *models.Reservation, room models.Room, own models.RoomRestriction) error {
+	rules, err := m.DB.PriceRules()
+	if err != nil {
+		return err
+	}
+
+	rooms, err := m.DB.AllRooms()
+	if err != nil {
+		return err
+	}
+
+	occupied, err := m.DB.OccupiedRoomsByNight(res.StartDate, res.EndDate)
+	if err != nil {
+		return err
+	}
+
+	occupancy := make(map[time.Time]int)
+	for _, x := range occupied {
+		count := x.Count
+		if !x.Date.Before(own.StartDate) && x.Date.Before(own.EndDate) && count > 0 {
+			count--
+		}
+		y, mo, d := x.Date.Date()
+		occupancy[time.Date(y, mo, d, 0, 0, 0, 0, time.UTC)] = occupancyPercent([]models.DailyCount{{Date: x.Date, Count: count}}, len(rooms))
+	}
+
+	bookedAt := res.CreatedAt
+	if bookedAt.IsZero() {
+		bookedAt = time.Now()
+	}
+
+	res.Adjustments = pricing.StayRate(rules, room, res.StartDate, res.EndDate, bookedAt, occupancy)
+
+	return nil
+}
+
+//AdminPriceRules shows all price rules
+func (m *Repository) AdminPriceRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := m.DB.PriceRules()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["rules"] = rules
+
+	render.Template(w, r, "admin-price-rules.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+//renderPriceRule renders the form of a price rule
+func (m *Repository) renderPriceRule(w http.ResponseWriter, r *http.Request, rule models.PriceRule, form *forms.Form) {
+	stringMap := make(map[string]string)
+	stringMap["adjustment"] = render.Money(rule.Adjustment)
+
+	data := make(map[string]interface{})
+	data["rule"] = rule
+	data["kinds"] = models.PriceRuleKinds
+
+	render.Template(w, r, "admin-price-rule-show.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+		Form:      form,
+	})
+}
+
+//AdminShowPriceRule shows the form for a new or existing price rule
+func (m *Repository) AdminShowPriceRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	rule := models.PriceRule{
+		Kind: models.PriceLengthOfStay,
+	}
+
+	if id > 0 {
+		rule, err = m.DB.GetPriceRuleByID(id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	m.renderPriceRule(w, r, rule, forms.New(nil))
+}
+
+//AdminPostPriceRule handles the posting of a price rule form
+func (m *Repository) AdminPostPriceRule(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("name", "kind", "threshold", "adjustment")
+	form.IsInt("threshold", 0, 1000)
+
+	threshold, _ := strconv.Atoi(form.Get("threshold"))
+
+	rule := models.PriceRule{
+		ID:        id,
+		Name:      strings.TrimSpace(form.Get("name")),
+		Kind:      form.Get("kind"),
+		Threshold: threshold,
+	}
+
+	if !isOneOf(rule.Kind, models.PriceRuleKinds) {
+		form.Errors.Add("kind", "Unknown kind of rule")
+	}
+
+	if rule.Kind == models.PriceOccupancy && threshold > 100 {
+		form.Errors.Add("threshold", "Occupancy can't be more than 100%")
+	}
+
+	//discounts are entered as negative percentages
+	adjustment := strings.TrimSpace(form.Get("adjustment"))
+	negative := strings.HasPrefix(adjustment, "-")
+
+	rule.Adjustment, err = parseMoney(strings.TrimPrefix(adjustment, "-"))
+	if negative {
+		rule.Adjustment = -rule.Adjustment
+	}
+
+	if err != nil || rule.Adjustment == 0 {
+		form.Errors.Add("adjustment", "Invalid adjustment")
+	} else if rule.Adjustment < -10000 {
+		form.Errors.Add("adjustment", "A discount can't be more than 100%")
+	}
+
+	if !form.Valid() {
+		m.renderPriceRule(w, r, rule, form)
+		return
+	}
+
+	if id > 0 {
+		err = m.DB.UpdatePriceRule(rule)
+	} else {
+		_, err = m.DB.InsertPriceRule(rule)
+	}
+
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/price-rules", http.StatusSeeOther)
+}
+
+//AdminDeletePriceRule deletes a price rule, reservations keep the prices they were charged
+func (m *Repository) AdminDeletePriceRule(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeletePriceRule(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Price rule deleted")
+	http.Redirect(w, r, "/admin/price-rules", http.StatusSeeOther)
+}
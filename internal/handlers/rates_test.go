@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestRepository_rateStay(t *testing.T) {
+	room := models.Room{ID: 1, Price: 10000}
+	res := models.Reservation{
+		StartDate: time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2050, 1, 8, 0, 0, 0, 0, time.UTC),
+		CreatedAt: time.Date(2049, 12, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	//the only room is taken every night, so the busy rate applies before the weekly discount
+	err := Repo.rateStay(&res, room, models.RoomRestriction{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Adjustments) != 2 || res.Adjustments[0].Amount != 14000 || res.Adjustments[1].Amount != -8400 {
+		t.Errorf("wrong adjustments %v", res.Adjustments)
+	}
+
+	//a stay moved within its own nights doesn't count towards occupancy
+	own := models.RoomRestriction{RoomID: 1, StartDate: res.StartDate, EndDate: res.EndDate}
+	if err = Repo.rateStay(&res, room, own); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Adjustments) != 1 || res.Adjustments[0].Name != "Weekly" || res.Adjustments[0].Amount != -7000 {
+		t.Errorf("wrong adjustments %v", res.Adjustments)
+	}
+
+	if err = Repo.priceStay(&res, room, models.PromoCode{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.RoomPrice() != 63000 {
+		t.Errorf("expected room price 63000, but got %d", res.RoomPrice())
+	}
+}
+
+func TestRepository_AdminPriceRules(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+	}{
+		{
+			name:               "rules",
+			url:                "/admin/price-rules",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "new-rule",
+			url:                "/admin/price-rules/0",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "existing-rule",
+			url:                "/admin/price-rules/1",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "missing-rule",
+			url:                "/admin/price-rules/101",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "invalid-rule-id",
+			url:                "/admin/price-rules/invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "delete-rule",
+			url:                "/admin/delete-price-rule/1/do",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "delete-rule-error",
+			url:                "/admin/delete-price-rule/101/do",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	routes := getRoutes()
+	ts := httptest.NewServer(routes)
+	defer ts.Close()
+
+	for _, tt := range theTests {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected %d but got %d", tt.name, tt.expectedStatusCode, resp.StatusCode)
+		}
+	}
+}
+
+func TestRepository_AdminPostPriceRule(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name: "valid-new-rule",
+			id:   "0",
+			postedData: url.Values{
+				"name":       {"Weekly"},
+				"kind":       {"length_of_stay"},
+				"threshold":  {"7"},
+				"adjustment": {"-10"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/price-rules",
+		},
+		{
+			name: "valid-existing-rule",
+			id:   "1",
+			postedData: url.Values{
+				"name":       {"Busy"},
+				"kind":       {"occupancy"},
+				"threshold":  {"80"},
+				"adjustment": {"12.5"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/price-rules",
+		},
+		{
+			name: "missing-fields",
+			id:   "0",
+			postedData: url.Values{
+				"name": {"Weekly"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-kind",
+			id:   "0",
+			postedData: url.Values{
+				"name":       {"Weekly"},
+				"kind":       {"weekdays"},
+				"threshold":  {"7"},
+				"adjustment": {"-10"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-occupancy",
+			id:   "0",
+			postedData: url.Values{
+				"name":       {"Busy"},
+				"kind":       {"occupancy"},
+				"threshold":  {"120"},
+				"adjustment": {"10"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-adjustment",
+			id:   "0",
+			postedData: url.Values{
+				"name":       {"Weekly"},
+				"kind":       {"length_of_stay"},
+				"threshold":  {"7"},
+				"adjustment": {"ten"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "zero-adjustment",
+			id:   "0",
+			postedData: url.Values{
+				"name":       {"Weekly"},
+				"kind":       {"length_of_stay"},
+				"threshold":  {"7"},
+				"adjustment": {"0"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "discount-over-100",
+			id:   "0",
+			postedData: url.Values{
+				"name":       {"Free"},
+				"kind":       {"early_bird"},
+				"threshold":  {"300"},
+				"adjustment": {"-150"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "database-error",
+			id:   "0",
+			postedData: url.Values{
+				"name":       {"error"},
+				"kind":       {"last_minute"},
+				"threshold":  {"2"},
+				"adjustment": {"-15"},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "invalid-id",
+			id:                 "invalid",
+			postedData:         url.Values{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/price-rules/"+tt.id, strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostPriceRule)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/payments"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+	"github.com/yalagtyarzh/leafsite/internal/repository"
+)
+
+//succeededPayment returns the succeeded payment of a folio with an id
+func succeededPayment(f models.Folio, id int) (models.FolioEntry, bool) {
+	for _, e := range f.Entries {
+		if e.ID == id && e.Kind == models.FolioPayment && e.Status == models.PaymentSucceeded {
+			return e, true
+		}
+	}
+
+	return models.FolioEntry{}, false
+}
+
+//AdminPostRefund gives back some or all of a payment of a reservation, through the payment provider it was
+//taken with, by hand for payments taken at the desk or onto the gift voucher it was made with, and lets the
+//guest know. Refunds through a provider are entered as pending before the provider is asked, and settled with
+//its answer
+func (m *Repository) AdminPostRefund(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	showURL := showReservationURL(chi.URLParam(r, "src"), id, r.Form.Get("year"), r.Form.Get("month"))
+
+	res, err := m.DB.GetReservationByID(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	folio, err := m.folio(res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	paymentID, _ := strconv.Atoi(r.Form.Get("payment_id"))
+	payment, ok := succeededPayment(folio, paymentID)
+	if !ok {
+		m.App.Session.Put(r.Context(), "error", "Payment can't be refunded")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	refundable := folio.Refundable(payment.ID)
+
+	amount, err := parseMoney(r.Form.Get("amount"))
+	if err != nil || amount == 0 || amount > refundable {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Refund an amount up to %s", render.Money(refundable)))
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	description := "Refund"
+	if reason := strings.TrimSpace(r.Form.Get("reason")); reason != "" {
+		description = fmt.Sprintf("Refund: %s", reason)
+	}
+
+	e := models.FolioEntry{
+		ReservationID: res.ID,
+		PaymentID:     payment.ID,
+		Kind:          models.FolioRefund,
+		Description:   description,
+		Amount:        amount,
+		Status:        models.PaymentSucceeded,
+		Provider:      payment.Provider,
+	}
+
+	online := payment.Provider != models.PaymentManual && payment.Provider != models.PaymentVoucher
+	if online {
+		if m.App.Payments == nil || m.App.Payments.Name() != payment.Provider {
+			m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Payments of %s can't be refunded here", payment.Provider))
+			http.Redirect(w, r, showURL, http.StatusSeeOther)
+			return
+		}
+
+		//the refund is on the folio before the provider is asked, so that money given back is never missing
+		//from it and counts against the payment while the provider answers
+		e.Status = models.PaymentPending
+	}
+
+	if payment.Provider == models.PaymentVoucher {
+		//the amount goes back on the voucher the payment was made with
+		e.Reference = payment.Reference
+		e.ID, err = m.DB.RefundGiftVoucher(e)
+	} else {
+		e.ID, err = m.DB.InsertRefund(e)
+	}
+	if errors.Is(err, repository.ErrRefundTooLarge) {
+		m.App.Session.Put(r.Context(), "error", "Payment was refunded in the meantime, nothing was refunded")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if online {
+		refund, err := m.App.Payments.Refund(payments.Refund{
+			PaymentReference: payment.Reference,
+			Amount:           amount,
+			Reason:           description,
+		})
+		if err == nil && refund.Status == models.PaymentFailed {
+			err = errors.New("declined by the provider")
+		}
+		if err != nil {
+			m.App.ErrorLog.Println(err)
+
+			if updateErr := m.DB.UpdateRefund(e.ID, models.PaymentFailed, refund.Reference); updateErr != nil {
+				helpers.ServerError(w, updateErr)
+				return
+			}
+
+			m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Refund failed: %s", err))
+			http.Redirect(w, r, showURL, http.StatusSeeOther)
+			return
+		}
+
+		e.Status = refund.Status
+		e.Reference = refund.Reference
+
+		//the provider has taken the refund, so the guest is told about it even if the folio can't be updated
+		err = m.DB.UpdateRefund(e.ID, e.Status, e.Reference)
+		if err != nil {
+			m.App.ErrorLog.Println(err)
+		}
+	}
+
+	m.App.MailChan <- models.MailData{
+		To:       res.Email,
+		From:     "me@here.com",
+		Subject:  "Refund",
+		Content:  refundMessage(res, e, m.App.Currency),
+		Template: "basic.html",
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Refund of %s %s", render.Money(amount), e.Status))
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
+
+//refundMessage returns the email telling a guest about a refund, which only says the money was given back
+//once the refund has succeeded
+func refundMessage(res models.Reservation, e models.FolioEntry, currency string) string {
+	start, end := res.StartDate.Format("2006-01-02"), res.EndDate.Format("2006-01-02")
+
+	switch {
+	case e.Provider == models.PaymentVoucher:
+		return fmt.Sprintf(`
+			<strong>Refund</strong><br>
+			Dear %s: <br>
+			We have put %s back on gift voucher %s from your payment for your stay from %s to %s.
+		`, res.FirstName, render.Price(e.Amount, currency), e.Reference, start, end)
+	case e.Status == models.PaymentSucceeded:
+		return fmt.Sprintf(`
+			<strong>Refund</strong><br>
+			Dear %s: <br>
+			We have refunded %s of your payment for your stay from %s to %s.
+			It may take a few days to show on your statement.
+		`, res.FirstName, render.Price(e.Amount, currency), start, end)
+	default:
+		return fmt.Sprintf(`
+			<strong>Refund</strong><br>
+			Dear %s: <br>
+			We are refunding %s of your payment for your stay from %s to %s.
+			The refund is being processed by our payment provider and may take a few days to complete.
+		`, res.FirstName, render.Price(e.Amount, currency), start, end)
+	}
+}
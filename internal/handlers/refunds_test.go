@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestRepository_AdminPostRefund(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedSession    string
+	}{
+		{"partial", "1", url.Values{"payment_id": {"1"}, "amount": {"10.00"}, "reason": {"Late check-in"}}, http.StatusSeeOther, "flash"},
+		{"rest-of-payment", "1", url.Values{"payment_id": {"1"}, "amount": {"35.00"}}, http.StatusSeeOther, "flash"},
+		{"manual-payment", "1", url.Values{"payment_id": {"4"}, "amount": {"10.00"}}, http.StatusSeeOther, "flash"},
+		{"voucher-payment", "2", url.Values{"payment_id": {"6"}, "amount": {"20.00"}}, http.StatusSeeOther, "flash"},
+		{"voucher-error", "2", url.Values{"payment_id": {"6"}, "amount": {"20.00"}, "reason": {"error"}}, http.StatusInternalServerError, ""},
+		{"refunded-meanwhile", "1", url.Values{"payment_id": {"1"}, "amount": {"10.00"}, "reason": {"refunded meanwhile"}}, http.StatusSeeOther, "error"},
+		{"insert-error", "1", url.Values{"payment_id": {"1"}, "amount": {"10.00"}, "reason": {"error"}}, http.StatusInternalServerError, ""},
+		{"update-error", "1", url.Values{"payment_id": {"1"}, "amount": {"10.00"}, "reason": {"update error"}}, http.StatusSeeOther, "flash"},
+		{"more-than-left", "1", url.Values{"payment_id": {"1"}, "amount": {"35.01"}}, http.StatusSeeOther, "error"},
+		{"invalid-amount", "1", url.Values{"payment_id": {"1"}, "amount": {"x"}}, http.StatusSeeOther, "error"},
+		{"pending-payment", "1", url.Values{"payment_id": {"3"}, "amount": {"10.00"}}, http.StatusSeeOther, "error"},
+		{"not-a-payment", "1", url.Values{"payment_id": {"2"}, "amount": {"10.00"}}, http.StatusSeeOther, "error"},
+		{"unknown-payment", "1", url.Values{"payment_id": {"9"}, "amount": {"10.00"}}, http.StatusSeeOther, "error"},
+		{"invalid-id", "x", url.Values{"payment_id": {"1"}, "amount": {"10.00"}}, http.StatusBadRequest, ""},
+		{"reservation-not-found", "1001", url.Values{"payment_id": {"1"}, "amount": {"10.00"}}, http.StatusInternalServerError, ""},
+		{"folio-error", "999", url.Values{"payment_id": {"1"}, "amount": {"10.00"}}, http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/reservations/all/"+tt.id+"/refunds", strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "all", "id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostRefund)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedStatusCode == http.StatusSeeOther {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != "/admin/reservations/all/"+tt.id+"/show" {
+				t.Errorf("failed %s: expected location /admin/reservations/all/%s/show, but got %s", tt.name, tt.id, actualLoc.String())
+			}
+		}
+
+		if tt.expectedSession != "" && !session.Exists(req.Context(), tt.expectedSession) {
+			t.Errorf("failed %s: expected %s in session", tt.name, tt.expectedSession)
+		}
+	}
+}
+
+func TestRefundMessage(t *testing.T) {
+	res := models.Reservation{
+		FirstName: "John",
+		StartDate: time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2050, 1, 3, 0, 0, 0, 0, time.UTC),
+	}
+
+	var theTests = []struct {
+		name     string
+		entry    models.FolioEntry
+		expected string
+		unwanted string
+	}{
+		{"succeeded", models.FolioEntry{Amount: 1000, Status: models.PaymentSucceeded, Provider: "fake"}, "We have refunded", "We are refunding"},
+		{"pending", models.FolioEntry{Amount: 1000, Status: models.PaymentPending, Provider: "fake"}, "We are refunding", "We have refunded"},
+		{"manual", models.FolioEntry{Amount: 1000, Status: models.PaymentSucceeded, Provider: models.PaymentManual}, "We have refunded", "We are refunding"},
+		{"voucher", models.FolioEntry{Amount: 1000, Status: models.PaymentSucceeded, Provider: models.PaymentVoucher, Reference: "GIFT1"}, "back on gift voucher GIFT1", "We have refunded"},
+	}
+
+	for _, tt := range theTests {
+		message := refundMessage(res, tt.entry, "USD")
+		if !strings.Contains(message, tt.expected) || strings.Contains(message, tt.unwanted) {
+			t.Errorf("failed %s: expected %q and not %q in %s", tt.name, tt.expected, tt.unwanted, message)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/pricing"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+	"github.com/yalagtyarzh/leafsite/internal/reports"
+)
+
+//maxReportNights is the longest range a report can cover
+const maxReportNights = 731
+
+//reportRange reads the start and end dates of a report from the query, defaulting to the current month
+func reportRange(r *http.Request) (time.Time, time.Time, error) {
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	layout := "2006-01-02"
+	var err error
+
+	if s := r.URL.Query().Get("start"); s != "" {
+		start, err = time.Parse(layout, s)
+		if err != nil {
+			return start, end, err
+		}
+	}
+
+	if e := r.URL.Query().Get("end"); e != "" {
+		end, err = time.Parse(layout, e)
+		if err != nil {
+			return start, end, err
+		}
+	}
+
+	if !end.After(start) || pricing.Nights(start, end) > maxReportNights {
+		return start, end, fmt.Errorf("invalid report range %s - %s", start.Format(layout), end.Format(layout))
+	}
+
+	return start, end, nil
+}
+
+//buildReport builds the report for the nights from start up to end, comparing with the same nights last year
+func (m *Repository) buildReport(start, end time.Time) (reports.Report, error) {
+	rooms, err := m.DB.AllRooms()
+	if err != nil {
+		return reports.Report{}, err
+	}
+
+	reservations, err := m.DB.ReservationsForReport(start, end)
+	if err != nil {
+		return reports.Report{}, err
+	}
+
+	lastYear, err := m.DB.ReservationsForReport(start.AddDate(-1, 0, 0), end.AddDate(-1, 0, 0))
+	if err != nil {
+		return reports.Report{}, err
+	}
+
+	return reports.Build(rooms, reservations, lastYear, start, end, time.Now()), nil
+}
+
+//AdminReports shows the revenue and occupancy report for a date range
+func (m *Repository) AdminReports(w http.ResponseWriter, r *http.Request) {
+	start, end, err := reportRange(r)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid date range")
+		http.Redirect(w, r, "/admin/reports", http.StatusSeeOther)
+		return
+	}
+
+	report, err := m.buildReport(start, end)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	stringMap := make(map[string]string)
+	stringMap["start"] = start.Format("2006-01-02")
+	stringMap["end"] = end.Format("2006-01-02")
+
+	data := make(map[string]interface{})
+	data["report"] = report
+
+	render.Template(w, r, "admin-reports.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+	})
+}
+
+//AdminDownloadReport sends the revenue and occupancy report for a date range as CSV or JSON
+func (m *Repository) AdminDownloadReport(w http.ResponseWriter, r *http.Request) {
+	start, end, err := reportRange(r)
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "csv" && format != "json" {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	report, err := m.buildReport(start, end)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	filename := fmt.Sprintf("report-%s-%s.%s", start.Format("20060102"), end.Format("20060102"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "json" {
+		out, err := json.MarshalIndent(report, "", "     ")
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(out)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	err = report.WriteCSV(w)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+	}
+}
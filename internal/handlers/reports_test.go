@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yalagtyarzh/leafsite/internal/reports"
+)
+
+func TestRepository_AdminReports(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		queryParams        string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{"default-range", "", http.StatusOK, ""},
+		{"range", "?start=2050-01-01&end=2050-02-01", http.StatusOK, ""},
+		{"invalid-start", "?start=invalid&end=2050-02-01", http.StatusSeeOther, "/admin/reports"},
+		{"end-before-start", "?start=2050-02-01&end=2050-01-01", http.StatusSeeOther, "/admin/reports"},
+		{"range-too-long", "?start=2050-01-01&end=2055-01-01", http.StatusSeeOther, "/admin/reports"},
+		{"database-error", "?start=2040-01-01&end=2040-02-01", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/reports"+tt.queryParams, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminReports)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
+
+func TestRepository_AdminDownloadReport(t *testing.T) {
+	var theTests = []struct {
+		name                string
+		queryParams         string
+		expectedStatusCode  int
+		expectedContentType string
+	}{
+		{"csv", "?start=2050-01-01&end=2050-02-01&format=csv", http.StatusOK, "text/csv"},
+		{"json", "?start=2050-01-01&end=2050-02-01&format=json", http.StatusOK, "application/json"},
+		{"unknown-format", "?start=2050-01-01&end=2050-02-01&format=xml", http.StatusBadRequest, ""},
+		{"invalid-range", "?start=2050-02-01&end=2050-01-01&format=csv", http.StatusBadRequest, ""},
+		{"database-error", "?start=2040-01-01&end=2040-02-01&format=csv", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", "/admin/reports/download"+tt.queryParams, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminDownloadReport)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedContentType != "" && rr.Header().Get("Content-Type") != tt.expectedContentType {
+			t.Errorf("failed %s: expected content type %s, but got %s", tt.name, tt.expectedContentType, rr.Header().Get("Content-Type"))
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/admin/reports/download?start=2050-01-01&end=2050-02-01&format=json", nil)
+	req = req.WithContext(getCtx(req))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(Repo.AdminDownloadReport).ServeHTTP(rr, req)
+
+	var report reports.Report
+	err := json.Unmarshal(rr.Body.Bytes(), &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Total.RoomName != "Total" || report.Start.Format("2006-01-02") != "2050-01-01" {
+		t.Errorf("expected total of the requested range in JSON report, but got %v", report)
+	}
+
+	req, _ = http.NewRequest("GET", "/admin/reports/download?start=2050-01-01&end=2050-02-01&format=csv", nil)
+	req = req.WithContext(getCtx(req))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(Repo.AdminDownloadReport).ServeHTTP(rr, req)
+
+	if !strings.Contains(rr.Body.String(), "\nTotal,") {
+		t.Errorf("expected total line in CSV report, but got %s", rr.Body.String())
+	}
+}
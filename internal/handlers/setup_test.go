@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/gob"
 	"fmt"
 	"html/template"
@@ -16,8 +17,12 @@ import (
 	"github.com/go-chi/chi/middleware"
 	"github.com/justinas/nosurf"
 	"github.com/yalagtyarzh/leafsite/internal/config"
+	"github.com/yalagtyarzh/leafsite/internal/currency"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
 	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/payments"
 	"github.com/yalagtyarzh/leafsite/internal/render"
+	"github.com/yalagtyarzh/leafsite/internal/urlsigner"
 )
 
 var testApp config.AppConfig
@@ -29,6 +34,10 @@ var functions = template.FuncMap{
 	"formatDate": render.FormatDate,
 	"iterate":    render.Iterate,
 	"add":        render.Add,
+	"money":      render.Money,
+	"price":      render.Price,
+	"convert":    render.Convert,
+	"rate":       currency.FormatRate,
 }
 
 func TestMain(m *testing.M) {
@@ -41,6 +50,13 @@ func TestMain(m *testing.M) {
 
 	//Change this to true when in production
 	testApp.InProduction = false
+	testApp.URL = "http://localhost:8080"
+	testApp.Signer = urlsigner.New("secret")
+	testApp.Payments = payments.NewFake("secret", testApp.URL)
+	testApp.Deposit = payments.DepositRule{Percent: 20}
+	testApp.Company = models.BillingDetails{Name: "Fort Smyth Bed and Breakfast", VATNumber: "GB123"}
+	testApp.Currency = "USD"
+	testApp.ExchangeRates = currency.NewRates()
 
 	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 	testApp.InfoLog = infoLog
@@ -72,7 +88,11 @@ func TestMain(m *testing.M) {
 
 	repo := NewTestingRepo(&testApp)
 	NewHandlers(repo)
+	if err := repo.LoadExchangeRates(); err != nil {
+		log.Fatal(err)
+	}
 	render.NewRenderer(&testApp)
+	helpers.NewHelpers(&testApp)
 
 	os.Exit(m.Run())
 }
@@ -99,13 +119,28 @@ func getRoutes() http.Handler {
 	mux.Get("/search-availability", Repo.Availability)
 	mux.Post("/search-availability", Repo.PostAvailability)
 	mux.Post("/search-availability-json", Repo.AvailabilityJSON)
+	mux.Get("/availability-calendar", Repo.AvailabilityCalendarJSON)
+
+	mux.Get("/waitlist", Repo.Waitlist)
+	mux.Post("/waitlist", Repo.PostWaitlist)
+	mux.Get("/waitlist/book", Repo.WaitlistBook)
+	mux.Get("/waitlist/decline", Repo.WaitlistDecline)
 
 	mux.Get("/contact", Repo.Contact)
+	mux.Post("/currency", Repo.SetCurrency)
 
 	mux.Get("/make-reservation", Repo.Reservation)
 	mux.Post("/make-reservation", Repo.PostReservation)
 	mux.Get("/reservation-summary", Repo.ReservationSummary)
 
+	mux.Post("/payments/webhook", Repo.PaymentWebhook)
+	mux.Get("/payments/fake/{ref}", Repo.FakeCheckout)
+	mux.Post("/payments/fake/{ref}", Repo.PostFakeCheckout)
+
+	mux.Get("/reservations/{id}/documents", Repo.GuestDocuments)
+	mux.Get("/reservations/{id}/invoices/{number}", Repo.GuestInvoicePDF)
+	mux.Get("/reservations/{id}/receipts/{payment}", Repo.GuestReceiptPDF)
+
 	mux.Get("/user/login", Repo.ShowLogin)
 	mux.Post("/user/login", Repo.PostShowLogin)
 	mux.Get("/user/logout", Repo.Logout)
@@ -121,9 +156,90 @@ func getRoutes() http.Handler {
 	mux.Post("/admin/reservations-calendar", Repo.AdminPostReservationsCalendar)
 	mux.Get("/admin/process-reservation/{src}/{id}/do", Repo.AdminProcessReservation)
 	mux.Get("/admin/delete-reservation/{src}/{id}/do", Repo.AdminDeleteReservation)
+	mux.Get("/admin/cancel-reservation/{src}/{id}/do", Repo.AdminCancelReservation)
+
+	mux.Get("/admin/import", Repo.AdminImport)
+	mux.Post("/admin/import", Repo.AdminPostImport)
+
+	mux.Get("/admin/export/reservations", Repo.AdminExportReservations)
+	mux.Get("/admin/export/guests", Repo.AdminExportGuests)
+
+	mux.Get("/admin/reports", Repo.AdminReports)
+	mux.Get("/admin/reports/download", Repo.AdminDownloadReport)
+
+	mux.Get("/admin/front-desk", Repo.AdminFrontDesk)
+	mux.Get("/admin/check-in/{id}/do", Repo.AdminCheckIn)
+	mux.Get("/admin/check-out/{id}/do", Repo.AdminCheckOut)
+	mux.Get("/admin/no-show/{id}/do", Repo.AdminMarkNoShow)
+
+	mux.Get("/admin/housekeeping", Repo.AdminHousekeeping)
+	mux.Post("/admin/housekeeping/{id}", Repo.AdminPostHousekeeping)
+	mux.Get("/admin/maintenance", Repo.AdminMaintenance)
+	mux.Get("/admin/maintenance/{id}", Repo.AdminShowMaintenanceTicket)
+	mux.Post("/admin/maintenance/{id}", Repo.AdminPostMaintenanceTicket)
+
+	mux.Get("/admin/guests", Repo.AdminGuests)
+	mux.Get("/admin/guests/{id}", Repo.AdminShowGuest)
+	mux.Get("/admin/merge-guest/{id}/{duplicate}/do", Repo.AdminMergeGuest)
+
+	mux.Get("/admin/waitlist", Repo.AdminWaitlist)
+	mux.Get("/admin/delete-waitlist-entry/{id}/do", Repo.AdminDeleteWaitlistEntry)
 
 	mux.Get("/admin/reservations/{src}/{id}/show", Repo.AdminShowReservation)
 	mux.Post("/admin/reservations/{src}/{id}", Repo.AdminPostShowReservation)
+	mux.Post("/admin/reservations/{src}/{id}/stay", Repo.AdminPostReservationStay)
+	mux.Post("/admin/reservations/{src}/{id}/notes", Repo.AdminPostReservationNote)
+	mux.Get("/admin/delete-reservation-note/{src}/{id}/{note}/do", Repo.AdminDeleteReservationNote)
+	mux.Post("/admin/reservations/{src}/{id}/tags", Repo.AdminPostReservationTags)
+	mux.Post("/admin/reservations/{src}/{id}/extras", Repo.AdminPostReservationExtra)
+	mux.Get("/admin/delete-reservation-extra/{src}/{id}/{extra}/do", Repo.AdminDeleteReservationExtra)
+	mux.Post("/admin/reservations/{src}/{id}/vouchers", Repo.AdminPostReservationVoucher)
+	mux.Post("/admin/reservations/{src}/{id}/folio", Repo.AdminPostFolioEntry)
+	mux.Post("/admin/reservations/{src}/{id}/refunds", Repo.AdminPostRefund)
+	mux.Post("/admin/reservations/{src}/{id}/invoices", Repo.AdminPostInvoice)
+	mux.Get("/admin/reservations/{src}/{id}/invoices/{number}", Repo.AdminInvoicePDF)
+	mux.Get("/admin/reservations/{src}/{id}/receipts/{payment}", Repo.AdminReceiptPDF)
+	mux.Post("/admin/reservations/{src}/{id}/receipts/{payment}", Repo.AdminPostReceipt)
+
+	mux.Get("/admin/cancellation-policies", Repo.AdminCancellationPolicies)
+	mux.Post("/admin/cancellation-policies-rooms", Repo.AdminPostRoomCancellationPolicies)
+	mux.Get("/admin/cancellation-policies/{id}", Repo.AdminShowCancellationPolicy)
+	mux.Post("/admin/cancellation-policies/{id}", Repo.AdminPostCancellationPolicy)
+	mux.Get("/admin/delete-cancellation-policy/{id}/do", Repo.AdminDeleteCancellationPolicy)
+
+	mux.Get("/admin/tax-rules", Repo.AdminTaxRules)
+	mux.Get("/admin/tax-rules/{id}", Repo.AdminShowTaxRule)
+	mux.Post("/admin/tax-rules/{id}", Repo.AdminPostTaxRule)
+	mux.Get("/admin/delete-tax-rule/{id}/do", Repo.AdminDeleteTaxRule)
+
+	mux.Get("/admin/promo-codes", Repo.AdminPromoCodes)
+	mux.Get("/admin/promo-codes/{id}", Repo.AdminShowPromoCode)
+	mux.Post("/admin/promo-codes/{id}", Repo.AdminPostPromoCode)
+	mux.Get("/admin/delete-promo-code/{id}/do", Repo.AdminDeletePromoCode)
+
+	mux.Get("/admin/price-rules", Repo.AdminPriceRules)
+	mux.Get("/admin/price-rules/{id}", Repo.AdminShowPriceRule)
+	mux.Post("/admin/price-rules/{id}", Repo.AdminPostPriceRule)
+	mux.Get("/admin/delete-price-rule/{id}/do", Repo.AdminDeletePriceRule)
+
+	mux.Get("/admin/rate-plans", Repo.AdminRatePlans)
+	mux.Get("/admin/rate-plans/{id}", Repo.AdminShowRatePlan)
+	mux.Post("/admin/rate-plans/{id}", Repo.AdminPostRatePlan)
+	mux.Get("/admin/delete-rate-plan/{id}/do", Repo.AdminDeleteRatePlan)
+
+	mux.Get("/admin/extras", Repo.AdminExtras)
+	mux.Get("/admin/extras/{id}", Repo.AdminShowExtra)
+	mux.Post("/admin/extras/{id}", Repo.AdminPostExtra)
+	mux.Get("/admin/delete-extra/{id}/do", Repo.AdminDeleteExtra)
+
+	mux.Get("/admin/gift-vouchers", Repo.AdminGiftVouchers)
+	mux.Get("/admin/gift-vouchers/{id}", Repo.AdminShowGiftVoucher)
+	mux.Post("/admin/gift-vouchers/{id}", Repo.AdminPostGiftVoucher)
+
+	mux.Get("/admin/exchange-rates", Repo.AdminExchangeRates)
+	mux.Get("/admin/exchange-rates/{id}", Repo.AdminShowExchangeRate)
+	mux.Post("/admin/exchange-rates/{id}", Repo.AdminPostExchangeRate)
+	mux.Get("/admin/delete-exchange-rate/{id}/do", Repo.AdminDeleteExchangeRate)
 
 	return mux
 }
@@ -178,3 +294,13 @@ func CreateTestTemplateCache() (map[string]*template.Template, error) {
 	}
 	return myCache, nil
 }
+
+//withURLParams adds chi URL parameters to a request that does not go through the router
+func withURLParams(r *http.Request, params map[string]string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
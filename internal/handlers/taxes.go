@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/forms"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/pricing"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//maxGuests is the most guests a reservation can be made for
+const maxGuests = 20
+
+//priceStay sets the total price of a reservation in a room, with the adjustments already rated for it, the
+//discount of a promo code, if its ID is not zero, the taxes charged on the discounted price and the extras
+//booked with it
+func (m *Repository) priceStay(res *models.Reservation, room models.Room, promo models.PromoCode) error {
+	rules, err := m.DB.TaxRules()
+	if err != nil {
+		return err
+	}
+
+	price := pricing.RatedPrice(room, res.StartDate, res.EndDate, res.Adjustments)
+
+	res.PromoCodeID, res.PromoCode, res.Discount = 0, "", 0
+	if promo.ID > 0 {
+		res.PromoCodeID = promo.ID
+		res.PromoCode = promo.Code
+		res.Discount = pricing.Discount(promo, price)
+		price -= res.Discount
+	}
+
+	res.Taxes = pricing.Taxes(rules, *res, price)
+	res.TotalPrice = pricing.TotalWithTaxes(price, res.Taxes) + res.ExtrasTotal()
+
+	return nil
+}
+
+//optionalDate returns the date posted in a form field, a zero time when it is left empty or invalid, which
+//is reported as a form error
+func optionalDate(form *forms.Form, field string) time.Time {
+	s := form.Get(field)
+	if s == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		form.Errors.Add(field, "Invalid date")
+	}
+
+	return t
+}
+
+//AdminTaxRules shows all tax rules
+func (m *Repository) AdminTaxRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := m.DB.TaxRules()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["rules"] = rules
+
+	render.Template(w, r, "admin-tax-rules.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+//renderTaxRule renders the form of a tax rule
+func (m *Repository) renderTaxRule(w http.ResponseWriter, r *http.Request, rule models.TaxRule, form *forms.Form) {
+	stringMap := make(map[string]string)
+	if !rule.ValidFrom.IsZero() {
+		stringMap["valid_from"] = rule.ValidFrom.Format("2006-01-02")
+	}
+	if !rule.ValidTo.IsZero() {
+		stringMap["valid_to"] = rule.ValidTo.Format("2006-01-02")
+	}
+	stringMap["rate"] = render.Money(rule.Rate)
+
+	data := make(map[string]interface{})
+	data["rule"] = rule
+	data["kinds"] = models.TaxKinds
+
+	render.Template(w, r, "admin-tax-rule-show.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+		Form:      form,
+	})
+}
+
+//AdminShowTaxRule shows the form for a new or existing tax rule
+func (m *Repository) AdminShowTaxRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	rule := models.TaxRule{
+		Kind: models.TaxPercent,
+	}
+
+	if id > 0 {
+		rule, err = m.DB.GetTaxRuleByID(id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	m.renderTaxRule(w, r, rule, forms.New(nil))
+}
+
+//AdminPostTaxRule handles the posting of a tax rule form
+func (m *Repository) AdminPostTaxRule(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("name", "kind", "rate")
+	if r.Form.Get("max_nights") != "" {
+		form.IsInt("max_nights", 0, 365)
+	}
+
+	maxNights, _ := strconv.Atoi(r.Form.Get("max_nights"))
+
+	rule := models.TaxRule{
+		ID:        id,
+		Name:      strings.TrimSpace(r.Form.Get("name")),
+		Kind:      r.Form.Get("kind"),
+		Inclusive: r.Form.Get("inclusive") != "",
+		MaxNights: maxNights,
+		ExemptTag: strings.TrimSpace(r.Form.Get("exempt_tag")),
+	}
+
+	if !isOneOf(rule.Kind, models.TaxKinds) {
+		form.Errors.Add("kind", "Unknown kind of tax")
+	}
+
+	rule.Rate, err = parseMoney(r.Form.Get("rate"))
+	if err != nil {
+		form.Errors.Add("rate", "Invalid rate")
+	}
+
+	if rule.Inclusive && rule.Kind != models.TaxPercent {
+		form.Errors.Add("inclusive", "Only percentages can be included in room prices")
+	}
+
+	rule.ValidFrom = optionalDate(form, "valid_from")
+	rule.ValidTo = optionalDate(form, "valid_to")
+
+	if !rule.ValidFrom.IsZero() && !rule.ValidTo.IsZero() && rule.ValidTo.Before(rule.ValidFrom) {
+		form.Errors.Add("valid_to", "Rule must end after it starts")
+	}
+
+	if !form.Valid() {
+		m.renderTaxRule(w, r, rule, form)
+		return
+	}
+
+	if id > 0 {
+		err = m.DB.UpdateTaxRule(rule)
+	} else {
+		_, err = m.DB.InsertTaxRule(rule)
+	}
+
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/tax-rules", http.StatusSeeOther)
+}
+
+//AdminDeleteTaxRule deletes a tax rule, reservations keep the taxes they were charged
+func (m *Repository) AdminDeleteTaxRule(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeleteTaxRule(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Tax rule deleted")
+	http.Redirect(w, r, "/admin/tax-rules", http.StatusSeeOther)
+}
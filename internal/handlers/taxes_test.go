@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestRepository_priceStay(t *testing.T) {
+	res := models.Reservation{
+		StartDate: time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2050, 1, 3, 0, 0, 0, 0, time.UTC),
+		Guests:    2,
+	}
+
+	err := Repo.priceStay(&res, models.Room{ID: 1, Price: 12000}, models.PromoCode{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the VAT is included in the room price, the levy of 2.50 per guest per night is added
+	if len(res.Taxes) != 2 || res.Taxes[0].Amount != 4000 || res.Taxes[1].Amount != 1000 {
+		t.Errorf("wrong taxes %v", res.Taxes)
+	}
+
+	if res.TotalPrice != 25000 || res.RoomPrice() != 24000 {
+		t.Errorf("expected total 25000 with room price 24000, but got %d and %d", res.TotalPrice, res.RoomPrice())
+	}
+
+	res.Tags = []string{"resident"}
+	if err = Repo.priceStay(&res, models.Room{ID: 1, Price: 12000}, models.PromoCode{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Taxes) != 1 || res.TotalPrice != 24000 {
+		t.Errorf("expected the levy to be exempt, but got %v and total %d", res.Taxes, res.TotalPrice)
+	}
+
+	//taxes are charged on the discounted price
+	promo := models.PromoCode{ID: 1, Code: "SUMMER", Kind: models.PromoPercent, Amount: 1000}
+	if err = Repo.priceStay(&res, models.Room{ID: 1, Price: 12000}, promo); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.PromoCodeID != 1 || res.PromoCode != "SUMMER" || res.Discount != 2400 || res.TotalPrice != 21600 ||
+		res.Taxes[0].Amount != 3600 {
+		t.Errorf("wrong discount %d on total %d with taxes %v", res.Discount, res.TotalPrice, res.Taxes)
+	}
+}
+
+func TestRepository_AdminTaxRules(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+	}{
+		{
+			name:               "rules",
+			url:                "/admin/tax-rules",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "new-rule",
+			url:                "/admin/tax-rules/0",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "existing-rule",
+			url:                "/admin/tax-rules/1",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "missing-rule",
+			url:                "/admin/tax-rules/101",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "invalid-rule-id",
+			url:                "/admin/tax-rules/invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "delete-rule",
+			url:                "/admin/delete-tax-rule/1/do",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "delete-rule-error",
+			url:                "/admin/delete-tax-rule/101/do",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	routes := getRoutes()
+	ts := httptest.NewServer(routes)
+	defer ts.Close()
+
+	for _, tt := range theTests {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected %d but got %d", tt.name, tt.expectedStatusCode, resp.StatusCode)
+		}
+	}
+}
+
+func TestRepository_AdminPostTaxRule(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name: "valid-new-rule",
+			id:   "0",
+			postedData: url.Values{
+				"name":      {"VAT"},
+				"kind":      {"percent"},
+				"rate":      {"20"},
+				"inclusive": {"1"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/tax-rules",
+		},
+		{
+			name: "valid-existing-rule",
+			id:   "1",
+			postedData: url.Values{
+				"name":       {"Tourist levy"},
+				"kind":       {"per_guest_night"},
+				"rate":       {"2.50"},
+				"valid_from": {"2050-04-01"},
+				"valid_to":   {"2050-10-31"},
+				"max_nights": {"7"},
+				"exempt_tag": {"Resident"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/tax-rules",
+		},
+		{
+			name: "invalid-kind",
+			id:   "0",
+			postedData: url.Values{
+				"name": {"VAT"},
+				"kind": {"everything"},
+				"rate": {"20"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-rate",
+			id:   "0",
+			postedData: url.Values{
+				"name": {"VAT"},
+				"kind": {"percent"},
+				"rate": {"twenty"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "fixed-tax-included",
+			id:   "0",
+			postedData: url.Values{
+				"name":      {"Tourist levy"},
+				"kind":      {"per_night"},
+				"rate":      {"2.50"},
+				"inclusive": {"1"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-dates",
+			id:   "0",
+			postedData: url.Values{
+				"name":       {"Tourist levy"},
+				"kind":       {"per_night"},
+				"rate":       {"2.50"},
+				"valid_from": {"2050-10-31"},
+				"valid_to":   {"2050-04-01"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-max-nights",
+			id:   "0",
+			postedData: url.Values{
+				"name":       {"Tourist levy"},
+				"kind":       {"per_night"},
+				"rate":       {"2.50"},
+				"max_nights": {"-1"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "database-error",
+			id:   "0",
+			postedData: url.Values{
+				"name": {"error"},
+				"kind": {"percent"},
+				"rate": {"20"},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "invalid-id",
+			id:                 "invalid",
+			postedData:         url.Values{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/tax-rules/"+tt.id, strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostTaxRule)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
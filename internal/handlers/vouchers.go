@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/forms"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/payments"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+	"github.com/yalagtyarzh/leafsite/internal/repository"
+)
+
+//voucherCodeAttempts is how many random codes are tried before giving up on issuing a gift voucher
+const voucherCodeAttempts = 5
+
+//findVoucher looks up a gift voucher entered by a guest or staff, returning a message explaining why it
+//can't be redeemed now, empty if it can
+func (m *Repository) findVoucher(code string) (models.GiftVoucher, string, error) {
+	v, found, err := m.DB.GetGiftVoucherByCode(code)
+	if err != nil {
+		return v, "", err
+	}
+
+	if !found {
+		return v, "Sorry, we don't know this voucher", nil
+	}
+
+	if err = payments.CheckVoucher(v, time.Now()); err != nil {
+		return v, "Sorry, " + err.Error(), nil
+	}
+
+	return v, "", nil
+}
+
+//newVoucherCode returns a random code no gift voucher has yet
+func (m *Repository) newVoucherCode() (string, error) {
+	for i := 0; i < voucherCodeAttempts; i++ {
+		code, err := payments.NewVoucherCode()
+		if err != nil {
+			return "", err
+		}
+
+		_, found, err := m.DB.GetGiftVoucherByCode(code)
+		if err != nil {
+			return "", err
+		}
+
+		if !found {
+			return code, nil
+		}
+	}
+
+	return "", errors.New("can't find an unused gift voucher code")
+}
+
+//AdminGiftVouchers shows all gift vouchers with their balances
+func (m *Repository) AdminGiftVouchers(w http.ResponseWriter, r *http.Request) {
+	vouchers, err := m.DB.GiftVouchers()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	var total models.GiftVoucher
+	for _, v := range vouchers {
+		total.Amount += v.Amount
+		total.Balance += v.Balance
+	}
+
+	data := make(map[string]interface{})
+	data["vouchers"] = vouchers
+	data["total"] = total
+
+	render.Template(w, r, "admin-gift-vouchers.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+//renderGiftVoucher renders the form of a gift voucher with its ledger
+func (m *Repository) renderGiftVoucher(w http.ResponseWriter, r *http.Request, v models.GiftVoucher, form *forms.Form) {
+	stringMap := make(map[string]string)
+	if !v.ExpiresAt.IsZero() {
+		stringMap["expires_at"] = v.ExpiresAt.Format("2006-01-02")
+	}
+	stringMap["amount"] = render.Money(v.Amount)
+
+	data := make(map[string]interface{})
+	data["voucher"] = v
+
+	render.Template(w, r, "admin-gift-voucher-show.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+		Form:      form,
+	})
+}
+
+//AdminShowGiftVoucher shows the form to sell a new gift voucher or to change an existing one
+func (m *Repository) AdminShowGiftVoucher(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	//vouchers sold are valid for a year unless staff choose otherwise
+	v := models.GiftVoucher{
+		ExpiresAt: time.Now().AddDate(1, 0, 0),
+	}
+
+	if id > 0 {
+		v, err = m.DB.GetGiftVoucherByID(id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	m.renderGiftVoucher(w, r, v, forms.New(nil))
+}
+
+//AdminPostGiftVoucher handles the posting of a gift voucher form. New vouchers get a random code, the code
+//and amount of a voucher sold can't be changed
+func (m *Repository) AdminPostGiftVoucher(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+
+	v := models.GiftVoucher{
+		ID:        id,
+		Purchaser: strings.TrimSpace(form.Get("purchaser")),
+		Recipient: strings.TrimSpace(form.Get("recipient")),
+		ExpiresAt: optionalDate(form, "expires_at"),
+	}
+
+	if id > 0 {
+		existing, err := m.DB.GetGiftVoucherByID(id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		v.Code = existing.Code
+		v.Amount = existing.Amount
+		v.Balance = existing.Balance
+		v.Redemptions = existing.Redemptions
+	} else {
+		form.Required("amount")
+
+		v.Amount, err = parseMoney(form.Get("amount"))
+		if err != nil || v.Amount == 0 {
+			form.Errors.Add("amount", "Invalid amount")
+		}
+		v.Balance = v.Amount
+	}
+
+	if !form.Valid() {
+		m.renderGiftVoucher(w, r, v, form)
+		return
+	}
+
+	flash := "Changes saved"
+	if id > 0 {
+		err = m.DB.UpdateGiftVoucher(v)
+	} else {
+		v.Code, err = m.newVoucherCode()
+		if err == nil {
+			_, err = m.DB.InsertGiftVoucher(v)
+		}
+		flash = fmt.Sprintf("Gift voucher %s issued for %s", v.Code, render.Money(v.Amount))
+	}
+
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, "/admin/gift-vouchers", http.StatusSeeOther)
+}
+
+//AdminPostReservationVoucher pays some or all of what is owed on the folio of a reservation with a gift
+//voucher. Without an amount as much of the balance as the voucher covers is paid
+func (m *Repository) AdminPostReservationVoucher(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	showURL := showReservationURL(chi.URLParam(r, "src"), id, r.Form.Get("year"), r.Form.Get("month"))
+
+	res, err := m.DB.GetReservationByID(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	folio, err := m.folio(res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	v, problem, err := m.findVoucher(strings.TrimSpace(r.Form.Get("code")))
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if problem != "" {
+		m.App.Session.Put(r.Context(), "error", problem)
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	covered := payments.VoucherAmount(v, folio.Balance())
+
+	amount := covered
+	if r.Form.Get("amount") != "" {
+		amount, err = parseMoney(r.Form.Get("amount"))
+		if err != nil {
+			amount = 0
+		}
+	}
+
+	if amount == 0 || amount > covered {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Redeem an amount up to %s", render.Money(covered)))
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	_, err = m.DB.RedeemGiftVoucher(v.ID, res.ID, amount)
+	if errors.Is(err, repository.ErrVoucherBalance) {
+		m.App.Session.Put(r.Context(), "error", "Not enough is left on the voucher")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("%s paid with gift voucher %s", render.Money(amount), v.Code))
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
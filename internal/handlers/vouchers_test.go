@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRepository_AdminGiftVouchers(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		url                string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{"vouchers", "/admin/gift-vouchers", http.StatusOK, "GIFT-50"},
+		{"new-voucher", "/admin/gift-vouchers/0", http.StatusOK, "name=\"amount\""},
+		{"existing-voucher", "/admin/gift-vouchers/3", http.StatusOK, "Refund put back"},
+		{"missing-voucher", "/admin/gift-vouchers/101", http.StatusInternalServerError, ""},
+		{"invalid-voucher-id", "/admin/gift-vouchers/invalid", http.StatusBadRequest, ""},
+	}
+
+	routes := getRoutes()
+	ts := httptest.NewServer(routes)
+	defer ts.Close()
+
+	for _, tt := range theTests {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected %d but got %d", tt.name, tt.expectedStatusCode, resp.StatusCode)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if tt.expectedBody != "" && !strings.Contains(string(body), tt.expectedBody) {
+			t.Errorf("failed %s: expected %q in body", tt.name, tt.expectedBody)
+		}
+	}
+}
+
+func TestRepository_AdminPostGiftVoucher(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name: "valid-new-voucher",
+			id:   "0",
+			postedData: url.Values{
+				"amount":     {"100"},
+				"purchaser":  {"Jane Doe"},
+				"recipient":  {"John Doe"},
+				"expires_at": {"2050-01-01"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/gift-vouchers",
+		},
+		{
+			name: "valid-existing-voucher",
+			id:   "1",
+			postedData: url.Values{
+				"recipient": {"John Doe"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/admin/gift-vouchers",
+		},
+		{
+			name:               "missing-amount",
+			id:                 "0",
+			postedData:         url.Values{},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-amount",
+			id:   "0",
+			postedData: url.Values{
+				"amount": {"lots"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-expiry",
+			id:   "1",
+			postedData: url.Values{
+				"expires_at": {"someday"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "database-error",
+			id:   "0",
+			postedData: url.Values{
+				"amount":    {"100"},
+				"purchaser": {"error"},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "missing-voucher",
+			id:                 "101",
+			postedData:         url.Values{},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "invalid-id",
+			id:                 "invalid",
+			postedData:         url.Values{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/gift-vouchers/"+tt.id, strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostGiftVoucher)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
+
+func TestRepository_AdminPostReservationVoucher(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		id                 string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedFlash      string
+		expectedError      string
+	}{
+		{"as-much-as-covered", "1", url.Values{"code": {"gift-50"}}, http.StatusSeeOther, "50.00 paid with gift voucher GIFT-50", ""},
+		{"balance-owed", "1", url.Values{"code": {"GIFT-1000"}}, http.StatusSeeOther, "170.00 paid with gift voucher GIFT-1000", ""},
+		{"partly", "1", url.Values{"code": {"GIFT-1000"}, "amount": {"20.00"}}, http.StatusSeeOther, "20.00 paid with gift voucher GIFT-1000", ""},
+		{"more-than-left", "1", url.Values{"code": {"GIFT-50"}, "amount": {"50.01"}}, http.StatusSeeOther, "", "Redeem an amount up to 50.00"},
+		{"more-than-owed", "1", url.Values{"code": {"GIFT-1000"}, "amount": {"170.01"}}, http.StatusSeeOther, "", "Redeem an amount up to 170.00"},
+		{"invalid-amount", "1", url.Values{"code": {"GIFT-50"}, "amount": {"x"}}, http.StatusSeeOther, "", "Redeem an amount up to 50.00"},
+		{"unknown-voucher", "1", url.Values{"code": {"NOTHING"}}, http.StatusSeeOther, "", "Sorry, we don't know this voucher"},
+		{"expired", "1", url.Values{"code": {"EXPIRED"}}, http.StatusSeeOther, "", "Sorry, this voucher has expired"},
+		{"used-up", "1", url.Values{"code": {"USED-UP"}}, http.StatusSeeOther, "", "Sorry, this voucher has been used up"},
+		{"used-up-meanwhile", "1", url.Values{"code": {"RACE"}}, http.StatusSeeOther, "", "Not enough is left on the voucher"},
+		{"voucher-error", "1", url.Values{"code": {"ERROR"}}, http.StatusInternalServerError, "", ""},
+		{"redeem-error", "1000", url.Values{"code": {"GIFT-50"}}, http.StatusInternalServerError, "", ""},
+		{"invalid-id", "x", url.Values{"code": {"GIFT-50"}}, http.StatusBadRequest, "", ""},
+		{"reservation-not-found", "1001", url.Values{"code": {"GIFT-50"}}, http.StatusInternalServerError, "", ""},
+		{"folio-error", "999", url.Values{"code": {"GIFT-50"}}, http.StatusInternalServerError, "", ""},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/admin/reservations/all/"+tt.id+"/vouchers", strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req = withURLParams(req, map[string]string{"src": "all", "id": tt.id})
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostReservationVoucher)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedStatusCode == http.StatusSeeOther {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != "/admin/reservations/all/"+tt.id+"/show" {
+				t.Errorf("failed %s: expected location /admin/reservations/all/%s/show, but got %s", tt.name, tt.id, actualLoc.String())
+			}
+		}
+
+		if msg := session.PopString(ctx, "flash"); msg != tt.expectedFlash {
+			t.Errorf("failed %s: expected flash %q, but got %q", tt.name, tt.expectedFlash, msg)
+		}
+
+		if msg := session.PopString(ctx, "error"); msg != tt.expectedError {
+			t.Errorf("failed %s: expected error %q, but got %q", tt.name, tt.expectedError, msg)
+		}
+	}
+}
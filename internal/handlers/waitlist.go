@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/yalagtyarzh/leafsite/internal/forms"
+	"github.com/yalagtyarzh/leafsite/internal/helpers"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+	"github.com/yalagtyarzh/leafsite/internal/urlsigner"
+)
+
+//waitlistOfferLifetime is how long the booking link sent to a waitlisted guest stays valid
+const waitlistOfferLifetime = 24 * time.Hour
+
+//Waitlist renders the form to join the waitlist, prefilled with the dates and room of the search
+func (m *Repository) Waitlist(w http.ResponseWriter, r *http.Request) {
+	rooms, err := m.DB.AllRooms()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	roomID, _ := strconv.Atoi(r.URL.Query().Get("room_id"))
+
+	stringMap := make(map[string]string)
+	stringMap["start"] = r.URL.Query().Get("s")
+	stringMap["end"] = r.URL.Query().Get("e")
+
+	data := make(map[string]interface{})
+	data["entry"] = models.WaitlistEntry{RoomID: roomID}
+	data["rooms"] = rooms
+
+	render.Template(w, r, "waitlist.page.tmpl", &models.TemplateData{
+		Form:      forms.New(nil),
+		Data:      data,
+		StringMap: stringMap,
+	})
+}
+
+//PostWaitlist puts a guest on the waitlist
+func (m *Repository) PostWaitlist(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't parse form")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("first_name", "last_name", "email", "start", "end")
+	form.MinLength("first_name", 3)
+	form.IsEmail("email")
+
+	layout := "2006-01-02"
+
+	startDate, err := time.Parse(layout, r.Form.Get("start"))
+	if err != nil {
+		form.Errors.Add("start", "Invalid arrival date")
+	}
+
+	endDate, err := time.Parse(layout, r.Form.Get("end"))
+	if err != nil {
+		form.Errors.Add("end", "Invalid departure date")
+	} else if !endDate.After(startDate) {
+		form.Errors.Add("end", "Departure must be after arrival")
+	}
+
+	roomID, err := strconv.Atoi(r.Form.Get("room_id"))
+	if err != nil {
+		roomID = 0
+	}
+
+	if roomID > 0 {
+		_, err = m.DB.GetRoomByID(roomID)
+		if err != nil {
+			form.Errors.Add("room_id", "Invalid room")
+		}
+	}
+
+	entry := models.WaitlistEntry{
+		FirstName: r.Form.Get("first_name"),
+		LastName:  r.Form.Get("last_name"),
+		Email:     r.Form.Get("email"),
+		RoomID:    roomID,
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+
+	if !form.Valid() {
+		rooms, err := m.DB.AllRooms()
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		stringMap := make(map[string]string)
+		stringMap["start"] = r.Form.Get("start")
+		stringMap["end"] = r.Form.Get("end")
+
+		data := make(map[string]interface{})
+		data["entry"] = entry
+		data["rooms"] = rooms
+
+		render.Template(w, r, "waitlist.page.tmpl", &models.TemplateData{
+			Form:      form,
+			Data:      data,
+			StringMap: stringMap,
+		})
+		return
+	}
+
+	_, err = m.DB.InsertWaitlistEntry(entry)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't add you to the waitlist")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "You are on the waitlist, we will email you as soon as a room becomes available")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+//WaitlistBook takes a guest from the booking link of a waitlist email to the reservation form, holding the room
+func (m *Repository) WaitlistBook(w http.ResponseWriter, r *http.Request) {
+	err := m.App.Signer.Verify(r.RequestURI)
+	if err == urlsigner.ErrExpired {
+		m.App.Session.Put(r.Context(), "error", "This booking link has expired")
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
+
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid booking link")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	roomID, err := strconv.Atoi(r.URL.Query().Get("room_id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	entry, err := m.DB.GetWaitlistEntryByID(id)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't get waitlist entry")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if entry.Status != models.WaitlistNotified {
+		m.App.Session.Put(r.Context(), "error", "Invalid booking link")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	room, err := m.DB.GetRoomByID(roomID)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't get room from db")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	res := models.Reservation{
+		FirstName: entry.FirstName,
+		LastName:  entry.LastName,
+		Email:     entry.Email,
+		StartDate: entry.StartDate,
+		EndDate:   entry.EndDate,
+		RoomID:    roomID,
+	}
+	res.Room.RoomName = room.RoomName
+
+	held, err := m.placeHold(r, res)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't hold room")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if !held {
+		m.App.Session.Put(r.Context(), "error", "Sorry, this room has just been taken")
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
+
+	//the offer is taken up when the reservation is booked, so the link can't book the room twice
+	m.App.Session.Put(r.Context(), "reservation", res)
+	m.App.Session.Put(r.Context(), "waitlist_id", entry.ID)
+
+	http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
+}
+
+//WaitlistDecline turns down the room offered by a waitlist email, which is then offered to the next guest in
+//line
+func (m *Repository) WaitlistDecline(w http.ResponseWriter, r *http.Request) {
+	err := m.App.Signer.Verify(r.RequestURI)
+	if err == urlsigner.ErrExpired {
+		m.App.Session.Put(r.Context(), "error", "This offer has already expired")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid link")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	entry, err := m.DB.GetWaitlistEntryByID(id)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't get waitlist entry")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	declined, err := m.DB.DeclineWaitlistOffer(entry.ID)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Can't decline the offer")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if !declined {
+		m.App.Session.Put(r.Context(), "error", "This offer is no longer open")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	m.notifyWaitlist(entry.OfferedRoomID, entry.StartDate, entry.EndDate)
+
+	m.App.Session.Put(r.Context(), "flash", "Thank you, we have offered the room to the next guest")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+//ExpireWaitlistOffers closes the waitlist offers whose booking link has expired and offers their rooms to the
+//next guests in line, returning how many were closed
+func (m *Repository) ExpireWaitlistOffers() (int, error) {
+	entries, err := m.DB.ExpireWaitlistOffers()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range entries {
+		if e.OfferedRoomID > 0 {
+			m.notifyWaitlist(e.OfferedRoomID, e.StartDate, e.EndDate)
+		}
+	}
+
+	return len(entries), nil
+}
+
+//notifyWaitlist offers nights of a room that have just been freed to the first guest in line whose stay fits
+//into them, by emailing a booking link. The next guest is offered the room once the link expires or is declined
+func (m *Repository) notifyWaitlist(roomID int, start, end time.Time) {
+	entries, err := m.DB.WaitingEntriesForRoom(roomID, start, end)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+
+	for _, e := range entries {
+		available, err := m.DB.SearchAvailabilityByDatesByRoomID(e.StartDate, e.EndDate, roomID)
+		if err != nil {
+			m.App.ErrorLog.Println(err)
+			continue
+		}
+
+		if !available {
+			continue
+		}
+
+		expires := time.Now().Add(waitlistOfferLifetime)
+		link := m.App.Signer.Sign(fmt.Sprintf("%s/waitlist/book?id=%d&room_id=%d", m.App.URL, e.ID, roomID), expires)
+		declineLink := m.App.Signer.Sign(fmt.Sprintf("%s/waitlist/decline?id=%d", m.App.URL, e.ID), expires)
+
+		htmlMessage := fmt.Sprintf(`
+			<strong>A Room Is Available</strong><br>
+			Dear %s: <br>
+			A room has become available for your stay from %s to %s.<br>
+			<a href="%s">Book it now</a>, the link is valid until %s.<br>
+			No longer need it? <a href="%s">Let the next guest have it</a>.
+		`, e.FirstName, e.StartDate.Format("2006-01-02"), e.EndDate.Format("2006-01-02"), link,
+			expires.Format("2006-01-02 15:04"), declineLink)
+
+		m.App.MailChan <- models.MailData{
+			To:       e.Email,
+			From:     "me@here.com",
+			Subject:  "A Room Is Available",
+			Content:  htmlMessage,
+			Template: "basic.html",
+		}
+
+		err = m.DB.MarkWaitlistEntryNotified(e.ID, roomID, expires)
+		if err != nil {
+			m.App.ErrorLog.Println(err)
+		}
+
+		return
+	}
+}
+
+//AdminWaitlist shows all guests on the waitlist
+func (m *Repository) AdminWaitlist(w http.ResponseWriter, r *http.Request) {
+	entries, err := m.DB.AllWaitlistEntries()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["entries"] = entries
+
+	render.Template(w, r, "admin-waitlist.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+//AdminDeleteWaitlistEntry removes a guest from the waitlist
+func (m *Repository) AdminDeleteWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeleteWaitlistEntry(id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Waitlist entry deleted")
+	http.Redirect(w, r, "/admin/waitlist", http.StatusSeeOther)
+}
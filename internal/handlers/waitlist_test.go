@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestRepository_PostWaitlist(t *testing.T) {
+	var theTests = []struct {
+		name               string
+		postedData         url.Values
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name: "valid-any-room",
+			postedData: url.Values{
+				"first_name": {"John"},
+				"last_name":  {"Smith"},
+				"email":      {"john@smith.com"},
+				"start":      {"2050-01-01"},
+				"end":        {"2050-01-02"},
+				"room_id":    {"0"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name: "valid-room",
+			postedData: url.Values{
+				"first_name": {"John"},
+				"last_name":  {"Smith"},
+				"email":      {"john@smith.com"},
+				"start":      {"2050-01-01"},
+				"end":        {"2050-01-02"},
+				"room_id":    {"1"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name: "invalid-email",
+			postedData: url.Values{
+				"first_name": {"John"},
+				"last_name":  {"Smith"},
+				"email":      {"john"},
+				"start":      {"2050-01-01"},
+				"end":        {"2050-01-02"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "departure-before-arrival",
+			postedData: url.Values{
+				"first_name": {"John"},
+				"last_name":  {"Smith"},
+				"email":      {"john@smith.com"},
+				"start":      {"2050-01-02"},
+				"end":        {"2050-01-01"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "invalid-room",
+			postedData: url.Values{
+				"first_name": {"John"},
+				"last_name":  {"Smith"},
+				"email":      {"john@smith.com"},
+				"start":      {"2050-01-01"},
+				"end":        {"2050-01-02"},
+				"room_id":    {"5"},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "insert-error",
+			postedData: url.Values{
+				"first_name": {"John"},
+				"last_name":  {"Smith"},
+				"email":      {"john@smith.com"},
+				"start":      {"2050-01-01"},
+				"end":        {"2050-01-02"},
+				"room_id":    {"1000"},
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("POST", "/waitlist", strings.NewReader(tt.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.PostWaitlist)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+	}
+}
+
+func TestRepository_WaitlistBook(t *testing.T) {
+	valid := time.Now().Add(time.Hour)
+
+	var theTests = []struct {
+		name               string
+		requestURI         string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name:               "valid-link",
+			requestURI:         testApp.Signer.Sign("/waitlist/book?id=1&room_id=1", valid),
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/make-reservation",
+		},
+		{
+			name:               "expired-link",
+			requestURI:         testApp.Signer.Sign("/waitlist/book?id=1&room_id=1", time.Now().Add(-time.Minute)),
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/search-availability",
+		},
+		{
+			name:               "unsigned-link",
+			requestURI:         "/waitlist/book?id=1&room_id=1",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "tampered-link",
+			requestURI:         strings.Replace(testApp.Signer.Sign("/waitlist/book?id=1&room_id=1", valid), "room_id=1", "room_id=2", 1),
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "missing-id",
+			requestURI:         testApp.Signer.Sign("/waitlist/book?room_id=1", valid),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "not-notified",
+			requestURI:         testApp.Signer.Sign("/waitlist/book?id=2&room_id=1", valid),
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "entry-not-found",
+			requestURI:         testApp.Signer.Sign("/waitlist/book?id=101&room_id=1", valid),
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "room-not-found",
+			requestURI:         testApp.Signer.Sign("/waitlist/book?id=1&room_id=5", valid),
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "hold-error",
+			requestURI:         testApp.Signer.Sign("/waitlist/book?id=1&room_id=1000", valid),
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", tt.requestURI, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.RequestURI = tt.requestURI
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.WaitlistBook)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if tt.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != tt.expectedLocation {
+				t.Errorf("failed %s: expected location %s, but got location %s", tt.name, tt.expectedLocation, actualLoc.String())
+			}
+		}
+
+		if tt.expectedLocation == "/make-reservation" {
+			res, ok := session.Get(ctx, "reservation").(models.Reservation)
+			if !ok || res.Email != "john@smith.com" || res.RoomID != 1 {
+				t.Errorf("failed %s: expected reservation of the waitlisted guest in session, but got %v", tt.name, res)
+			}
+
+			if id := session.GetInt(ctx, "waitlist_id"); id != 1 {
+				t.Errorf("failed %s: expected waitlist entry 1 in session, but got %d", tt.name, id)
+			}
+		}
+	}
+}
+
+func TestRepository_WaitlistDecline(t *testing.T) {
+	valid := time.Now().Add(time.Hour)
+
+	var theTests = []struct {
+		name               string
+		requestURI         string
+		expectedStatusCode int
+		expectedFlash      string
+		expectedError      string
+	}{
+		{"valid-link", testApp.Signer.Sign("/waitlist/decline?id=1", valid), http.StatusSeeOther, "Thank you, we have offered the room to the next guest", ""},
+		{"not-open", testApp.Signer.Sign("/waitlist/decline?id=2", valid), http.StatusSeeOther, "", "This offer is no longer open"},
+		{"expired-link", testApp.Signer.Sign("/waitlist/decline?id=1", time.Now().Add(-time.Minute)), http.StatusSeeOther, "", "This offer has already expired"},
+		{"unsigned-link", "/waitlist/decline?id=1", http.StatusSeeOther, "", "Invalid link"},
+		{"missing-id", testApp.Signer.Sign("/waitlist/decline", valid), http.StatusBadRequest, "", ""},
+		{"entry-not-found", testApp.Signer.Sign("/waitlist/decline?id=101", valid), http.StatusSeeOther, "", "Can't get waitlist entry"},
+		{"database-error", testApp.Signer.Sign("/waitlist/decline?id=3", valid), http.StatusSeeOther, "", "Can't decline the offer"},
+	}
+
+	for _, tt := range theTests {
+		req, _ := http.NewRequest("GET", tt.requestURI, nil)
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.RequestURI = tt.requestURI
+
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.WaitlistDecline)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatusCode {
+			t.Errorf("failed %s: expected code %d, but got %d", tt.name, tt.expectedStatusCode, rr.Code)
+		}
+
+		if msg := session.PopString(ctx, "flash"); msg != tt.expectedFlash {
+			t.Errorf("failed %s: expected flash %q, but got %q", tt.name, tt.expectedFlash, msg)
+		}
+
+		if msg := session.PopString(ctx, "error"); msg != tt.expectedError {
+			t.Errorf("failed %s: expected error %q, but got %q", tt.name, tt.expectedError, msg)
+		}
+	}
+}
+
+func TestRepository_ExpireWaitlistOffers(t *testing.T) {
+	expired, err := Repo.ExpireWaitlistOffers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expired != 1 {
+		t.Errorf("expected 1 expired offer, but got %d", expired)
+	}
+}
+
+func TestRepository_AdminDeleteWaitlistEntry(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/admin/delete-waitlist-entry/1/do", nil)
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	req = withURLParams(req, map[string]string{"id": "1"})
+
+	rr := httptest.NewRecorder()
+
+	handler := http.HandlerFunc(Repo.AdminDeleteWaitlistEntry)
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("AdminDeleteWaitlistEntry returned wrong response code: got %d, wanted %d", rr.Code, http.StatusSeeOther)
+	}
+}
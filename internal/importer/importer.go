@@ -0,0 +1,334 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/forms"
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/pricing"
+)
+
+//Fields are the reservation fields a CSV column can be mapped to
+var Fields = []string{"first_name", "last_name", "email", "phone", "start_date", "end_date", "room", "total_price"}
+
+//requiredFields must be mapped to a column of the file
+var requiredFields = []string{"first_name", "last_name", "email", "start_date", "end_date", "room"}
+
+//DefaultDateLayout is the date layout used when Options leave it empty
+const DefaultDateLayout = "2006-01-02"
+
+//Store is the part of the repository the importer needs
+type Store interface {
+	AllRooms() ([]models.Room, error)
+	SearchAvailabilityByDatesByRoomID(start, end time.Time, roomID int) (bool, error)
+	ImportReservations(res []models.Reservation) (bool, error)
+}
+
+//Mapping maps reservation fields to the CSV column headers holding them, unmapped fields are looked up
+//by their own name
+type Mapping map[string]string
+
+//Options control an import
+type Options struct {
+	Mapping    Mapping
+	DateLayout string
+	DryRun     bool
+}
+
+//Row is a line of the file with the reservation read from it and what is wrong with it
+type Row struct {
+	Line        int
+	Reservation models.Reservation
+	Errors      []string
+}
+
+//Report is the outcome of an import
+type Report struct {
+	Rows     []Row
+	Invalid  int
+	Imported int
+}
+
+//Valid returns true if no row has errors
+func (r Report) Valid() bool {
+	return r.Invalid == 0
+}
+
+//ParseMapping reads a mapping written as field=Column pairs separated by commas
+func ParseMapping(s string) (Mapping, error) {
+	mapping := make(Mapping)
+
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mapping %q, expected field=Column", pair)
+		}
+
+		mapping[strings.TrimSpace(field)] = strings.TrimSpace(column)
+	}
+
+	return mapping, nil
+}
+
+//column returns the header mapped to a field
+func (m Mapping) column(field string) string {
+	if c := m[field]; c != "" {
+		return c
+	}
+
+	return field
+}
+
+//Import reads reservations from CSV, validates them, checks them against each other and the existing room
+//restrictions and, unless it is a dry run, saves them all in a single transaction if every row is valid
+func Import(db Store, r io.Reader, opts Options) (Report, error) {
+	var report Report
+
+	if opts.DateLayout == "" {
+		opts.DateLayout = DefaultDateLayout
+	}
+
+	rooms, err := db.AllRooms()
+	if err != nil {
+		return report, err
+	}
+
+	report.Rows, err = parse(r, opts, rooms)
+	if err != nil {
+		return report, err
+	}
+
+	err = check(db, report.Rows)
+	if err != nil {
+		return report, err
+	}
+
+	var reservations []models.Reservation
+	for _, x := range report.Rows {
+		if len(x.Errors) > 0 {
+			report.Invalid++
+		}
+		reservations = append(reservations, x.Reservation)
+	}
+
+	if opts.DryRun || !report.Valid() || len(reservations) == 0 {
+		return report, nil
+	}
+
+	imported, err := db.ImportReservations(reservations)
+	if err != nil {
+		return report, err
+	}
+
+	if !imported {
+		return report, errors.New("rooms were booked while importing, nothing was imported")
+	}
+
+	report.Imported = len(reservations)
+
+	return report, nil
+}
+
+//parse reads and validates the rows of the file
+func parse(r io.Reader, opts Options, rooms []models.Room) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int)
+	for i, h := range header {
+		index[strings.TrimSpace(h)] = i
+	}
+
+	columns := make(map[string]int)
+	for _, f := range Fields {
+		if i, ok := index[opts.Mapping.column(f)]; ok {
+			columns[f] = i
+		}
+	}
+
+	for _, f := range requiredFields {
+		if _, ok := columns[f]; !ok {
+			return nil, fmt.Errorf("column %q for %s not found", opts.Mapping.column(f), f)
+		}
+	}
+
+	var rows []Row
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		get := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		rows = append(rows, parseRow(line, get, opts.DateLayout, rooms))
+	}
+
+	return rows, nil
+}
+
+//parseRow builds the reservation of a line and validates it
+func parseRow(line int, get func(string) string, layout string, rooms []models.Room) Row {
+	row := Row{Line: line}
+	res := models.Reservation{
+		FirstName: get("first_name"),
+		LastName:  get("last_name"),
+		Email:     get("email"),
+		Phone:     get("phone"),
+		Status:    models.ReservationConfirmed,
+		Processed: 1,
+	}
+
+	values := make(map[string][]string)
+	for _, f := range Fields {
+		values[f] = []string{get(f)}
+	}
+
+	form := forms.New(values)
+	form.Required("first_name", "last_name", "email", "start_date", "end_date", "room")
+	if form.Has("email") {
+		form.IsEmail("email")
+	}
+	for _, f := range Fields {
+		if msg := form.Errors.Get(f); msg != "" {
+			row.Errors = append(row.Errors, fmt.Sprintf("%s: %s", f, msg))
+		}
+	}
+
+	var err error
+	if s := get("start_date"); s != "" {
+		res.StartDate, err = time.Parse(layout, s)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("start_date: %q is not a date like %s", s, layout))
+		}
+	}
+
+	if s := get("end_date"); s != "" {
+		res.EndDate, err = time.Parse(layout, s)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("end_date: %q is not a date like %s", s, layout))
+		}
+	}
+
+	if !res.StartDate.IsZero() && !res.EndDate.IsZero() && !res.EndDate.After(res.StartDate) {
+		row.Errors = append(row.Errors, "end_date: departure must be after arrival")
+	}
+
+	if s := get("room"); s != "" {
+		room, ok := findRoom(rooms, s)
+		if ok {
+			res.RoomID = room.ID
+			res.Room = room
+		} else {
+			row.Errors = append(row.Errors, fmt.Sprintf("room: %q not found", s))
+		}
+	}
+
+	if s := get("total_price"); s != "" {
+		res.TotalPrice, err = parseMoney(s)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("total_price: %q is not an amount", s))
+		}
+	} else if res.RoomID > 0 {
+		res.TotalPrice = pricing.StayPrice(res.Room, res.StartDate, res.EndDate)
+	}
+
+	row.Reservation = res
+
+	return row
+}
+
+//check marks valid rows that overlap an earlier row of the file or an existing room restriction. Like room
+//restrictions, a stay blocks its departure day, so a stay arriving on the day another leaves overlaps it
+func check(db Store, rows []Row) error {
+	for i := range rows {
+		row := &rows[i]
+		if len(row.Errors) > 0 {
+			continue
+		}
+
+		res := row.Reservation
+
+		for _, other := range rows[:i] {
+			o := other.Reservation
+			if len(other.Errors) == 0 && o.RoomID == res.RoomID &&
+				!res.StartDate.After(o.EndDate) && !o.StartDate.After(res.EndDate) {
+				row.Errors = append(row.Errors, fmt.Sprintf("conflict: overlaps line %d", other.Line))
+				break
+			}
+		}
+
+		available, err := db.SearchAvailabilityByDatesByRoomID(res.StartDate, res.EndDate, res.RoomID)
+		if err != nil {
+			return err
+		}
+
+		if !available {
+			row.Errors = append(row.Errors, fmt.Sprintf("conflict: %s is not available for these dates", res.Room.RoomName))
+		}
+	}
+
+	return nil
+}
+
+//findRoom finds a room by id or by name, ignoring case
+func findRoom(rooms []models.Room, s string) (models.Room, bool) {
+	id, err := strconv.Atoi(s)
+
+	for _, x := range rooms {
+		if (err == nil && x.ID == id) || strings.EqualFold(x.RoomName, s) {
+			return x, true
+		}
+	}
+
+	return models.Room{}, false
+}
+
+//parseMoney reads an amount like 123 or 123.45 into minor units
+func parseMoney(s string) (int, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("too many decimals in %s", s)
+	}
+
+	units, err := strconv.Atoi(whole)
+	if err != nil || units < 0 {
+		return 0, fmt.Errorf("invalid amount %s", s)
+	}
+
+	cents := 0
+	if frac != "" {
+		cents, err = strconv.Atoi(frac + strings.Repeat("0", 2-len(frac)))
+		if err != nil || cents < 0 {
+			return 0, fmt.Errorf("invalid amount %s", s)
+		}
+	}
+
+	return units*100 + cents, nil
+}
@@ -0,0 +1,232 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+type fakeStore struct {
+	taken    time.Time
+	imported []models.Reservation
+	conflict bool
+}
+
+func (s *fakeStore) AllRooms() ([]models.Room, error) {
+	return []models.Room{
+		{ID: 1, RoomName: "General's Quarters", Price: 10000},
+		{ID: 2, RoomName: "Major's Suite", Price: 15000},
+	}, nil
+}
+
+func (s *fakeStore) SearchAvailabilityByDatesByRoomID(start, end time.Time, roomID int) (bool, error) {
+	if roomID == 0 {
+		return false, errors.New("no room")
+	}
+
+	return !(roomID == 2 && !start.After(s.taken) && !end.Before(s.taken)), nil
+}
+
+func (s *fakeStore) ImportReservations(res []models.Reservation) (bool, error) {
+	if s.conflict {
+		return false, nil
+	}
+
+	s.imported = res
+	return true, nil
+}
+
+func TestImport(t *testing.T) {
+	taken, _ := time.Parse("2006-01-02", "2050-01-10")
+
+	file := `Guest First,Guest Last,Mail,Arrive,Leave,Unit,Paid
+John,Smith,john@smith.com,01/01/2050,01/03/2050,1,
+Jane,Doe,jane@doe.com,01/05/2050,01/07/2050,major's suite,250.5
+`
+
+	store := &fakeStore{taken: taken}
+	opts := Options{
+		Mapping: Mapping{
+			"first_name":  "Guest First",
+			"last_name":   "Guest Last",
+			"email":       "Mail",
+			"start_date":  "Arrive",
+			"end_date":    "Leave",
+			"room":        "Unit",
+			"total_price": "Paid",
+		},
+		DateLayout: "01/02/2006",
+		DryRun:     true,
+	}
+
+	report, err := Import(store, strings.NewReader(file), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Valid() || len(report.Rows) != 2 || report.Imported != 0 || store.imported != nil {
+		t.Fatalf("expected 2 valid rows and nothing imported on dry run, but got %+v", report)
+	}
+
+	if res := report.Rows[0].Reservation; res.RoomID != 1 || res.TotalPrice != 20000 || res.Processed != 1 {
+		t.Errorf("expected room 1 priced 20000 from the room rate, but got room %d priced %d", res.RoomID, res.TotalPrice)
+	}
+
+	if res := report.Rows[1].Reservation; res.RoomID != 2 || res.TotalPrice != 25050 {
+		t.Errorf("expected room 2 priced 25050 from the file, but got room %d priced %d", res.RoomID, res.TotalPrice)
+	}
+
+	opts.DryRun = false
+	report, err = Import(store, strings.NewReader(file), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Imported != 2 || len(store.imported) != 2 {
+		t.Errorf("expected 2 imported reservations, but got %d", report.Imported)
+	}
+
+	store.imported = nil
+	store.conflict = true
+	_, err = Import(store, strings.NewReader(file), opts)
+	if err == nil || store.imported != nil {
+		t.Error("expected an error and nothing imported when rooms are booked while importing")
+	}
+}
+
+func TestImport_InvalidRows(t *testing.T) {
+	taken, _ := time.Parse("2006-01-02", "2050-01-10")
+
+	file := `first_name,last_name,email,phone,start_date,end_date,room
+John,Smith,john@smith.com,,2050-01-01,2050-01-03,1
+Jack,Smith,jack@smith.com,,2050-01-02,2050-01-04,1
+Jane,Doe,jane,,2050-01-05,2050-01-07,2
+Jim,Beam,jim@beam.com,,2050-01-09,2050-01-11,2
+,Nobody,no@body.com,,2050-13-01,2050-01-01,5
+Joe,Day,joe@day.com,,2050-01-05,2050-01-04,1
+`
+
+	store := &fakeStore{taken: taken}
+
+	report, err := Import(store, strings.NewReader(file), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Valid() || report.Invalid != 5 || store.imported != nil {
+		t.Fatalf("expected 5 invalid rows and nothing imported, but got %d invalid", report.Invalid)
+	}
+
+	var theTests = []struct {
+		line     int
+		expected string
+	}{
+		{3, "conflict: overlaps line 2"},
+		{4, "email: Invalid email address"},
+		{5, "conflict: Major's Suite is not available for these dates"},
+		{6, "first_name: This field cannot be blank"},
+		{6, "start_date: \"2050-13-01\" is not a date like 2006-01-02"},
+		{6, "room: \"5\" not found"},
+		{7, "end_date: departure must be after arrival"},
+	}
+
+	for _, tt := range theTests {
+		row := report.Rows[tt.line-2]
+		found := false
+		for _, e := range row.Errors {
+			if e == tt.expected {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("expected error %q on line %d, but got %v", tt.expected, tt.line, row.Errors)
+		}
+	}
+
+	if len(report.Rows[0].Errors) != 0 {
+		t.Errorf("expected line 2 to be valid, but got %v", report.Rows[0].Errors)
+	}
+}
+
+func TestImport_BackToBack(t *testing.T) {
+	file := `first_name,last_name,email,phone,start_date,end_date,room
+John,Smith,john@smith.com,,2050-01-01,2050-01-03,1
+Jack,Smith,jack@smith.com,,2050-01-03,2050-01-05,1
+Jane,Doe,jane@doe.com,,2050-01-03,2050-01-05,2
+`
+
+	store := &fakeStore{}
+
+	report, err := Import(store, strings.NewReader(file), Options{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Invalid != 1 {
+		t.Fatalf("expected 1 invalid row, but got %d", report.Invalid)
+	}
+
+	if errs := report.Rows[1].Errors; len(errs) != 1 || errs[0] != "conflict: overlaps line 2" {
+		t.Errorf("expected the stay arriving on the departure day in the same room to conflict, but got %v", errs)
+	}
+
+	if len(report.Rows[2].Errors) != 0 {
+		t.Errorf("expected the stay in another room to be valid, but got %v", report.Rows[2].Errors)
+	}
+}
+
+func TestImport_MissingColumn(t *testing.T) {
+	file := "first_name,last_name,email,start_date,end_date\n"
+
+	_, err := Import(&fakeStore{}, strings.NewReader(file), Options{})
+	if err == nil || !strings.Contains(err.Error(), "room") {
+		t.Errorf("expected missing room column error, but got %v", err)
+	}
+
+	_, err = Import(&fakeStore{}, strings.NewReader(""), Options{})
+	if err == nil {
+		t.Error("expected error for empty file")
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	mapping, err := ParseMapping("first_name=First Name, room = Room No,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mapping.column("first_name") != "First Name" || mapping.column("room") != "Room No" || mapping.column("email") != "email" {
+		t.Errorf("unexpected mapping %v", mapping)
+	}
+
+	_, err = ParseMapping("first_name")
+	if err == nil {
+		t.Error("expected error for a pair without column")
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	var theTests = []struct {
+		value    string
+		expected int
+		valid    bool
+	}{
+		{"123", 12300, true},
+		{"123.4", 12340, true},
+		{"123.45", 12345, true},
+		{"123.456", 0, false},
+		{"-1", 0, false},
+		{"abc", 0, false},
+	}
+
+	for _, tt := range theTests {
+		got, err := parseMoney(tt.value)
+		if (err == nil) != tt.valid || got != tt.expected {
+			t.Errorf("parseMoney(%s): expected %d valid %v, but got %d with %v", tt.value, tt.expected, tt.valid, got, err)
+		}
+	}
+}
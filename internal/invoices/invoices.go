@@ -0,0 +1,82 @@
+package invoices
+
+import (
+	"fmt"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/pricing"
+)
+
+//Lines returns the lines of an invoice for what is charged on a folio, the stay with its discount, the
+//taxes added to it and its extras first
+func Lines(f models.Folio) []models.InvoiceLine {
+	res := f.Reservation
+
+	room := res.Room.RoomName
+	if res.RatePlan != "" {
+		room += ", " + res.RatePlan
+	}
+
+	stay := fmt.Sprintf("Stay in %s, %s to %s (%d nights)", room, res.StartDate.Format("2006-01-02"),
+		res.EndDate.Format("2006-01-02"), pricing.Nights(res.StartDate, res.EndDate))
+
+	var lines []models.InvoiceLine
+	if res.Status == models.ReservationCancelled {
+		if f.StayCharge() > 0 {
+			lines = append(lines, models.InvoiceLine{Description: "Cancellation fee, " + stay, Amount: f.StayCharge()})
+		}
+	} else {
+		lines = append(lines, models.InvoiceLine{Description: stay, Amount: res.RoomPrice() + res.Discount})
+		if res.Discount > 0 {
+			lines = append(lines, models.InvoiceLine{Description: "Promo code " + res.PromoCode, Amount: -res.Discount})
+		}
+		for _, t := range res.Taxes {
+			if !t.Inclusive {
+				lines = append(lines, models.InvoiceLine{Description: t.Name, Amount: t.Amount})
+			}
+		}
+		for _, x := range res.Extras {
+			lines = append(lines, models.InvoiceLine{Description: fmt.Sprintf("%s x %d", x.Name, x.Quantity), Amount: x.Amount})
+		}
+	}
+
+	for _, e := range f.Entries {
+		if e.Kind == models.FolioCharge {
+			lines = append(lines, models.InvoiceLine{Description: e.Description, Amount: e.Amount})
+		}
+	}
+
+	return lines
+}
+
+//Taxes returns the taxes contained in what is charged on a folio, none once the reservation is cancelled
+func Taxes(f models.Folio) []models.InvoiceLine {
+	if f.Reservation.Status == models.ReservationCancelled {
+		return nil
+	}
+
+	var taxes []models.InvoiceLine
+	for _, t := range f.Reservation.Taxes {
+		taxes = append(taxes, models.InvoiceLine{Description: t.Name, Amount: t.Amount})
+	}
+
+	return taxes
+}
+
+//New returns an invoice for what is charged on a folio, to be numbered when it is issued
+func New(f models.Folio, seller, buyer models.BillingDetails) models.Invoice {
+	return models.Invoice{
+		ReservationID: f.Reservation.ID,
+		Seller:        seller,
+		Buyer:         buyer,
+		Lines:         Lines(f),
+		Taxes:         Taxes(f),
+		Total:         f.Charged(),
+		Paid:          f.Paid(),
+	}
+}
+
+//ReceiptCode returns the number printed on the receipt of a payment
+func ReceiptCode(payment models.FolioEntry) string {
+	return fmt.Sprintf("RCT-%06d", payment.ID)
+}
@@ -0,0 +1,207 @@
+package invoices
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func testFolio(status string) models.Folio {
+	return models.Folio{
+		Reservation: models.Reservation{
+			ID:              7,
+			StartDate:       time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:         time.Date(2050, 1, 3, 0, 0, 0, 0, time.UTC),
+			Room:            models.Room{RoomName: "General's Quarters"},
+			TotalPrice:      20800,
+			Status:          status,
+			CancellationFee: 5000,
+			Taxes: []models.ReservationTax{
+				{Name: "VAT 20%", Amount: 3333, Inclusive: true},
+				{Name: "Tourist levy", Amount: 800},
+			},
+		},
+		Entries: []models.FolioEntry{
+			{Kind: models.FolioPayment, Description: "Deposit", Amount: 4000, Status: models.PaymentSucceeded},
+			{Kind: models.FolioCharge, Description: "Minibar", Amount: 1500, Status: models.PaymentSucceeded},
+		},
+	}
+}
+
+func TestLines(t *testing.T) {
+	lines := Lines(testFolio(models.ReservationConfirmed))
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, but got %d", len(lines))
+	}
+
+	if lines[0].Description != "Stay in General's Quarters, 2050-01-01 to 2050-01-03 (2 nights)" || lines[0].Amount != 20000 {
+		t.Errorf("wrong stay line %v", lines[0])
+	}
+
+	if lines[1].Description != "Tourist levy" || lines[1].Amount != 800 {
+		t.Errorf("wrong tax line %v", lines[1])
+	}
+
+	if lines[2].Description != "Minibar" || lines[2].Amount != 1500 {
+		t.Errorf("wrong charge line %v", lines[2])
+	}
+
+	lines = Lines(testFolio(models.ReservationCancelled))
+	if len(lines) != 2 || lines[0].Description != "Cancellation fee, Stay in General's Quarters, 2050-01-01 to 2050-01-03 (2 nights)" || lines[0].Amount != 5000 {
+		t.Errorf("wrong cancellation fee lines %v", lines)
+	}
+
+	f := testFolio(models.ReservationCancelled)
+	f.Reservation.CancellationFee = 0
+	if lines = Lines(f); len(lines) != 1 {
+		t.Errorf("expected only the charge without a cancellation fee, but got %v", lines)
+	}
+}
+
+func TestLines_Discount(t *testing.T) {
+	f := testFolio(models.ReservationConfirmed)
+	f.Reservation.PromoCode = "SUMMER"
+	f.Reservation.Discount = 2000
+
+	lines := Lines(f)
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, but got %v", lines)
+	}
+
+	if lines[0].Amount != 22000 || lines[1].Description != "Promo code SUMMER" || lines[1].Amount != -2000 {
+		t.Errorf("wrong discounted stay lines %v", lines[:2])
+	}
+}
+
+func TestLines_RatePlan(t *testing.T) {
+	f := testFolio(models.ReservationConfirmed)
+	f.Reservation.RatePlan = "Bed & Breakfast"
+
+	lines := Lines(f)
+	if lines[0].Description != "Stay in General's Quarters, Bed & Breakfast, 2050-01-01 to 2050-01-03 (2 nights)" {
+		t.Errorf("wrong stay line %q", lines[0].Description)
+	}
+}
+
+func TestLines_Extras(t *testing.T) {
+	f := testFolio(models.ReservationConfirmed)
+	f.Reservation.TotalPrice += 3000
+	f.Reservation.Extras = []models.ReservationExtra{{ExtraID: 1, Name: "Parking", Quantity: 2, Amount: 3000}}
+
+	lines := Lines(f)
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, but got %v", lines)
+	}
+
+	if lines[0].Amount != 20000 || lines[2].Description != "Parking x 2" || lines[2].Amount != 3000 {
+		t.Errorf("wrong extra lines %v", lines)
+	}
+}
+
+func TestTaxes(t *testing.T) {
+	taxes := Taxes(testFolio(models.ReservationConfirmed))
+	if len(taxes) != 2 || taxes[0].Description != "VAT 20%" || taxes[0].Amount != 3333 || taxes[1].Amount != 800 {
+		t.Errorf("wrong taxes %v", taxes)
+	}
+
+	if taxes = Taxes(testFolio(models.ReservationCancelled)); len(taxes) != 0 {
+		t.Errorf("expected no taxes on a cancellation fee, but got %v", taxes)
+	}
+}
+
+func TestNew(t *testing.T) {
+	seller := models.BillingDetails{Name: "Fort Smythe B&B", VATNumber: "GB123"}
+	buyer := models.BillingDetails{Name: "John Smith", Company: "Acme"}
+
+	inv := New(testFolio(models.ReservationConfirmed), seller, buyer)
+
+	if inv.ReservationID != 7 || inv.Seller != seller || inv.Buyer != buyer || len(inv.Lines) != 3 || len(inv.Taxes) != 2 {
+		t.Errorf("wrong invoice details %v", inv)
+	}
+
+	if inv.Total != 22300 || inv.Net() != 18167 || inv.Paid != 4000 || inv.Due() != 18300 {
+		t.Errorf("wrong totals %d, %d, %d, %d", inv.Total, inv.Net(), inv.Paid, inv.Due())
+	}
+}
+
+func TestRender(t *testing.T) {
+	inv := New(testFolio(models.ReservationConfirmed),
+		models.BillingDetails{Name: "Fort Smythe B&B", Address: "1 Main Street\nSpringfield", VATNumber: "GB123"},
+		models.BillingDetails{Name: "John Smith", Company: "Acme (UK) Ltd", VATNumber: "GB999"},
+	)
+	inv.Number = 42
+	inv.IssuedAt = time.Date(2050, 1, 3, 10, 0, 0, 0, time.UTC)
+
+	out := Render(inv)
+
+	for _, s := range []string{
+		"Invoice number: INV-000042",
+		"Date: 2050-01-03",
+		"Reservation: 7",
+		"Springfield",
+		"VAT number: GB123",
+		`Acme \(UK\) Ltd`,
+		"VAT number: GB999",
+		"(Minibar)",
+		"(Tourist levy)",
+		"(VAT 20%)",
+		"(33.33)",
+		"(223.00)",
+		"(Total without taxes)",
+		"(181.67)",
+		"(Balance due)",
+		"(183.00)",
+	} {
+		if !bytes.Contains(out, []byte(s)) {
+			t.Errorf("invoice does not contain %s", s)
+		}
+	}
+
+	if bytes.Contains(out, []byte("Paid in full")) {
+		t.Error("invoice with a balance due says it was paid in full")
+	}
+
+	inv.Paid = inv.Total
+	if !bytes.Contains(Render(inv), []byte("Paid in full")) {
+		t.Error("paid invoice does not say so")
+	}
+}
+
+func TestRenderReceipt(t *testing.T) {
+	f := testFolio(models.ReservationConfirmed)
+	f.Reservation.FirstName = "John"
+	f.Reservation.LastName = "Smith"
+
+	payment := models.FolioEntry{
+		ID:          17,
+		Kind:        models.FolioPayment,
+		Description: "Deposit",
+		Amount:      4000,
+		Status:      models.PaymentSucceeded,
+		Provider:    "fake",
+		Reference:   "fake_1",
+		CreatedAt:   time.Date(2049, 12, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	out := RenderReceipt(models.BillingDetails{Name: "Fort Smythe B&B"}, f.Reservation, payment)
+
+	for _, s := range []string{
+		"Receipt number: RCT-000017",
+		"Date: 2049-12-01",
+		"(John Smith)",
+		"Paid online \\(fake fake_1\\)",
+		"(Total received)",
+		"(40.00)",
+	} {
+		if !bytes.Contains(out, []byte(s)) {
+			t.Errorf("receipt does not contain %s", s)
+		}
+	}
+
+	payment.Provider = models.PaymentManual
+	if !bytes.Contains(RenderReceipt(models.BillingDetails{}, f.Reservation, payment), []byte("Paid at the desk")) {
+		t.Error("receipt of a manual payment does not say it was paid at the desk")
+	}
+}
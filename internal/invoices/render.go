@@ -0,0 +1,185 @@
+package invoices
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/pdf"
+	"github.com/yalagtyarzh/leafsite/internal/pricing"
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//Page layout in points
+const (
+	left     = 50.0
+	middle   = 300.0
+	right    = pdf.A4Width - 50
+	top      = 60.0
+	bottom   = pdf.A4Height - 60
+	fontSize = 10.0
+	leading  = 14.0
+)
+
+//writer writes rows down the pages of a document, starting a new page when one is full
+type writer struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	y    float64
+}
+
+func newWriter(title string) *writer {
+	w := &writer{doc: pdf.New(title)}
+	w.page = w.doc.AddPage()
+	w.y = top
+	return w
+}
+
+//header writes the title of the document with its number, date and reservation on the right
+func (w *writer) header(title string, meta ...string) {
+	w.page.Text(left, w.y+10, 22, true, title)
+
+	for i, s := range meta {
+		w.page.TextRight(right, w.y+float64(i)*leading, fontSize, false, s)
+	}
+
+	w.y += float64(len(meta)+2) * leading
+}
+
+//party writes billing details under a heading at x, returns where the block ends
+func (w *writer) party(x float64, heading string, d models.BillingDetails) float64 {
+	y := w.y
+	w.page.Text(x, y, 8, true, strings.ToUpper(heading))
+	y += leading
+
+	var lines []string
+	for _, s := range []string{d.Company, d.Name} {
+		if s != "" {
+			lines = append(lines, s)
+		}
+	}
+	for _, s := range strings.Split(d.Address, "\n") {
+		if s = strings.TrimSpace(s); s != "" {
+			lines = append(lines, s)
+		}
+	}
+	if d.VATNumber != "" {
+		lines = append(lines, "VAT number: "+d.VATNumber)
+	}
+
+	for i, s := range lines {
+		w.page.Text(x, y, fontSize, i == 0, pdf.Truncate(s, middle-left-20, fontSize, i == 0))
+		y += leading
+	}
+
+	return y
+}
+
+//parties writes the details of both sides next to each other
+func (w *writer) parties(leftHeading string, l models.BillingDetails, rightHeading string, r models.BillingDetails) {
+	ly := w.party(left, leftHeading, l)
+	ry := w.party(middle, rightHeading, r)
+
+	if ry > ly {
+		ly = ry
+	}
+	w.y = ly + leading
+}
+
+//row writes a description with an amount aligned to the right
+func (w *writer) row(description, amount string, bold bool) {
+	if w.y+leading > bottom {
+		w.page = w.doc.AddPage()
+		w.y = top
+	}
+
+	w.page.Text(left, w.y, fontSize, bold, pdf.Truncate(description, right-left-100, fontSize, bold))
+	w.page.TextRight(right, w.y, fontSize, bold, amount)
+	w.y += leading
+}
+
+//rule draws a line under the last row
+func (w *writer) rule() {
+	w.page.Line(left, w.y-10, right, w.y-10)
+	w.y += 4
+}
+
+//text writes a line of text
+func (w *writer) text(s string) {
+	w.row(s, "", false)
+}
+
+//Render returns an invoice as a PDF document
+func Render(inv models.Invoice) []byte {
+	w := newWriter("Invoice " + inv.Code())
+
+	w.header("INVOICE",
+		"Invoice number: "+inv.Code(),
+		"Date: "+inv.IssuedAt.Format("2006-01-02"),
+		fmt.Sprintf("Reservation: %d", inv.ReservationID),
+	)
+	w.parties("From", inv.Seller, "Bill to", inv.Buyer)
+
+	w.row("Description", "Amount", true)
+	w.rule()
+	for _, l := range inv.Lines {
+		w.row(l.Description, render.Money(l.Amount), false)
+	}
+	w.rule()
+
+	w.row("Total", render.Money(inv.Total), true)
+
+	if len(inv.Taxes) > 0 {
+		w.y += leading
+		w.row("Taxes included in the total", "", true)
+		for _, t := range inv.Taxes {
+			w.row(t.Description, render.Money(t.Amount), false)
+		}
+		w.row("Total without taxes", render.Money(inv.Net()), false)
+		w.y += leading
+	}
+
+	if inv.Paid > 0 {
+		w.row("Paid", "-"+render.Money(inv.Paid), false)
+		w.row("Balance due", render.Money(inv.Due()), true)
+	}
+
+	if inv.Total > 0 && inv.Due() <= 0 {
+		w.y += leading
+		w.text("Paid in full, thank you.")
+	}
+
+	return w.doc.Bytes()
+}
+
+//RenderReceipt returns a receipt for a succeeded payment of a reservation as a PDF document
+func RenderReceipt(seller models.BillingDetails, res models.Reservation, payment models.FolioEntry) []byte {
+	code := ReceiptCode(payment)
+	w := newWriter("Receipt " + code)
+
+	w.header("RECEIPT",
+		"Receipt number: "+code,
+		"Date: "+payment.CreatedAt.Format("2006-01-02"),
+		fmt.Sprintf("Reservation: %d", res.ID),
+	)
+	w.parties("From", seller, "Received from", models.BillingDetails{
+		Name:    fmt.Sprintf("%s %s", res.FirstName, res.LastName),
+		Address: res.Email,
+	})
+
+	method := "Paid at the desk"
+	if payment.Provider != models.PaymentManual {
+		method = fmt.Sprintf("Paid online (%s %s)", payment.Provider, payment.Reference)
+	}
+
+	w.row("Description", "Amount", true)
+	w.rule()
+	w.row(payment.Description, render.Money(payment.Amount), false)
+	w.text(method)
+	w.text(fmt.Sprintf("For the stay in %s, %s to %s (%d nights)", res.Room.RoomName, res.StartDate.Format("2006-01-02"),
+		res.EndDate.Format("2006-01-02"), pricing.Nights(res.StartDate, res.EndDate)))
+	w.rule()
+	w.row("Total received", render.Money(payment.Amount), true)
+
+	return w.doc.Bytes()
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -18,12 +19,52 @@ type User struct {
 
 //Rooms is the room model
 type Room struct {
-	ID        int
-	RoomName  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID                   int
+	RoomName             string
+	Price                int
+	CancellationPolicyID int
+	HousekeepingStatus   string
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+//Housekeeping statuses of a room, an out of order room can't be booked
+const (
+	RoomClean      = "clean"
+	RoomDirty      = "dirty"
+	RoomInspected  = "inspected"
+	RoomOutOfOrder = "out_of_order"
+)
+
+//HousekeepingStatuses lists the housekeeping statuses in the order they are offered
+var HousekeepingStatuses = []string{RoomClean, RoomDirty, RoomInspected, RoomOutOfOrder}
+
+//HousekeepingRoom is a room on the housekeeping board of a day. Departing is true while a guest due to
+//leave that day hasn't checked out, Arriving and Occupied if a stay starts or goes on that night
+type HousekeepingRoom struct {
+	Room      Room
+	Departing bool
+	Arriving  bool
+	Occupied  bool
+}
+
+//ToClean returns true if the room needs cleaning on the day of the board
+func (h HousekeepingRoom) ToClean() bool {
+	if h.Room.HousekeepingStatus == RoomOutOfOrder {
+		return false
+	}
+
+	return h.Room.HousekeepingStatus == RoomDirty || h.Departing
 }
 
+//Restriction types, matching the seeded restrictions table
+const (
+	RestrictionReservation = 1
+	RestrictionOwnerBlock  = 2
+	RestrictionHold        = 3
+	RestrictionMaintenance = 4
+)
+
 //Restriction is the reservation model
 type Restriction struct {
 	ID              int
@@ -32,20 +73,108 @@ type Restriction struct {
 	UpdatedAt       time.Time
 }
 
+//Reservation statuses
+const (
+	ReservationConfirmed = "confirmed"
+	ReservationCancelled = "cancelled"
+	ReservationNoShow    = "no_show"
+)
+
 //Reservation is the reservation model
 type Reservation struct {
-	ID        int
-	FirstName string
-	LastName  string
-	Email     string
-	Phone     string
-	StartDate time.Time
-	EndDate   time.Time
-	RoomID    int
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Room      Room
-	Processed int
+	ID              int
+	FirstName       string
+	LastName        string
+	Email           string
+	Phone           string
+	StartDate       time.Time
+	EndDate         time.Time
+	RoomID          int
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Room            Room
+	Processed       int
+	TotalPrice      int
+	Status          string
+	CancelledAt     time.Time
+	CancellationFee int
+	Tags            []string
+	GuestID         int
+	CheckedInAt     time.Time
+	CheckedOutAt    time.Time
+	Guests          int
+	Taxes           []ReservationTax
+	PromoCodeID     int
+	PromoCode       string
+	Discount        int
+	Adjustments     []PriceAdjustment
+	RatePlanID      int
+	RatePlan        string
+	Extras          []ReservationExtra
+	WaitlistEntryID int
+}
+
+//AddedTaxes returns the taxes charged on top of the room price, which are part of the total
+func (r Reservation) AddedTaxes() int {
+	var total int
+	for _, t := range r.Taxes {
+		if !t.Inclusive {
+			total += t.Amount
+		}
+	}
+
+	return total
+}
+
+//ExtrasTotal returns what the extras booked with the reservation cost, which is part of the total
+func (r Reservation) ExtrasTotal() int {
+	var total int
+	for _, e := range r.Extras {
+		total += e.Amount
+	}
+
+	return total
+}
+
+//RoomPrice returns the total without the taxes charged on top of it and the extras
+func (r Reservation) RoomPrice() int {
+	return r.TotalPrice - r.AddedTaxes() - r.ExtrasTotal()
+}
+
+//ReservationTax is a tax charged on a reservation. Inclusive taxes are part of the room price, the others
+//are added on top of it
+type ReservationTax struct {
+	TaxRuleID int
+	Name      string
+	Amount    int
+	Inclusive bool
+}
+
+//Guest is a person who made one or more reservations, with figures over all of them. Stays doesn't count
+//cancelled reservations, LifetimeValue is what was paid including cancellation fees
+type Guest struct {
+	ID            int
+	FirstName     string
+	LastName      string
+	Email         string
+	Phone         string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Stays         int
+	Nights        int
+	LifetimeValue int
+	LastStay      time.Time
+}
+
+//ReservationNote is an internal note left on a reservation by an admin user
+type ReservationNote struct {
+	ID            int
+	ReservationID int
+	UserID        int
+	Body          string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	User          User
 }
 
 //RoomRestriction is the room restriction model
@@ -56,6 +185,7 @@ type RoomRestriction struct {
 	RoomID        int
 	ReservationID int
 	RestrictionID int
+	ExpiresAt     time.Time
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	Room          Room
@@ -63,11 +193,541 @@ type RoomRestriction struct {
 	Restriction   Restriction
 }
 
+//Cancellation fee types
+const (
+	CancellationFeePercent    = "percent"
+	CancellationFeeFirstNight = "first_night"
+)
+
+//Folio entry kinds
+const (
+	FolioCharge  = "charge"
+	FolioPayment = "payment"
+	FolioRefund  = "refund"
+)
+
+//Payment statuses, used for refunds as well. Only succeeded payments and refunds count towards what was paid
+const (
+	PaymentPending   = "pending"
+	PaymentSucceeded = "succeeded"
+	PaymentFailed    = "failed"
+)
+
+//PaymentManual is the provider of payments taken by staff outside of the payment provider
+const PaymentManual = "manual"
+
+//PaymentVoucher is the provider of payments made with gift vouchers, their reference is the voucher code
+const PaymentVoucher = "voucher"
+
+//FolioEntry is a charge, payment or refund on the account of a reservation. Payments and refunds through
+//the payment provider carry its reference and start as pending until the provider confirms them, a refund
+//has the ID of the payment it gives back in PaymentID
+type FolioEntry struct {
+	ID            int
+	ReservationID int
+	PaymentID     int
+	Kind          string
+	Description   string
+	Amount        int
+	Status        string
+	Provider      string
+	Reference     string
+	CheckoutURL   string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+//Folio is the account of a reservation, the stay itself plus the charges and payments entered on it
+type Folio struct {
+	Reservation Reservation
+	Entries     []FolioEntry
+}
+
+//StayCharge returns what is charged for the stay, the cancellation fee once the reservation is cancelled
+func (f Folio) StayCharge() int {
+	if f.Reservation.Status == ReservationCancelled {
+		return f.Reservation.CancellationFee
+	}
+
+	return f.Reservation.TotalPrice
+}
+
+//Charged returns the stay charge plus all other charges
+func (f Folio) Charged() int {
+	total := f.StayCharge()
+	for _, e := range f.Entries {
+		if e.Kind == FolioCharge {
+			total += e.Amount
+		}
+	}
+
+	return total
+}
+
+//Paid returns the sum of the succeeded payments less the succeeded refunds
+func (f Folio) Paid() int {
+	var total int
+	for _, e := range f.Entries {
+		if e.Kind == FolioPayment && e.Status == PaymentSucceeded {
+			total += e.Amount
+		}
+	}
+
+	return total - f.Refunded()
+}
+
+//Refunded returns the sum of the succeeded refunds
+func (f Folio) Refunded() int {
+	var total int
+	for _, e := range f.Entries {
+		if e.Kind == FolioRefund && e.Status == PaymentSucceeded {
+			total += e.Amount
+		}
+	}
+
+	return total
+}
+
+//Refundable returns how much of a succeeded payment can still be refunded, refunds that are pending count
+//as given back already
+func (f Folio) Refundable(paymentID int) int {
+	var total int
+	for _, e := range f.Entries {
+		switch {
+		case e.ID == paymentID && e.Kind == FolioPayment && e.Status == PaymentSucceeded:
+			total += e.Amount
+		case e.PaymentID == paymentID && e.Kind == FolioRefund && e.Status != PaymentFailed:
+			total -= e.Amount
+		}
+	}
+
+	if total < 0 {
+		return 0
+	}
+
+	return total
+}
+
+//Balance returns what is still owed, negative if the guest paid too much
+func (f Folio) Balance() int {
+	return f.Charged() - f.Paid()
+}
+
+//BillingDetails are who an invoice is issued by or to
+type BillingDetails struct {
+	Name      string
+	Company   string
+	Address   string
+	VATNumber string
+}
+
+//InvoiceLine is a line of an invoice, the amount includes VAT
+type InvoiceLine struct {
+	Description string
+	Amount      int
+}
+
+//Invoice is a numbered invoice for the charges of a reservation. Numbers run without gaps in the order
+//invoices are issued. Its lines, totals and the details of both parties are copied when it is issued, so
+//that later changes to the folio don't alter it. Taxes itemizes the taxes contained in the total
+type Invoice struct {
+	ID            int
+	Number        int
+	ReservationID int
+	Seller        BillingDetails
+	Buyer         BillingDetails
+	Lines         []InvoiceLine
+	Taxes         []InvoiceLine
+	Total         int
+	Paid          int
+	IssuedAt      time.Time
+}
+
+//Code returns the number printed on the invoice
+func (i Invoice) Code() string {
+	return fmt.Sprintf("INV-%06d", i.Number)
+}
+
+//Net returns the total without taxes
+func (i Invoice) Net() int {
+	net := i.Total
+	for _, t := range i.Taxes {
+		net -= t.Amount
+	}
+
+	return net
+}
+
+//Due returns what was still owed when the invoice was issued
+func (i Invoice) Due() int {
+	return i.Total - i.Paid
+}
+
+//Maintenance ticket priorities
+const (
+	MaintenanceLow    = "low"
+	MaintenanceNormal = "normal"
+	MaintenanceHigh   = "high"
+	MaintenanceUrgent = "urgent"
+)
+
+//MaintenancePriorities lists the maintenance ticket priorities in the order they are offered
+var MaintenancePriorities = []string{MaintenanceLow, MaintenanceNormal, MaintenanceHigh, MaintenanceUrgent}
+
+//Maintenance ticket statuses, closing a ticket releases the block it placed on its room
+const (
+	MaintenanceOpen       = "open"
+	MaintenanceInProgress = "in_progress"
+	MaintenanceClosed     = "closed"
+)
+
+//MaintenanceStatuses lists the maintenance ticket statuses in the order they are offered
+var MaintenanceStatuses = []string{MaintenanceOpen, MaintenanceInProgress, MaintenanceClosed}
+
+//MaintenanceTicket is an issue with a room. While the ticket is open and has offline dates the room is
+//blocked from OfflineStart through OfflineEnd, both inclusive, by the room restriction RoomRestrictionID
+type MaintenanceTicket struct {
+	ID                int
+	RoomID            int
+	Title             string
+	Description       string
+	Priority          string
+	Status            string
+	AssigneeID        int
+	OfflineStart      time.Time
+	OfflineEnd        time.Time
+	RoomRestrictionID int
+	ClosedAt          time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Room              Room
+	Assignee          User
+}
+
+//Offline returns true if the ticket takes its room offline
+func (t MaintenanceTicket) Offline() bool {
+	return !t.OfflineStart.IsZero() && !t.OfflineEnd.IsZero()
+}
+
+//Tax rule kinds
+const (
+	TaxPercent       = "percent"
+	TaxPerNight      = "per_night"
+	TaxPerGuest      = "per_guest"
+	TaxPerGuestNight = "per_guest_night"
+)
+
+//TaxKinds lists the tax rule kinds in the order they are offered
+var TaxKinds = []string{TaxPercent, TaxPerNight, TaxPerGuest, TaxPerGuestNight}
+
+//TaxRule is a tax charged on stays, like VAT or a tourist levy. Rate is in hundredths of a percent for
+//percentage taxes and in minor units for the others. Only percentage taxes can be included in room prices,
+//the others are always added on top. A rule applies to the nights from ValidFrom to ValidTo, zero dates
+//leave the range open, for no more than MaxNights nights of a stay when set, and never to reservations
+//tagged with ExemptTag
+type TaxRule struct {
+	ID        int
+	Name      string
+	Kind      string
+	Rate      int
+	Inclusive bool
+	ValidFrom time.Time
+	ValidTo   time.Time
+	MaxNights int
+	ExemptTag string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+//Price rule kinds
+const (
+	PriceLengthOfStay = "length_of_stay"
+	PriceEarlyBird    = "early_bird"
+	PriceLastMinute   = "last_minute"
+	PriceOccupancy    = "occupancy"
+)
+
+//PriceRuleKinds lists the price rule kinds in the order they are offered
+var PriceRuleKinds = []string{PriceLengthOfStay, PriceEarlyBird, PriceLastMinute, PriceOccupancy}
+
+//PriceRule adjusts room prices by Adjustment, in hundredths of a percent, negative for a discount. Length of
+//stay rules apply to stays of at least Threshold nights, early bird rules to stays booked at least Threshold
+//days before arrival, last minute rules to stays booked at most Threshold days before arrival and occupancy
+//rules to the nights when at least Threshold percent of the rooms are sold
+type PriceRule struct {
+	ID         int
+	Name       string
+	Kind       string
+	Threshold  int
+	Adjustment int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+//PriceAdjustment is what a price rule added to the price of a stay, negative when it took something off
+type PriceAdjustment struct {
+	PriceRuleID int
+	Name        string
+	Amount      int
+}
+
+//RatePlan is one of the ways a room is sold, such as non-refundable or bed & breakfast, at its own price per
+//night and with what it includes. A zero CancellationPolicyID keeps the cancellation policy of the room
+type RatePlan struct {
+	ID                   int
+	RoomID               int
+	Room                 Room
+	Name                 string
+	Price                int
+	CancellationPolicyID int
+	Inclusions           string
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+//ApplyTo returns the room as sold under the plan, with the price and cancellation policy of the plan
+func (p RatePlan) ApplyTo(room Room) Room {
+	room.Price = p.Price
+	if p.CancellationPolicyID > 0 {
+		room.CancellationPolicyID = p.CancellationPolicyID
+	}
+
+	return room
+}
+
+//Extra pricing kinds
+const (
+	ExtraPerStay       = "per_stay"
+	ExtraPerNight      = "per_night"
+	ExtraPerGuest      = "per_guest"
+	ExtraPerGuestNight = "per_guest_night"
+)
+
+//ExtraKinds lists the extra pricing kinds in the order they are offered
+var ExtraKinds = []string{ExtraPerStay, ExtraPerNight, ExtraPerGuest, ExtraPerGuestNight}
+
+//Extra is an add-on booked with a stay, such as breakfast or parking, at a price per unit charged as its
+//Kind says. Inventory is how many units can be booked for the same night, zero for no limit
+type Extra struct {
+	ID          int
+	Name        string
+	Description string
+	Kind        string
+	Price       int
+	Inventory   int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+//ReservationExtra is an extra booked with a reservation, Amount is what its units cost for the whole stay
+type ReservationExtra struct {
+	ID       int
+	ExtraID  int
+	Name     string
+	Quantity int
+	Amount   int
+}
+
+//GiftVoucher is a prepaid code sold to be spent on stays. Balance is the Amount it was sold for less what
+//was redeemed from it. It can be redeemed until the end of the day it expires, a zero ExpiresAt never expires
+type GiftVoucher struct {
+	ID          int
+	Code        string
+	Amount      int
+	Balance     int
+	Purchaser   string
+	Recipient   string
+	ExpiresAt   time.Time
+	Redemptions []VoucherRedemption
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+//VoucherRedemption is an entry of the ledger of a gift voucher, a payment it made on the folio of a
+//reservation. Refunds of the payment put the amount back on the voucher as redemptions of a negative amount
+type VoucherRedemption struct {
+	ID            int
+	GiftVoucherID int
+	ReservationID int
+	FolioEntryID  int
+	Amount        int
+	CreatedAt     time.Time
+}
+
+//ExchangeRate is how much of a currency guests can see prices in one unit of the base currency buys, in
+//millionths. Charges are always made in the base currency
+type ExchangeRate struct {
+	ID        int
+	Currency  string
+	Rate      int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+//Promo code discount kinds
+const (
+	PromoPercent = "percent"
+	PromoFixed   = "fixed"
+)
+
+//PromoKinds lists the promo code discount kinds in the order they are offered
+var PromoKinds = []string{PromoPercent, PromoFixed}
+
+//PromoCode is a code guests enter when booking to get a discount off the room price, before taxes. Amount is
+//in hundredths of a percent for percentage discounts and in minor units for fixed ones. The code can be
+//redeemed from ValidFrom to ValidTo, zero dates leave the window open, for stays of at least MinNights in
+//one of RoomIDs, any room when empty, and no more than MaxUses times when set. Uses counts redemptions
+type PromoCode struct {
+	ID          int
+	Code        string
+	Description string
+	Kind        string
+	Amount      int
+	ValidFrom   time.Time
+	ValidTo     time.Time
+	MinNights   int
+	RoomIDs     []int
+	MaxUses     int
+	Uses        int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+//HasRoom returns true if a promo code can be used for a room
+func (p PromoCode) HasRoom(roomID int) bool {
+	if len(p.RoomIDs) == 0 {
+		return true
+	}
+
+	for _, id := range p.RoomIDs {
+		if id == roomID {
+			return true
+		}
+	}
+
+	return false
+}
+
+//PromoCodeUsage holds how a promo code was used by the reservations made over a period. Discount and
+//Revenue leave out cancelled reservations
+type PromoCodeUsage struct {
+	PromoCode     PromoCode
+	Reservations  int
+	Cancellations int
+	Discount      int
+	Revenue       int
+}
+
+//CancellationPolicy is the cancellation policy model
+type CancellationPolicy struct {
+	ID             int
+	Name           string
+	FreeDaysBefore int
+	FeeType        string
+	FeePercent     int
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+//NightAvailability is the availability of a room for a single night
+type NightAvailability struct {
+	Date      time.Time
+	Available bool
+	Price     int
+}
+
+//Waitlist entry statuses
+const (
+	WaitlistWaiting  = "waiting"
+	WaitlistNotified = "notified"
+	WaitlistBooked   = "booked"
+	WaitlistDeclined = "declined"
+	WaitlistExpired  = "expired"
+)
+
+//WaitlistEntry is a guest waiting for a room to become available, RoomID is 0 for any room. A notified guest
+//has been offered OfferedRoomID until OfferExpiresAt
+type WaitlistEntry struct {
+	ID             int
+	FirstName      string
+	LastName       string
+	Email          string
+	RoomID         int
+	StartDate      time.Time
+	EndDate        time.Time
+	Status         string
+	NotifiedAt     time.Time
+	OfferedRoomID  int
+	OfferExpiresAt time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Room           Room
+}
+
+//Processed values of ReservationFilter
+const (
+	ProcessedAny = iota
+	ProcessedNew
+	ProcessedDone
+)
+
+//ReservationFilter narrows down a list of reservations to stays overlapping Start up to End, a room, a
+//status, whether they have been processed, a text found in the name, email or phone of the guest and a tag.
+//Zero fields match everything
+type ReservationFilter struct {
+	Start     time.Time
+	End       time.Time
+	RoomID    int
+	Status    string
+	Processed int
+	Search    string
+	Tag       string
+}
+
+//Page selects a page of a list sorted by a column, Number starts at 1
+type Page struct {
+	Number int
+	Size   int
+	Sort   string
+	Desc   bool
+}
+
+//GuestContact holds the latest contact details a guest left, with the number of reservations made
+type GuestContact struct {
+	FirstName    string
+	LastName     string
+	Email        string
+	Phone        string
+	Reservations int
+	LastStay     time.Time
+}
+
+//DashboardCounts holds the front desk figures of a day
+type DashboardCounts struct {
+	Arrivals        int
+	Departures      int
+	InHouse         int
+	NewReservations int
+}
+
+//DailyCount is a count for a single day
+type DailyCount struct {
+	Date  time.Time
+	Count int
+}
+
 //MailData holds an email message
 type MailData struct {
-	To       string
-	From     string
-	Subject  string
-	Content  string
-	Template string
+	To          string
+	From        string
+	Subject     string
+	Content     string
+	Template    string
+	Attachments []MailAttachment
+}
+
+//MailAttachment is a file attached to an email
+type MailAttachment struct {
+	Name string
+	Data []byte
 }
@@ -0,0 +1,32 @@
+package payments
+
+import (
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/pricing"
+)
+
+//DepositRule decides how much of a reservation is paid when it is booked. Percent of the total is asked
+//as a deposit, unless the guest arrives within FullWithinDays days and pays everything up front
+type DepositRule struct {
+	Percent        int
+	FullWithinDays int
+}
+
+//Amount returns the deposit due for a reservation booked at a moment
+func (d DepositRule) Amount(res models.Reservation, at time.Time) int {
+	if d.FullWithinDays > 0 && pricing.Nights(at, res.StartDate) < d.FullWithinDays {
+		return res.TotalPrice
+	}
+
+	if d.Percent <= 0 {
+		return 0
+	}
+
+	if d.Percent >= 100 {
+		return res.TotalPrice
+	}
+
+	return res.TotalPrice * d.Percent / 100
+}
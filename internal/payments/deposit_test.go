@@ -0,0 +1,33 @@
+package payments
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestDepositRule_Amount(t *testing.T) {
+	booked := time.Date(2030, 1, 1, 15, 0, 0, 0, time.UTC)
+
+	var theTests = []struct {
+		name     string
+		rule     DepositRule
+		arrival  time.Time
+		expected int
+	}{
+		{"no-deposit", DepositRule{}, booked.AddDate(0, 1, 0), 0},
+		{"percent", DepositRule{Percent: 30}, booked.AddDate(0, 1, 0), 3000},
+		{"over-hundred-percent", DepositRule{Percent: 150}, booked.AddDate(0, 1, 0), 10000},
+		{"far-ahead", DepositRule{Percent: 30, FullWithinDays: 7}, booked.AddDate(0, 0, 7), 3000},
+		{"last-minute", DepositRule{Percent: 30, FullWithinDays: 7}, booked.AddDate(0, 0, 6), 10000},
+		{"last-minute-without-deposit", DepositRule{FullWithinDays: 7}, booked, 10000},
+	}
+
+	for _, tt := range theTests {
+		res := models.Reservation{StartDate: tt.arrival, TotalPrice: 10000}
+		if got := tt.rule.Amount(res, booked); got != tt.expected {
+			t.Errorf("failed %s: expected %d, but got %d", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package payments
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+//FakeSignatureHeader is the header the fake provider signs its webhooks in
+const FakeSignatureHeader = "Fake-Signature"
+
+//Fake is an in-process provider for development and tests. The guest "pays" on a checkout page of the
+//site itself, which confirms the payment through a signed webhook like a real provider would
+type Fake struct {
+	Secret  string
+	BaseURL string
+
+	mu       sync.Mutex
+	payments map[string]Payment
+	refunded map[string]int
+}
+
+//fakeEvent is the webhook payload of the fake provider
+type fakeEvent struct {
+	Reference string `json:"reference"`
+	Status    string `json:"status"`
+}
+
+//NewFake creates a fake provider signing webhooks with the secret and linking to checkout pages on baseURL
+func NewFake(secret, baseURL string) *Fake {
+	return &Fake{
+		Secret:   secret,
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		payments: make(map[string]Payment),
+		refunded: make(map[string]int),
+	}
+}
+
+//Name identifies the provider on the payments it took
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+//CreatePayment starts a payment and returns it with the reference and the link where the guest pays
+func (f *Fake) CreatePayment(p Payment) (Payment, error) {
+	ref, err := fakeReference()
+	if err != nil {
+		return p, err
+	}
+
+	p.Reference = ref
+	p.Status = models.PaymentPending
+	p.CheckoutURL = fmt.Sprintf("%s/payments/fake/%s", f.BaseURL, p.Reference)
+
+	f.mu.Lock()
+	f.payments[p.Reference] = p
+	f.mu.Unlock()
+
+	return p, nil
+}
+
+//Refund gives back some or all of a succeeded payment at once. Payments taken before a restart are
+//forgotten, their refunds are accepted without checking the amount
+func (f *Fake) Refund(r Refund) (Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, known := f.payments[r.PaymentReference]
+	if known && p.Status != models.PaymentSucceeded {
+		return r, ErrUnknownPayment
+	}
+
+	if r.Amount <= 0 || known && f.refunded[p.Reference]+r.Amount > p.Amount {
+		return r, ErrRefundTooLarge
+	}
+
+	ref, err := fakeReference()
+	if err != nil {
+		return r, err
+	}
+
+	f.refunded[r.PaymentReference] += r.Amount
+
+	r.Reference = ref
+	r.Status = models.PaymentSucceeded
+
+	return r, nil
+}
+
+//fakeReference returns a new random reference
+func fakeReference() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "fake_" + hex.EncodeToString(b), nil
+}
+
+//Payment returns a payment started with the provider
+func (f *Fake) Payment(reference string) (Payment, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.payments[reference]
+	return p, ok
+}
+
+//Complete settles a pending payment and returns the signed webhook reporting it
+func (f *Fake) Complete(reference string, succeeded bool) ([]byte, http.Header, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.payments[reference]
+	if !ok {
+		return nil, nil, ErrUnknownPayment
+	}
+
+	if p.Status == models.PaymentPending {
+		p.Status = models.PaymentFailed
+		if succeeded {
+			p.Status = models.PaymentSucceeded
+		}
+		f.payments[reference] = p
+	}
+
+	payload, err := json.Marshal(fakeEvent{Reference: p.Reference, Status: p.Status})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := make(http.Header)
+	header.Set(FakeSignatureHeader, Sign(f.Secret, payload, time.Now()))
+
+	return payload, header, nil
+}
+
+//ParseWebhook verifies the signature of a webhook call and returns the event it reports
+func (f *Fake) ParseWebhook(payload []byte, header http.Header) (Event, error) {
+	err := VerifySignature(f.Secret, payload, header.Get(FakeSignatureHeader), time.Now())
+	if err != nil {
+		return Event{}, err
+	}
+
+	var e fakeEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return Event{}, err
+	}
+
+	if e.Status != models.PaymentSucceeded && e.Status != models.PaymentFailed {
+		return Event{}, fmt.Errorf("unknown payment status %q", e.Status)
+	}
+
+	return Event{Reference: e.Reference, Status: e.Status}, nil
+}
@@ -0,0 +1,93 @@
+package payments
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestFake(t *testing.T) {
+	f := NewFake("secret", "http://localhost:8080/")
+
+	p, err := f.CreatePayment(Payment{ReservationID: 1, Amount: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Status != models.PaymentPending || !strings.HasPrefix(p.CheckoutURL, "http://localhost:8080/payments/fake/fake_") {
+		t.Errorf("unexpected new payment %+v", p)
+	}
+
+	payload, header, err := f.Complete(p.Reference, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := f.ParseWebhook(payload, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Reference != p.Reference || e.Status != models.PaymentSucceeded {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	//a settled payment stays settled
+	payload, header, _ = f.Complete(p.Reference, false)
+	if e, _ = f.ParseWebhook(payload, header); e.Status != models.PaymentSucceeded {
+		t.Errorf("settled payment changed to %s", e.Status)
+	}
+
+	if _, err := NewFake("other", "").ParseWebhook(payload, header); err != ErrInvalidSignature {
+		t.Errorf("expected %v for another secret, but got %v", ErrInvalidSignature, err)
+	}
+
+	if _, _, err := f.Complete("fake_unknown", true); err != ErrUnknownPayment {
+		t.Errorf("expected %v for an unknown payment, but got %v", ErrUnknownPayment, err)
+	}
+}
+
+func TestFake_Refund(t *testing.T) {
+	f := NewFake("secret", "")
+
+	p, _ := f.CreatePayment(Payment{ReservationID: 1, Amount: 5000})
+
+	if _, err := f.Refund(Refund{PaymentReference: p.Reference, Amount: 1000}); err != ErrUnknownPayment {
+		t.Errorf("expected %v refunding a pending payment, but got %v", ErrUnknownPayment, err)
+	}
+
+	f.Complete(p.Reference, true)
+
+	var theTests = []struct {
+		name     string
+		amount   int
+		expected error
+	}{
+		{"partial", 3000, nil},
+		{"too-large", 2500, ErrRefundTooLarge},
+		{"rest", 2000, nil},
+		{"nothing-left", 1, ErrRefundTooLarge},
+		{"zero", 0, ErrRefundTooLarge},
+	}
+
+	for _, tt := range theTests {
+		r, err := f.Refund(Refund{PaymentReference: p.Reference, Amount: tt.amount})
+		if err != tt.expected {
+			t.Errorf("failed %s: expected %v, but got %v", tt.name, tt.expected, err)
+		}
+
+		if err == nil && (r.Reference == "" || r.Status != models.PaymentSucceeded) {
+			t.Errorf("failed %s: unexpected refund %+v", tt.name, r)
+		}
+	}
+}
+
+func TestFake_RefundForgottenPayment(t *testing.T) {
+	f := NewFake("secret", "")
+
+	r, err := f.Refund(Refund{PaymentReference: "fake_before_restart", Amount: 1000})
+	if err != nil || r.Status != models.PaymentSucceeded {
+		t.Errorf("refund of a forgotten payment returned %+v, %v", r, err)
+	}
+}
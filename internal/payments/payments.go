@@ -0,0 +1,57 @@
+package payments
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	//ErrInvalidSignature is returned for webhooks that were not signed by the provider or were tampered with
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	//ErrUnknownPayment is returned for references the provider doesn't know
+	ErrUnknownPayment = errors.New("unknown payment")
+	//ErrRefundTooLarge is returned for refunds of more than is left of a payment
+	ErrRefundTooLarge = errors.New("refund is larger than what is left of the payment")
+)
+
+//Payment is a payment asked of a guest. Amount is in minor currency units, Reference, Status and
+//CheckoutURL are filled in by the provider
+type Payment struct {
+	ReservationID int
+	Amount        int
+	Description   string
+	Email         string
+	Reference     string
+	Status        string
+	CheckoutURL   string
+}
+
+//Refund gives back some or all of a succeeded payment. Amount is in minor currency units, Reference and
+//Status are filled in by the provider
+type Refund struct {
+	PaymentReference string
+	Amount           int
+	Reason           string
+	Reference        string
+	Status           string
+}
+
+//Event is a change of the status of a payment or refund reported by the provider
+type Event struct {
+	Reference string
+	Status    string
+}
+
+//Provider takes payments from guests and refunds them. Payments start as pending and the provider confirms
+//them asynchronously by calling the webhook
+type Provider interface {
+	//Name identifies the provider on the payments it took
+	Name() string
+	//CreatePayment starts a payment and returns it with the reference and the link where the guest pays
+	CreatePayment(p Payment) (Payment, error)
+	//Refund starts a refund of a payment and returns it with the reference, refunds may be pending until
+	//the provider confirms them like payments
+	Refund(r Refund) (Refund, error)
+	//ParseWebhook verifies the signature of a webhook call and returns the event it reports
+	ParseWebhook(payload []byte, header http.Header) (Event, error)
+}
@@ -0,0 +1,63 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//SignatureTolerance is how old a signed webhook can be, so that a captured call can't be replayed later
+const SignatureTolerance = 5 * time.Minute
+
+//Sign returns the signature header value of a webhook payload sent at a moment, the time followed by an
+//HMAC-SHA256 of the time and the payload
+func Sign(secret string, payload []byte, at time.Time) string {
+	t := strconv.FormatInt(at.Unix(), 10)
+
+	return "t=" + t + ",v1=" + signature(secret, t, payload)
+}
+
+//VerifySignature checks the signature header value of a webhook payload received at a moment
+func VerifySignature(secret string, payload []byte, header string, now time.Time) error {
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "t":
+			t = kv[1]
+		case "v1":
+			v1 = kv[1]
+		}
+	}
+
+	sent, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || v1 == "" {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(v1), []byte(signature(secret, t, payload))) {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(sent, 0))
+	if age > SignatureTolerance || age < -SignatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func signature(secret, t string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,33 @@
+package payments
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"reference":"fake_1","status":"succeeded"}`)
+	now := time.Now()
+	valid := Sign("secret", payload, now)
+
+	var theTests = []struct {
+		name     string
+		payload  []byte
+		header   string
+		expected error
+	}{
+		{"valid", payload, valid, nil},
+		{"tampered", []byte(`{"reference":"fake_2","status":"succeeded"}`), valid, ErrInvalidSignature},
+		{"other-secret", payload, Sign("other", payload, now), ErrInvalidSignature},
+		{"too-old", payload, Sign("secret", payload, now.Add(-time.Hour)), ErrInvalidSignature},
+		{"from-the-future", payload, Sign("secret", payload, now.Add(time.Hour)), ErrInvalidSignature},
+		{"missing", payload, "", ErrInvalidSignature},
+		{"malformed", payload, "t=x,v1=y", ErrInvalidSignature},
+	}
+
+	for _, tt := range theTests {
+		if err := VerifySignature("secret", tt.payload, tt.header, now); err != tt.expected {
+			t.Errorf("failed %s: expected %v, but got %v", tt.name, tt.expected, err)
+		}
+	}
+}
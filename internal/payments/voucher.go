@@ -0,0 +1,71 @@
+package payments
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+var (
+	//ErrVoucherExpired is returned for a gift voucher redeemed after the day it expires
+	ErrVoucherExpired = errors.New("this voucher has expired")
+	//ErrVoucherUsedUp is returned for a gift voucher with nothing left on it
+	ErrVoucherUsedUp = errors.New("this voucher has been used up")
+)
+
+//voucherAlphabet leaves out the letters and digits that are easily mistaken for one another
+const voucherAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+//NewVoucherCode returns a random gift voucher code of four groups of four characters
+func NewVoucherCode() (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(voucherAlphabet)))
+
+	for i := 0; i < 16; i++ {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(voucherAlphabet[n.Int64()])
+	}
+
+	return b.String(), nil
+}
+
+//CheckVoucher returns an error explaining why a gift voucher can't be redeemed at a moment, nil if it can
+func CheckVoucher(v models.GiftVoucher, at time.Time) error {
+	if !v.ExpiresAt.IsZero() {
+		y, m, d := at.Date()
+		ey, em, ed := v.ExpiresAt.Date()
+		if time.Date(y, m, d, 0, 0, 0, 0, time.UTC).After(time.Date(ey, em, ed, 0, 0, 0, 0, time.UTC)) {
+			return ErrVoucherExpired
+		}
+	}
+
+	if v.Balance <= 0 {
+		return ErrVoucherUsedUp
+	}
+
+	return nil
+}
+
+//VoucherAmount returns how much of a gift voucher goes towards an amount owed, as much as is left on it
+func VoucherAmount(v models.GiftVoucher, owed int) int {
+	if owed <= 0 {
+		return 0
+	}
+
+	if v.Balance < owed {
+		return v.Balance
+	}
+
+	return owed
+}
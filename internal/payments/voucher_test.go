@@ -0,0 +1,70 @@
+package payments
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestNewVoucherCode(t *testing.T) {
+	format := regexp.MustCompile(`^[A-HJ-NP-Z2-9]{4}(-[A-HJ-NP-Z2-9]{4}){3}$`)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := NewVoucherCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !format.MatchString(code) {
+			t.Errorf("unexpected code format %q", code)
+		}
+
+		if seen[code] {
+			t.Errorf("code %q was generated twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestCheckVoucher(t *testing.T) {
+	at := time.Date(2030, 6, 1, 23, 0, 0, 0, time.UTC)
+
+	var theTests = []struct {
+		name     string
+		voucher  models.GiftVoucher
+		expected error
+	}{
+		{"no-expiry", models.GiftVoucher{Balance: 5000}, nil},
+		{"expires-today", models.GiftVoucher{Balance: 5000, ExpiresAt: time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC)}, nil},
+		{"expired", models.GiftVoucher{Balance: 5000, ExpiresAt: time.Date(2030, 5, 31, 0, 0, 0, 0, time.UTC)}, ErrVoucherExpired},
+		{"used-up", models.GiftVoucher{Amount: 5000}, ErrVoucherUsedUp},
+	}
+
+	for _, tt := range theTests {
+		if err := CheckVoucher(tt.voucher, at); err != tt.expected {
+			t.Errorf("failed %s: expected %v, but got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestVoucherAmount(t *testing.T) {
+	var theTests = []struct {
+		name     string
+		balance  int
+		owed     int
+		expected int
+	}{
+		{"partly", 5000, 20000, 5000},
+		{"fully", 50000, 20000, 20000},
+		{"nothing-owed", 5000, -100, 0},
+	}
+
+	for _, tt := range theTests {
+		if got := VoucherAmount(models.GiftVoucher{Balance: tt.balance}, tt.owed); got != tt.expected {
+			t.Errorf("failed %s: expected %d, but got %d", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestDocument(t *testing.T) {
+	d := New("Invoice (1)")
+	p := d.AddPage()
+	p.Text(50, 50, 12, true, `Hello (world) \ café`)
+	p.Line(50, 60, 545, 60)
+	d.AddPage().Text(50, 50, 10, false, "Page 2")
+
+	out := d.Bytes()
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("not a PDF document")
+	}
+
+	for _, s := range []string{
+		`/Count 2`,
+		`/Title (Invoice \(1\))`,
+		`BT /F2 12 Tf 50 791.89 Td (Hello \(world\) \\ caf\351) Tj ET`,
+		`BT /F1 10 Tf 50 791.89 Td (Page 2) Tj ET`,
+		`0.5 w 50 781.89 m 545 781.89 l S`,
+	} {
+		if !bytes.Contains(out, []byte(s)) {
+			t.Errorf("document does not contain %s", s)
+		}
+	}
+
+	//every object must start at the offset the cross reference table gives
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n0 10\n")) {
+		t.Fatalf("startxref %d does not point at the cross reference table", xref)
+	}
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	if len(entries) != 9 {
+		t.Fatalf("got %d objects, expected 9", len(entries))
+	}
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		if !bytes.HasPrefix(out[offset:], []byte(fmt.Sprintf("%d 0 obj", i+1))) {
+			t.Errorf("object %d is not at offset %d", i+1, offset)
+		}
+	}
+}
+
+func TestEmptyDocument(t *testing.T) {
+	out := New("").Bytes()
+
+	if !bytes.Contains(out, []byte("/Count 1")) {
+		t.Error("an empty document should have a blank page")
+	}
+}
+
+func TestWidth(t *testing.T) {
+	if w := Width("100.00", 10, false); w != 30.58 {
+		t.Errorf("got width %v, expected 30.58", w)
+	}
+
+	if Width("Total", 10, true) <= Width("Total", 10, false) {
+		t.Error("bold text should be wider")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if s := Truncate("Short", 100, 10, false); s != "Short" {
+		t.Errorf("got %s, expected the text unchanged", s)
+	}
+
+	s := Truncate("A rather long description of a charge", 60, 10, false)
+	if Width(s, 10, false) > 60 || s[len(s)-3:] != "..." {
+		t.Errorf("got %s, expected it shortened to fit", s)
+	}
+}
@@ -0,0 +1,25 @@
+package pricing
+
+import (
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+//ExtraPrice returns what quantity units of an extra cost for the stay of a reservation
+func ExtraPrice(e models.Extra, res models.Reservation, quantity int) int {
+	nights := Nights(res.StartDate, res.EndDate)
+	guests := res.Guests
+	if guests < 1 {
+		guests = 1
+	}
+
+	switch e.Kind {
+	case models.ExtraPerNight:
+		return e.Price * nights * quantity
+	case models.ExtraPerGuest:
+		return e.Price * guests * quantity
+	case models.ExtraPerGuestNight:
+		return e.Price * guests * nights * quantity
+	default:
+		return e.Price * quantity
+	}
+}
@@ -0,0 +1,43 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestExtraPrice(t *testing.T) {
+	res := models.Reservation{
+		StartDate: time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2050, 1, 4, 0, 0, 0, 0, time.UTC),
+		Guests:    2,
+	}
+
+	var theTests = []struct {
+		name     string
+		kind     string
+		quantity int
+		expected int
+	}{
+		{"per-stay", models.ExtraPerStay, 1, 1000},
+		{"per-stay-units", models.ExtraPerStay, 2, 2000},
+		{"per-night", models.ExtraPerNight, 2, 6000},
+		{"per-guest", models.ExtraPerGuest, 1, 2000},
+		{"per-guest-night", models.ExtraPerGuestNight, 1, 6000},
+		{"none", models.ExtraPerNight, 0, 0},
+	}
+
+	for _, tt := range theTests {
+		e := models.Extra{Kind: tt.kind, Price: 1000}
+		if got := ExtraPrice(e, res, tt.quantity); got != tt.expected {
+			t.Errorf("failed %s: expected %d, but got %d", tt.name, tt.expected, got)
+		}
+	}
+
+	//a reservation without guests counts as one
+	res.Guests = 0
+	if got := ExtraPrice(models.Extra{Kind: models.ExtraPerGuest, Price: 1000}, res, 1); got != 1000 {
+		t.Errorf("expected 1000 for one guest, but got %d", got)
+	}
+}
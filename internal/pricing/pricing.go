@@ -0,0 +1,66 @@
+package pricing
+
+import (
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+//Nights returns the number of nights between arrival and departure
+func Nights(start, end time.Time) int {
+	n := int(dateOf(end).Sub(dateOf(start)).Hours() / 24)
+	if n < 0 {
+		return 0
+	}
+
+	return n
+}
+
+//StayPrice returns the price of staying in a room from start to end
+func StayPrice(room models.Room, start, end time.Time) int {
+	return room.Price * Nights(start, end)
+}
+
+//CancellationFee returns the fee charged for cancelling a reservation at a given moment under a policy.
+//A policy with zero ID means cancellation is always free
+func CancellationFee(p models.CancellationPolicy, res models.Reservation, at time.Time) int {
+	if p.ID == 0 {
+		return 0
+	}
+
+	daysBefore := int(dateOf(res.StartDate).Sub(dateOf(at)).Hours() / 24)
+	if daysBefore >= p.FreeDaysBefore {
+		return 0
+	}
+
+	var fee int
+	switch p.FeeType {
+	case models.CancellationFeePercent:
+		fee = res.TotalPrice * p.FeePercent / 100
+	case models.CancellationFeeFirstNight:
+		if n := Nights(res.StartDate, res.EndDate); n > 0 {
+			fee = res.TotalPrice / n
+		}
+	}
+
+	if fee > res.TotalPrice {
+		return res.TotalPrice
+	}
+
+	return fee
+}
+
+//Refund returns the amount returned to the guest after the cancellation fee is kept
+func Refund(res models.Reservation, fee int) int {
+	if fee >= res.TotalPrice {
+		return 0
+	}
+
+	return res.TotalPrice - fee
+}
+
+//dateOf strips the time of day so that day differences are counted by calendar dates
+func dateOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,83 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestNights(t *testing.T) {
+	var theTests = []struct {
+		start    string
+		end      string
+		expected int
+	}{
+		{"2030-01-01", "2030-01-02", 1},
+		{"2030-01-30", "2030-02-02", 3},
+		{"2030-01-02", "2030-01-02", 0},
+		{"2030-01-03", "2030-01-02", 0},
+	}
+
+	for _, tt := range theTests {
+		if got := Nights(date(tt.start), date(tt.end)); got != tt.expected {
+			t.Errorf("Nights(%s, %s) returned %d, expected %d", tt.start, tt.end, got, tt.expected)
+		}
+	}
+}
+
+func TestStayPrice(t *testing.T) {
+	room := models.Room{Price: 7500}
+
+	if got := StayPrice(room, date("2030-01-01"), date("2030-01-04")); got != 22500 {
+		t.Errorf("StayPrice returned %d, expected 22500", got)
+	}
+}
+
+func TestCancellationFee(t *testing.T) {
+	res := models.Reservation{
+		StartDate:  date("2030-01-10"),
+		EndDate:    date("2030-01-14"),
+		TotalPrice: 40000,
+	}
+
+	percent := models.CancellationPolicy{ID: 1, FreeDaysBefore: 7, FeeType: models.CancellationFeePercent, FeePercent: 50}
+	firstNight := models.CancellationPolicy{ID: 2, FreeDaysBefore: 2, FeeType: models.CancellationFeeFirstNight}
+
+	var theTests = []struct {
+		name     string
+		policy   models.CancellationPolicy
+		at       time.Time
+		expected int
+	}{
+		{"no policy", models.CancellationPolicy{}, date("2030-01-10"), 0},
+		{"percent in free window", percent, date("2030-01-03"), 0},
+		{"percent after free window", percent, date("2030-01-04"), 20000},
+		{"first night in free window", firstNight, date("2030-01-08").Add(23 * time.Hour), 0},
+		{"first night after free window", firstNight, date("2030-01-09"), 10000},
+		{"first night after arrival", firstNight, date("2030-01-11"), 10000},
+	}
+
+	for _, tt := range theTests {
+		if got := CancellationFee(tt.policy, res, tt.at); got != tt.expected {
+			t.Errorf("%s: CancellationFee returned %d, expected %d", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestRefund(t *testing.T) {
+	res := models.Reservation{TotalPrice: 40000}
+
+	if got := Refund(res, 10000); got != 30000 {
+		t.Errorf("Refund returned %d, expected 30000", got)
+	}
+
+	if got := Refund(res, 50000); got != 0 {
+		t.Errorf("Refund returned %d, expected 0", got)
+	}
+}
@@ -0,0 +1,65 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+var (
+	//ErrPromoNotStarted is returned for a promo code redeemed before its window opens
+	ErrPromoNotStarted = errors.New("this code can't be used yet")
+	//ErrPromoExpired is returned for a promo code redeemed after its window closed
+	ErrPromoExpired = errors.New("this code has expired")
+	//ErrPromoRoom is returned for a promo code that doesn't apply to the room booked
+	ErrPromoRoom = errors.New("this code is not valid for this room")
+	//ErrPromoUsedUp is returned for a promo code redeemed as many times as it can be
+	ErrPromoUsedUp = errors.New("this code has been used up")
+)
+
+//CheckPromoCode returns an error explaining why a promo code can't be redeemed at a moment for a reservation,
+//nil if it can
+func CheckPromoCode(p models.PromoCode, res models.Reservation, at time.Time) error {
+	day := dateOf(at)
+
+	if !p.ValidFrom.IsZero() && day.Before(dateOf(p.ValidFrom)) {
+		return ErrPromoNotStarted
+	}
+
+	if !p.ValidTo.IsZero() && day.After(dateOf(p.ValidTo)) {
+		return ErrPromoExpired
+	}
+
+	if !p.HasRoom(res.RoomID) {
+		return ErrPromoRoom
+	}
+
+	if nights := Nights(res.StartDate, res.EndDate); nights < p.MinNights {
+		return fmt.Errorf("this code is only valid for stays of %d nights or more", p.MinNights)
+	}
+
+	if p.MaxUses > 0 && p.Uses >= p.MaxUses {
+		return ErrPromoUsedUp
+	}
+
+	return nil
+}
+
+//Discount returns what a promo code takes off a room price, never more than the price itself
+func Discount(p models.PromoCode, price int) int {
+	var discount int
+	switch p.Kind {
+	case models.PromoPercent:
+		discount = divRound(price*p.Amount, 10000)
+	case models.PromoFixed:
+		discount = p.Amount
+	}
+
+	if discount > price {
+		return price
+	}
+
+	return discount
+}
@@ -0,0 +1,65 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestCheckPromoCode(t *testing.T) {
+	res := models.Reservation{
+		RoomID:    1,
+		StartDate: date("2030-01-10"),
+		EndDate:   date("2030-01-13"),
+	}
+	at := date("2030-01-01")
+
+	var theTests = []struct {
+		name     string
+		promo    models.PromoCode
+		expected string
+	}{
+		{"open", models.PromoCode{}, ""},
+		{"in-window", models.PromoCode{ValidFrom: date("2030-01-01"), ValidTo: date("2030-01-01")}, ""},
+		{"not-started", models.PromoCode{ValidFrom: date("2030-01-02")}, ErrPromoNotStarted.Error()},
+		{"expired", models.PromoCode{ValidTo: date("2029-12-31")}, ErrPromoExpired.Error()},
+		{"room", models.PromoCode{RoomIDs: []int{1, 2}}, ""},
+		{"other-room", models.PromoCode{RoomIDs: []int{2}}, ErrPromoRoom.Error()},
+		{"min-nights", models.PromoCode{MinNights: 3}, ""},
+		{"too-short", models.PromoCode{MinNights: 4}, "this code is only valid for stays of 4 nights or more"},
+		{"uses-left", models.PromoCode{MaxUses: 5, Uses: 4}, ""},
+		{"used-up", models.PromoCode{MaxUses: 5, Uses: 5}, ErrPromoUsedUp.Error()},
+	}
+
+	for _, tt := range theTests {
+		err := CheckPromoCode(tt.promo, res, at)
+
+		if tt.expected == "" && err != nil {
+			t.Errorf("failed %s: expected no error, but got %s", tt.name, err)
+		}
+
+		if tt.expected != "" && (err == nil || err.Error() != tt.expected) {
+			t.Errorf("failed %s: expected %q, but got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestDiscount(t *testing.T) {
+	var theTests = []struct {
+		name     string
+		promo    models.PromoCode
+		expected int
+	}{
+		{"percent", models.PromoCode{Kind: models.PromoPercent, Amount: 1500}, 3000},
+		{"percent-rounded", models.PromoCode{Kind: models.PromoPercent, Amount: 333}, 666},
+		{"fixed", models.PromoCode{Kind: models.PromoFixed, Amount: 2500}, 2500},
+		{"fixed-above-price", models.PromoCode{Kind: models.PromoFixed, Amount: 50000}, 20000},
+		{"unknown-kind", models.PromoCode{Kind: "x", Amount: 2500}, 0},
+	}
+
+	for _, tt := range theTests {
+		if got := Discount(tt.promo, 20000); got != tt.expected {
+			t.Errorf("failed %s: expected %d, but got %d", tt.name, tt.expected, got)
+		}
+	}
+}
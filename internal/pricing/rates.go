@@ -0,0 +1,113 @@
+package pricing
+
+import (
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+//StayRate returns the adjustments price rules make to the price of staying in a room from start to end,
+//booked at bookedAt, given the percentage of rooms sold on each night. Occupancy rules apply night by night,
+//the one with the highest threshold reached. Of every other kind a single rule applies to the whole stay:
+//the longest length of stay reached, the earliest early bird and the latest last minute rate. They are
+//worked out on the price after occupancy adjustments, each rounded to minor units, and rules are taken in
+//order on ties, so the same inputs always give the same price
+func StayRate(rules []models.PriceRule, room models.Room, start, end, bookedAt time.Time,
+	occupancy map[time.Time]int) []models.PriceAdjustment {
+	var adjustments []models.PriceAdjustment
+
+	add := func(rule models.PriceRule, amount int) {
+		if amount == 0 {
+			return
+		}
+
+		for i := range adjustments {
+			if adjustments[i].PriceRuleID == rule.ID && adjustments[i].Name == rule.Name {
+				adjustments[i].Amount += amount
+				return
+			}
+		}
+
+		adjustments = append(adjustments, models.PriceAdjustment{PriceRuleID: rule.ID, Name: rule.Name, Amount: amount})
+	}
+
+	price := 0
+	for d := dateOf(start); d.Before(dateOf(end)); d = d.AddDate(0, 0, 1) {
+		night := room.Price
+
+		if rule, ok := bestRule(rules, models.PriceOccupancy, occupancy[d], true); ok {
+			amount := percentOf(room.Price, rule.Adjustment)
+			add(rule, amount)
+			night += amount
+		}
+
+		price += night
+	}
+
+	nights := Nights(start, end)
+	lead := Nights(bookedAt, start)
+
+	stay := []struct {
+		kind  string
+		value int
+		above bool
+	}{
+		{models.PriceLengthOfStay, nights, true},
+		{models.PriceEarlyBird, lead, true},
+		{models.PriceLastMinute, lead, false},
+	}
+
+	for _, s := range stay {
+		if rule, ok := bestRule(rules, s.kind, s.value, s.above); ok {
+			add(rule, percentOf(price, rule.Adjustment))
+		}
+	}
+
+	return adjustments
+}
+
+//RatedPrice returns the price of a stay in a room with the adjustments made to it, never below zero
+func RatedPrice(room models.Room, start, end time.Time, adjustments []models.PriceAdjustment) int {
+	price := StayPrice(room, start, end)
+	for _, a := range adjustments {
+		price += a.Amount
+	}
+
+	if price < 0 {
+		return 0
+	}
+
+	return price
+}
+
+//bestRule returns the rule of a kind whose threshold value reaches, the highest one when above is true and
+//value must be at least the threshold, the lowest one when value must be at most the threshold
+func bestRule(rules []models.PriceRule, kind string, value int, above bool) (models.PriceRule, bool) {
+	var best models.PriceRule
+	found := false
+
+	for _, rule := range rules {
+		if rule.Kind != kind {
+			continue
+		}
+
+		if above && value >= rule.Threshold && (!found || rule.Threshold > best.Threshold) {
+			best, found = rule, true
+		}
+
+		if !above && value <= rule.Threshold && (!found || rule.Threshold < best.Threshold) {
+			best, found = rule, true
+		}
+	}
+
+	return best, found
+}
+
+//percentOf returns rate hundredths of a percent of an amount, rounded half away from zero
+func percentOf(amount, rate int) int {
+	if rate < 0 {
+		return -divRound(amount*-rate, 10000)
+	}
+
+	return divRound(amount*rate, 10000)
+}
@@ -0,0 +1,94 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestStayRate(t *testing.T) {
+	room := models.Room{ID: 1, Price: 10000}
+
+	rules := []models.PriceRule{
+		{ID: 1, Name: "Weekly", Kind: models.PriceLengthOfStay, Threshold: 7, Adjustment: -1000},
+		{ID: 2, Name: "Monthly", Kind: models.PriceLengthOfStay, Threshold: 28, Adjustment: -2500},
+		{ID: 3, Name: "Early bird", Kind: models.PriceEarlyBird, Threshold: 60, Adjustment: -500},
+		{ID: 4, Name: "Last minute", Kind: models.PriceLastMinute, Threshold: 3, Adjustment: -1500},
+		{ID: 5, Name: "Tonight", Kind: models.PriceLastMinute, Threshold: 0, Adjustment: -2000},
+		{ID: 6, Name: "Busy", Kind: models.PriceOccupancy, Threshold: 50, Adjustment: 1000},
+		{ID: 7, Name: "Nearly full", Kind: models.PriceOccupancy, Threshold: 80, Adjustment: 2500},
+	}
+
+	busy := map[time.Time]int{
+		date("2030-03-01"): 50,
+		date("2030-03-02"): 90,
+		date("2030-03-03"): 20,
+	}
+
+	var theTests = []struct {
+		name     string
+		start    string
+		end      string
+		bookedAt string
+		expected map[string]int
+	}{
+		{"no-rules-apply", "2030-03-10", "2030-03-12", "2030-02-20", map[string]int{}},
+		{"weekly", "2030-03-10", "2030-03-17", "2030-02-20", map[string]int{"Weekly": -7000}},
+		{"monthly-not-weekly", "2030-03-10", "2030-04-07", "2030-02-20", map[string]int{"Monthly": -70000}},
+		{"early-bird", "2030-03-10", "2030-03-12", "2030-01-01", map[string]int{"Early bird": -1000}},
+		{"last-minute", "2030-03-10", "2030-03-12", "2030-03-08", map[string]int{"Last minute": -3000}},
+		{"latest-last-minute", "2030-03-10", "2030-03-12", "2030-03-10", map[string]int{"Tonight": -4000}},
+		{"occupancy-by-night", "2030-03-01", "2030-03-04", "2030-02-20", map[string]int{"Busy": 1000, "Nearly full": 2500}},
+		{"occupancy-then-weekly", "2030-03-01", "2030-03-08", "2030-02-20",
+			map[string]int{"Busy": 1000, "Nearly full": 2500, "Weekly": -7350}},
+	}
+
+	for _, tt := range theTests {
+		adjustments := StayRate(rules, room, date(tt.start), date(tt.end), date(tt.bookedAt), busy)
+
+		if len(adjustments) != len(tt.expected) {
+			t.Errorf("failed %s: expected %v, but got %v", tt.name, tt.expected, adjustments)
+			continue
+		}
+
+		for _, a := range adjustments {
+			if amount, ok := tt.expected[a.Name]; !ok || amount != a.Amount {
+				t.Errorf("failed %s: unexpected adjustment %v", tt.name, a)
+			}
+		}
+	}
+}
+
+func TestStayRate_IsDeterministic(t *testing.T) {
+	room := models.Room{ID: 1, Price: 9999}
+	rules := []models.PriceRule{
+		{ID: 1, Name: "First", Kind: models.PriceLengthOfStay, Threshold: 2, Adjustment: -333},
+		{ID: 2, Name: "Second", Kind: models.PriceLengthOfStay, Threshold: 2, Adjustment: -999},
+	}
+
+	first := StayRate(rules, room, date("2030-03-01"), date("2030-03-04"), date("2030-02-01"), nil)
+	for i := 0; i < 10; i++ {
+		again := StayRate(rules, room, date("2030-03-01"), date("2030-03-04"), date("2030-02-01"), nil)
+		if len(again) != 1 || again[0] != first[0] {
+			t.Fatalf("expected %v every time, but got %v", first, again)
+		}
+	}
+
+	if first[0].Name != "First" || first[0].Amount != -999 {
+		t.Errorf("expected the first rule on a tie, rounded to -999, but got %v", first[0])
+	}
+}
+
+func TestRatedPrice(t *testing.T) {
+	room := models.Room{Price: 10000}
+	start, end := date("2030-03-01"), date("2030-03-03")
+
+	if got := RatedPrice(room, start, end, []models.PriceAdjustment{{Amount: 1000}, {Amount: -3000}}); got != 18000 {
+		t.Errorf("expected 18000, but got %d", got)
+	}
+
+	if got := RatedPrice(room, start, end, []models.PriceAdjustment{{Amount: -30000}}); got != 0 {
+		t.Errorf("expected a price never below 0, but got %d", got)
+	}
+}
@@ -0,0 +1,125 @@
+package pricing
+
+import (
+	"strings"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+//Taxes returns the taxes rules charge on a reservation whose room price without added taxes is price, in
+//the order of the rules. Rules that don't apply to any night of the stay are left out
+func Taxes(rules []models.TaxRule, res models.Reservation, price int) []models.ReservationTax {
+	var taxes []models.ReservationTax
+
+	nights := Nights(res.StartDate, res.EndDate)
+	guests := res.Guests
+	if guests < 1 {
+		guests = 1
+	}
+
+	for _, rule := range rules {
+		if exempt(rule, res) {
+			continue
+		}
+
+		taxed := taxedNights(rule, res.StartDate, res.EndDate)
+		if taxed == 0 {
+			continue
+		}
+
+		var amount int
+		switch rule.Kind {
+		case models.TaxPercent:
+			base := price * taxed / nights
+			if rule.Inclusive {
+				amount = divRound(base*rule.Rate, 10000+rule.Rate)
+			} else {
+				amount = divRound(base*rule.Rate, 10000)
+			}
+		case models.TaxPerNight:
+			amount = rule.Rate * taxed
+		case models.TaxPerGuest:
+			amount = rule.Rate * guests
+		case models.TaxPerGuestNight:
+			amount = rule.Rate * guests * taxed
+		default:
+			continue
+		}
+
+		taxes = append(taxes, models.ReservationTax{
+			TaxRuleID: rule.ID,
+			Name:      rule.Name,
+			Amount:    amount,
+			Inclusive: rule.Inclusive && rule.Kind == models.TaxPercent,
+		})
+	}
+
+	return taxes
+}
+
+//TotalWithTaxes returns what is paid for a room price once the taxes not included in it are added
+func TotalWithTaxes(price int, taxes []models.ReservationTax) int {
+	for _, t := range taxes {
+		if !t.Inclusive {
+			price += t.Amount
+		}
+	}
+
+	return price
+}
+
+//Retax returns the taxes and total price of a reservation charged again with its current tags, for the
+//same price before taxes it was booked for
+func Retax(rules []models.TaxRule, res models.Reservation) ([]models.ReservationTax, int) {
+	price := res.TotalPrice - res.ExtrasTotal()
+	for _, t := range res.Taxes {
+		if !t.Inclusive {
+			price -= t.Amount
+		}
+	}
+
+	taxes := Taxes(rules, res, price)
+
+	return taxes, TotalWithTaxes(price, taxes) + res.ExtrasTotal()
+}
+
+//exempt returns true if a reservation carries the exemption tag of a rule
+func exempt(rule models.TaxRule, res models.Reservation) bool {
+	if rule.ExemptTag == "" {
+		return false
+	}
+
+	for _, tag := range res.Tags {
+		if strings.EqualFold(tag, rule.ExemptTag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//taxedNights returns how many nights of a stay fall within the dates of a rule, up to its maximum
+func taxedNights(rule models.TaxRule, start, end time.Time) int {
+	var n int
+	for d := dateOf(start); d.Before(dateOf(end)); d = d.AddDate(0, 0, 1) {
+		if !rule.ValidFrom.IsZero() && d.Before(dateOf(rule.ValidFrom)) {
+			continue
+		}
+		if !rule.ValidTo.IsZero() && d.After(dateOf(rule.ValidTo)) {
+			continue
+		}
+
+		n++
+		if rule.MaxNights > 0 && n == rule.MaxNights {
+			break
+		}
+	}
+
+	return n
+}
+
+//divRound divides and rounds half up, for amounts that are never negative
+func divRound(a, b int) int {
+	return (2*a + b) / (2 * b)
+}
@@ -0,0 +1,115 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func TestTaxes(t *testing.T) {
+	res := models.Reservation{
+		StartDate: date("2030-01-01"),
+		EndDate:   date("2030-01-05"),
+		Guests:    2,
+		Tags:      []string{"diplomat"},
+	}
+
+	var theTests = []struct {
+		name     string
+		rule     models.TaxRule
+		guests   int
+		expected int
+	}{
+		{"inclusive-percent", models.TaxRule{Kind: models.TaxPercent, Rate: 2000, Inclusive: true}, 2, 6667},
+		{"added-percent", models.TaxRule{Kind: models.TaxPercent, Rate: 1000}, 2, 4000},
+		{"per-night", models.TaxRule{Kind: models.TaxPerNight, Rate: 250}, 2, 1000},
+		{"per-guest", models.TaxRule{Kind: models.TaxPerGuest, Rate: 500}, 2, 1000},
+		{"per-guest-night", models.TaxRule{Kind: models.TaxPerGuestNight, Rate: 150}, 2, 1200},
+		{"no-guests-counts-one", models.TaxRule{Kind: models.TaxPerGuestNight, Rate: 150}, 0, 600},
+		{"max-nights", models.TaxRule{Kind: models.TaxPerGuestNight, Rate: 150, MaxNights: 3}, 2, 900},
+		{"valid-from", models.TaxRule{Kind: models.TaxPerNight, Rate: 250, ValidFrom: date("2030-01-03")}, 2, 500},
+		{"valid-from-percent", models.TaxRule{Kind: models.TaxPercent, Rate: 1000, ValidFrom: date("2030-01-03")}, 2, 2000},
+		{"valid-to", models.TaxRule{Kind: models.TaxPerNight, Rate: 250, ValidTo: date("2030-01-01")}, 2, 250},
+		{"expired", models.TaxRule{Kind: models.TaxPerNight, Rate: 250, ValidTo: date("2029-12-31")}, 2, -1},
+		{"not-yet-valid", models.TaxRule{Kind: models.TaxPerNight, Rate: 250, ValidFrom: date("2030-01-05")}, 2, -1},
+		{"exempt", models.TaxRule{Kind: models.TaxPerNight, Rate: 250, ExemptTag: "Diplomat"}, 2, -1},
+		{"not-exempt", models.TaxRule{Kind: models.TaxPerNight, Rate: 250, ExemptTag: "Child"}, 2, 1000},
+		{"unknown-kind", models.TaxRule{Kind: "x", Rate: 250}, 2, -1},
+	}
+
+	for _, tt := range theTests {
+		res.Guests = tt.guests
+		tt.rule.ID = 7
+		tt.rule.Name = tt.name
+
+		taxes := Taxes([]models.TaxRule{tt.rule}, res, 40000)
+
+		if tt.expected < 0 {
+			if len(taxes) != 0 {
+				t.Errorf("failed %s: expected no tax, but got %v", tt.name, taxes)
+			}
+			continue
+		}
+
+		if len(taxes) != 1 {
+			t.Errorf("failed %s: expected one tax, but got %v", tt.name, taxes)
+			continue
+		}
+
+		if taxes[0].Amount != tt.expected {
+			t.Errorf("failed %s: expected %d, but got %d", tt.name, tt.expected, taxes[0].Amount)
+		}
+
+		if taxes[0].TaxRuleID != 7 || taxes[0].Name != tt.name || taxes[0].Inclusive != tt.rule.Inclusive {
+			t.Errorf("failed %s: wrong tax %v", tt.name, taxes[0])
+		}
+	}
+}
+
+func TestTaxes_OnlyPercentagesAreIncluded(t *testing.T) {
+	res := models.Reservation{StartDate: date("2030-01-01"), EndDate: date("2030-01-02")}
+
+	taxes := Taxes([]models.TaxRule{{Kind: models.TaxPerNight, Rate: 250, Inclusive: true}}, res, 10000)
+	if len(taxes) != 1 || taxes[0].Inclusive {
+		t.Errorf("a fixed tax should be added on top, but got %v", taxes)
+	}
+}
+
+func TestTotalWithTaxes(t *testing.T) {
+	taxes := []models.ReservationTax{
+		{Name: "VAT", Amount: 6667, Inclusive: true},
+		{Name: "Tourist levy", Amount: 1000},
+	}
+
+	if got := TotalWithTaxes(40000, taxes); got != 41000 {
+		t.Errorf("TotalWithTaxes returned %d, expected 41000", got)
+	}
+}
+
+func TestRetax(t *testing.T) {
+	rules := []models.TaxRule{
+		{ID: 1, Name: "VAT", Kind: models.TaxPercent, Rate: 2000, Inclusive: true},
+		{ID: 2, Name: "Tourist levy", Kind: models.TaxPerGuestNight, Rate: 250, ExemptTag: "Resident"},
+	}
+
+	res := models.Reservation{
+		StartDate: date("2030-01-01"),
+		EndDate:   date("2030-01-03"),
+		Guests:    2,
+		Extras:    []models.ReservationExtra{{Name: "Breakfast", Amount: 2000}},
+	}
+	res.Taxes = Taxes(rules, res, 12000)
+	res.TotalPrice = TotalWithTaxes(12000, res.Taxes) + res.ExtrasTotal()
+
+	res.Tags = []string{"resident"}
+	taxes, total := Retax(rules, res)
+	if len(taxes) != 1 || taxes[0].Name != "VAT" || total != 14000 {
+		t.Errorf("expected only VAT and a total of 14000, but got %v and %d", taxes, total)
+	}
+
+	res.Taxes, res.TotalPrice, res.Tags = taxes, total, nil
+	taxes, total = Retax(rules, res)
+	if len(taxes) != 2 || total != 15000 {
+		t.Errorf("expected the levy back and a total of 15000, but got %v and %d", taxes, total)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/justinas/nosurf"
 	"github.com/yalagtyarzh/leafsite/internal/config"
+	"github.com/yalagtyarzh/leafsite/internal/currency"
 	"github.com/yalagtyarzh/leafsite/internal/models"
 )
 
@@ -19,6 +20,10 @@ var functions = template.FuncMap{
 	"formatDate": FormatDate,
 	"iterate":    Iterate,
 	"add":        Add,
+	"money":      Money,
+	"price":      Price,
+	"convert":    Convert,
+	"rate":       currency.FormatRate,
 }
 
 var app *config.AppConfig
@@ -39,6 +44,35 @@ func Iterate(count int) []int {
 	return items
 }
 
+//Money formats an amount in minor currency units as a decimal number
+func Money(amount int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+//Price formats an amount in minor units of a currency followed by its code
+func Price(amount int, code string) string {
+	if code == "" {
+		return Money(amount)
+	}
+
+	return Money(amount) + " " + code
+}
+
+//Convert formats an amount in minor units of the base currency in the currency of a rate, empty without a rate
+func Convert(amount int, r models.ExchangeRate) string {
+	if r.Rate == 0 {
+		return ""
+	}
+
+	return Price(currency.Convert(amount, r), r.Currency)
+}
+
 //NewRenderer sets the config for the template package
 func NewRenderer(a *config.AppConfig) {
 	app = a
@@ -61,6 +95,13 @@ func AddDefaultData(td *models.TemplateData, r *http.Request) *models.TemplateDa
 	if app.Session.Exists(r.Context(), "user_id") {
 		td.IsAuthenticated = 1
 	}
+
+	//charges are in the base currency, the one a guest chose to see prices in is only for reference
+	td.Currency = app.Currency
+	if app.ExchangeRates != nil {
+		td.Currencies = app.ExchangeRates.Codes()
+		td.DisplayRate, _ = app.ExchangeRates.Get(app.Session.GetString(r.Context(), "currency"))
+	}
 	return td
 }
 
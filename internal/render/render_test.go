@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/yalagtyarzh/leafsite/internal/currency"
 	"github.com/yalagtyarzh/leafsite/internal/models"
 )
 
@@ -76,3 +77,65 @@ func TestCreateTemplateCache(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestMoney(t *testing.T) {
+	var theTests = []struct {
+		amount   int
+		expected string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{12345, "123.45"},
+		{-250, "-2.50"},
+	}
+
+	for _, tt := range theTests {
+		if got := Money(tt.amount); got != tt.expected {
+			t.Errorf("Money(%d) returned %s, expected %s", tt.amount, got, tt.expected)
+		}
+	}
+}
+
+func TestPrice(t *testing.T) {
+	if got := Price(12345, "EUR"); got != "123.45 EUR" {
+		t.Errorf("expected 123.45 EUR, but got %s", got)
+	}
+
+	if got := Price(12345, ""); got != "123.45" {
+		t.Errorf("expected 123.45 without a currency, but got %s", got)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	if got := Convert(10000, models.ExchangeRate{Currency: "USD", Rate: 1082500}); got != "108.25 USD" {
+		t.Errorf("expected 108.25 USD, but got %s", got)
+	}
+
+	if got := Convert(10000, models.ExchangeRate{}); got != "" {
+		t.Errorf("expected nothing without a rate, but got %s", got)
+	}
+}
+
+func TestAddDefaultData_Currency(t *testing.T) {
+	testApp.Currency = "EUR"
+	testApp.ExchangeRates = currency.NewRates()
+	testApp.ExchangeRates.Set([]models.ExchangeRate{{Currency: "USD", Rate: 1082500}})
+	defer func() {
+		testApp.Currency = ""
+		testApp.ExchangeRates = nil
+	}()
+
+	r, err := getSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session.Put(r.Context(), "currency", "USD")
+
+	var td models.TemplateData
+	result := AddDefaultData(&td, r)
+	if result.Currency != "EUR" || len(result.Currencies) != 1 || result.DisplayRate.Currency != "USD" {
+		t.Errorf("expected EUR shown in USD, but got %s, %v and %v", result.Currency, result.Currencies,
+			result.DisplayRate)
+	}
+}
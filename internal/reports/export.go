@@ -0,0 +1,57 @@
+package reports
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/yalagtyarzh/leafsite/internal/render"
+)
+
+//csvHeader names the columns written by WriteCSV
+var csvHeader = []string{
+	"room", "room_nights_available", "room_nights_sold", "occupancy", "revenue", "adr", "revpar",
+	"arrivals", "average_lead_time", "average_length_of_stay", "cancellations", "cancellation_rate",
+	"on_the_books", "on_the_books_last_year", "pickup", "pickup_last_year",
+}
+
+//WriteCSV writes a row for every room followed by the total
+func (r Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	err := cw.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range append(r.Rooms, r.Total) {
+		err = cw.Write(m.csvRecord())
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (m Metrics) csvRecord() []string {
+	return []string{
+		m.RoomName,
+		strconv.Itoa(m.RoomNightsAvailable),
+		strconv.Itoa(m.RoomNightsSold),
+		strconv.FormatFloat(m.Occupancy, 'f', 2, 64),
+		render.Money(m.Revenue),
+		render.Money(m.ADR),
+		render.Money(m.RevPAR),
+		strconv.Itoa(m.Arrivals),
+		strconv.FormatFloat(m.AverageLeadTime, 'f', 2, 64),
+		strconv.FormatFloat(m.AverageLengthOfStay, 'f', 2, 64),
+		strconv.Itoa(m.Cancellations),
+		strconv.FormatFloat(m.CancellationRate, 'f', 2, 64),
+		strconv.Itoa(m.OnTheBooks),
+		strconv.Itoa(m.OnTheBooksLastYear),
+		strconv.Itoa(m.Pickup),
+		strconv.Itoa(m.PickupLastYear),
+	}
+}
@@ -0,0 +1,230 @@
+package reports
+
+import (
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/pricing"
+)
+
+//pickupDays is the window over which newly booked room nights count as pickup
+const pickupDays = 7
+
+//Metrics holds the figures of a report for one room, or for all rooms when RoomID is 0.
+//Money is in minor units, percentages and averages are rounded to two decimals. Revenue is net of taxes
+type Metrics struct {
+	RoomID              int     `json:"room_id"`
+	RoomName            string  `json:"room_name"`
+	RoomNightsAvailable int     `json:"room_nights_available"`
+	RoomNightsSold      int     `json:"room_nights_sold"`
+	Occupancy           float64 `json:"occupancy"`
+	Revenue             int     `json:"revenue"`
+	ADR                 int     `json:"adr"`
+	RevPAR              int     `json:"revpar"`
+	Arrivals            int     `json:"arrivals"`
+	AverageLeadTime     float64 `json:"average_lead_time"`
+	AverageLengthOfStay float64 `json:"average_length_of_stay"`
+	Cancellations       int     `json:"cancellations"`
+	CancellationRate    float64 `json:"cancellation_rate"`
+	OnTheBooks          int     `json:"on_the_books"`
+	OnTheBooksLastYear  int     `json:"on_the_books_last_year"`
+	Pickup              int     `json:"pickup"`
+	PickupLastYear      int     `json:"pickup_last_year"`
+
+	leadDays   int
+	stayNights int
+}
+
+//Report holds the metrics of every room and the total for the nights from Start up to End, with the taxes
+//charged on them
+type Report struct {
+	Start  time.Time  `json:"start"`
+	End    time.Time  `json:"end"`
+	AsOf   time.Time  `json:"as_of"`
+	Rooms  []Metrics  `json:"rooms"`
+	Total  Metrics    `json:"total"`
+	Taxes  []TaxTotal `json:"taxes"`
+	Extras []TaxTotal `json:"extras"`
+}
+
+//TaxTotal is how much of a tax, or of an extra, was charged on the nights of a report, in minor units
+type TaxTotal struct {
+	Name   string `json:"name"`
+	Amount int    `json:"amount"`
+}
+
+//Build computes a report for the nights from start up to end as of a moment. The reservations must include
+//the cancelled ones overlapping the range, lastYear the ones overlapping the same range a year before
+func Build(rooms []models.Room, reservations, lastYear []models.Reservation, start, end, asOf time.Time) Report {
+	report := Report{
+		Start: start,
+		End:   end,
+		AsOf:  asOf,
+		Total: Metrics{RoomName: "Total"},
+	}
+
+	nights := pricing.Nights(start, end)
+	byRoom := make(map[int]*Metrics)
+	taxes := make(map[string]int)
+	extras := make(map[string]int)
+
+	report.Rooms = make([]Metrics, len(rooms))
+	for i, x := range rooms {
+		report.Rooms[i] = Metrics{
+			RoomID:              x.ID,
+			RoomName:            x.RoomName,
+			RoomNightsAvailable: nights,
+		}
+		byRoom[x.ID] = &report.Rooms[i]
+	}
+
+	for _, res := range reservations {
+		m, ok := byRoom[res.RoomID]
+		if !ok {
+			continue
+		}
+
+		inRange := nightsInRange(res, start, end)
+
+		if arrivesInRange(res, start, end) {
+			m.Arrivals++
+			if res.Status == models.ReservationCancelled {
+				m.Cancellations++
+			} else {
+				m.leadDays += pricing.Nights(res.CreatedAt, res.StartDate)
+				m.stayNights += pricing.Nights(res.StartDate, res.EndDate)
+			}
+		}
+
+		if res.Status != models.ReservationCancelled {
+			m.RoomNightsSold += inRange
+			if n := pricing.Nights(res.StartDate, res.EndDate); n > 0 {
+				net := res.TotalPrice
+				for _, t := range res.Taxes {
+					net -= t.Amount
+
+					i, ok := taxes[t.Name]
+					if !ok {
+						i = len(report.Taxes)
+						taxes[t.Name] = i
+						report.Taxes = append(report.Taxes, TaxTotal{Name: t.Name})
+					}
+					report.Taxes[i].Amount += t.Amount * inRange / n
+				}
+
+				for _, x := range res.Extras {
+					net -= x.Amount
+
+					i, ok := extras[x.Name]
+					if !ok {
+						i = len(report.Extras)
+						extras[x.Name] = i
+						report.Extras = append(report.Extras, TaxTotal{Name: x.Name})
+					}
+					report.Extras[i].Amount += x.Amount * inRange / n
+				}
+
+				m.Revenue += net * inRange / n
+			}
+		}
+
+		if onTheBooks(res, asOf) {
+			m.OnTheBooks += inRange
+			if !res.CreatedAt.Before(asOf.AddDate(0, 0, -pickupDays)) {
+				m.Pickup += inRange
+			}
+		}
+	}
+
+	lastYearStart, lastYearEnd, lastYearAsOf := start.AddDate(-1, 0, 0), end.AddDate(-1, 0, 0), asOf.AddDate(-1, 0, 0)
+	for _, res := range lastYear {
+		m, ok := byRoom[res.RoomID]
+		if !ok || !onTheBooks(res, lastYearAsOf) {
+			continue
+		}
+
+		inRange := nightsInRange(res, lastYearStart, lastYearEnd)
+		m.OnTheBooksLastYear += inRange
+		if !res.CreatedAt.Before(lastYearAsOf.AddDate(0, 0, -pickupDays)) {
+			m.PickupLastYear += inRange
+		}
+	}
+
+	for i := range report.Rooms {
+		m := &report.Rooms[i]
+		finish(m)
+
+		t := &report.Total
+		t.RoomNightsAvailable += m.RoomNightsAvailable
+		t.RoomNightsSold += m.RoomNightsSold
+		t.Revenue += m.Revenue
+		t.Arrivals += m.Arrivals
+		t.Cancellations += m.Cancellations
+		t.OnTheBooks += m.OnTheBooks
+		t.OnTheBooksLastYear += m.OnTheBooksLastYear
+		t.Pickup += m.Pickup
+		t.PickupLastYear += m.PickupLastYear
+		t.leadDays += m.leadDays
+		t.stayNights += m.stayNights
+	}
+
+	finish(&report.Total)
+
+	return report
+}
+
+//finish computes the ratios of a metrics row from its sums
+func finish(m *Metrics) {
+	if m.RoomNightsAvailable > 0 {
+		m.Occupancy = percent(m.RoomNightsSold, m.RoomNightsAvailable)
+		m.RevPAR = m.Revenue / m.RoomNightsAvailable
+	}
+
+	if m.RoomNightsSold > 0 {
+		m.ADR = m.Revenue / m.RoomNightsSold
+	}
+
+	if m.Arrivals > 0 {
+		m.CancellationRate = percent(m.Cancellations, m.Arrivals)
+	}
+
+	if stays := m.Arrivals - m.Cancellations; stays > 0 {
+		m.AverageLeadTime = round(float64(m.leadDays) / float64(stays))
+		m.AverageLengthOfStay = round(float64(m.stayNights) / float64(stays))
+	}
+}
+
+//nightsInRange returns how many nights of a reservation fall between start and end
+func nightsInRange(res models.Reservation, start, end time.Time) int {
+	from, to := res.StartDate, res.EndDate
+	if from.Before(start) {
+		from = start
+	}
+	if to.After(end) {
+		to = end
+	}
+
+	return pricing.Nights(from, to)
+}
+
+//arrivesInRange returns true if a reservation arrives between start and end
+func arrivesInRange(res models.Reservation, start, end time.Time) bool {
+	return !res.StartDate.Before(start) && res.StartDate.Before(end)
+}
+
+//onTheBooks returns true if a reservation had been made and not cancelled yet at a moment
+func onTheBooks(res models.Reservation, at time.Time) bool {
+	if res.CreatedAt.After(at) {
+		return false
+	}
+
+	return res.Status != models.ReservationCancelled || res.CancelledAt.After(at)
+}
+
+func percent(part, whole int) float64 {
+	return round(float64(part) * 100 / float64(whole))
+}
+
+func round(f float64) float64 {
+	return float64(int(f*100+0.5)) / 100
+}
@@ -0,0 +1,186 @@
+package reports
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yalagtyarzh/leafsite/internal/models"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestBuild(t *testing.T) {
+	rooms := []models.Room{{ID: 1, RoomName: "General's Quarters"}, {ID: 2, RoomName: "Major's Suite"}}
+	start, end, asOf := date("2050-03-01"), date("2050-03-11"), date("2050-02-20")
+
+	reservations := []models.Reservation{
+		//4 nights inside the range, booked 10 days ahead
+		{RoomID: 1, StartDate: date("2050-03-02"), EndDate: date("2050-03-06"), TotalPrice: 40000,
+			CreatedAt: date("2050-02-20"), Status: models.ReservationConfirmed},
+		//2 of 4 nights inside the range, arriving before it
+		{RoomID: 1, StartDate: date("2050-02-27"), EndDate: date("2050-03-03"), TotalPrice: 40000,
+			CreatedAt: date("2050-01-01"), Status: models.ReservationConfirmed},
+		//cancelled after asOf, so still on the books then
+		{RoomID: 2, StartDate: date("2050-03-05"), EndDate: date("2050-03-07"), TotalPrice: 30000,
+			CreatedAt: date("2050-01-01"), Status: models.ReservationCancelled, CancelledAt: date("2050-02-25")},
+		//unknown room
+		{RoomID: 3, StartDate: date("2050-03-05"), EndDate: date("2050-03-07"), TotalPrice: 30000,
+			CreatedAt: date("2050-01-01"), Status: models.ReservationConfirmed},
+	}
+
+	lastYear := []models.Reservation{
+		{RoomID: 2, StartDate: date("2049-03-01"), EndDate: date("2049-03-04"), TotalPrice: 30000,
+			CreatedAt: date("2049-02-15"), Status: models.ReservationConfirmed},
+		//made after the same moment last year
+		{RoomID: 2, StartDate: date("2049-03-05"), EndDate: date("2049-03-06"), TotalPrice: 10000,
+			CreatedAt: date("2049-02-25"), Status: models.ReservationConfirmed},
+	}
+
+	report := Build(rooms, reservations, lastYear, start, end, asOf)
+
+	gq := report.Rooms[0]
+	if gq.RoomNightsAvailable != 10 || gq.RoomNightsSold != 6 {
+		t.Errorf("expected 10 available and 6 sold nights, but got %d and %d", gq.RoomNightsAvailable, gq.RoomNightsSold)
+	}
+
+	if gq.Occupancy != 60 {
+		t.Errorf("expected occupancy 60, but got %v", gq.Occupancy)
+	}
+
+	if gq.Revenue != 60000 || gq.ADR != 10000 || gq.RevPAR != 6000 {
+		t.Errorf("expected revenue 60000, ADR 10000 and RevPAR 6000, but got %d, %d and %d", gq.Revenue, gq.ADR, gq.RevPAR)
+	}
+
+	if gq.Arrivals != 1 || gq.AverageLeadTime != 10 || gq.AverageLengthOfStay != 4 {
+		t.Errorf("expected 1 arrival with lead time 10 and stay 4, but got %d, %v and %v",
+			gq.Arrivals, gq.AverageLeadTime, gq.AverageLengthOfStay)
+	}
+
+	if gq.OnTheBooks != 6 || gq.Pickup != 4 {
+		t.Errorf("expected 6 nights on the books and pickup 4, but got %d and %d", gq.OnTheBooks, gq.Pickup)
+	}
+
+	ms := report.Rooms[1]
+	if ms.RoomNightsSold != 0 || ms.Cancellations != 1 || ms.CancellationRate != 100 {
+		t.Errorf("expected no sold nights and 100%% cancellations, but got %d sold, %d cancelled at %v",
+			ms.RoomNightsSold, ms.Cancellations, ms.CancellationRate)
+	}
+
+	if ms.OnTheBooks != 2 || ms.OnTheBooksLastYear != 3 || ms.PickupLastYear != 3 {
+		t.Errorf("expected 2 nights on the books against 3 last year with pickup 3, but got %d, %d and %d",
+			ms.OnTheBooks, ms.OnTheBooksLastYear, ms.PickupLastYear)
+	}
+
+	total := report.Total
+	if total.RoomNightsAvailable != 20 || total.RoomNightsSold != 6 || total.Occupancy != 30 {
+		t.Errorf("expected total 20 available, 6 sold and occupancy 30, but got %d, %d and %v",
+			total.RoomNightsAvailable, total.RoomNightsSold, total.Occupancy)
+	}
+
+	if total.Arrivals != 2 || total.CancellationRate != 50 {
+		t.Errorf("expected 2 arrivals with 50%% cancelled, but got %d and %v", total.Arrivals, total.CancellationRate)
+	}
+}
+
+func TestBuild_Taxes(t *testing.T) {
+	rooms := []models.Room{{ID: 1, RoomName: "General's Quarters"}}
+
+	reservations := []models.Reservation{
+		//2 of 4 nights inside the range
+		{RoomID: 1, StartDate: date("2050-03-09"), EndDate: date("2050-03-13"), TotalPrice: 44000,
+			CreatedAt: date("2050-01-01"), Status: models.ReservationConfirmed,
+			Taxes: []models.ReservationTax{
+				{Name: "VAT", Amount: 6667, Inclusive: true},
+				{Name: "Tourist levy", Amount: 4000},
+			}},
+		{RoomID: 1, StartDate: date("2050-03-02"), EndDate: date("2050-03-03"), TotalPrice: 10000,
+			CreatedAt: date("2050-01-01"), Status: models.ReservationConfirmed,
+			Taxes: []models.ReservationTax{{Name: "VAT", Amount: 1667, Inclusive: true}}},
+		//cancelled stays charge no taxes
+		{RoomID: 1, StartDate: date("2050-03-04"), EndDate: date("2050-03-05"), TotalPrice: 10000,
+			CreatedAt: date("2050-01-01"), Status: models.ReservationCancelled,
+			Taxes: []models.ReservationTax{{Name: "VAT", Amount: 1667, Inclusive: true}}},
+	}
+
+	report := Build(rooms, reservations, nil, date("2050-03-01"), date("2050-03-11"), date("2050-02-20"))
+
+	if len(report.Taxes) != 2 {
+		t.Fatalf("expected 2 taxes, but got %v", report.Taxes)
+	}
+
+	if report.Taxes[0].Name != "VAT" || report.Taxes[0].Amount != 3333+1667 {
+		t.Errorf("wrong VAT total %v", report.Taxes[0])
+	}
+
+	if report.Taxes[1].Name != "Tourist levy" || report.Taxes[1].Amount != 2000 {
+		t.Errorf("wrong levy total %v", report.Taxes[1])
+	}
+
+	if report.Total.Revenue != 16666+8333 {
+		t.Errorf("expected revenue net of taxes 24999, but got %d", report.Total.Revenue)
+	}
+}
+
+func TestBuild_Extras(t *testing.T) {
+	rooms := []models.Room{{ID: 1, RoomName: "General's Quarters"}}
+
+	reservations := []models.Reservation{
+		//2 of 4 nights inside the range
+		{RoomID: 1, StartDate: date("2050-03-09"), EndDate: date("2050-03-13"), TotalPrice: 48000,
+			CreatedAt: date("2050-01-01"), Status: models.ReservationConfirmed,
+			Extras: []models.ReservationExtra{{Name: "Parking", Quantity: 1, Amount: 4000}}},
+		{RoomID: 1, StartDate: date("2050-03-02"), EndDate: date("2050-03-03"), TotalPrice: 12000,
+			CreatedAt: date("2050-01-01"), Status: models.ReservationConfirmed,
+			Extras: []models.ReservationExtra{
+				{Name: "Breakfast", Quantity: 1, Amount: 1000},
+				{Name: "Parking", Quantity: 1, Amount: 1000},
+			}},
+	}
+
+	report := Build(rooms, reservations, nil, date("2050-03-01"), date("2050-03-11"), date("2050-02-20"))
+
+	if len(report.Extras) != 2 {
+		t.Fatalf("expected 2 extras, but got %v", report.Extras)
+	}
+
+	if report.Extras[0].Name != "Parking" || report.Extras[0].Amount != 2000+1000 {
+		t.Errorf("wrong parking total %v", report.Extras[0])
+	}
+
+	if report.Extras[1].Name != "Breakfast" || report.Extras[1].Amount != 1000 {
+		t.Errorf("wrong breakfast total %v", report.Extras[1])
+	}
+
+	if report.Total.Revenue != 22000+10000 {
+		t.Errorf("expected room revenue without extras 32000, but got %d", report.Total.Revenue)
+	}
+}
+
+func TestReport_WriteCSV(t *testing.T) {
+	rooms := []models.Room{{ID: 1, RoomName: "General's Quarters"}}
+	report := Build(rooms, nil, nil, date("2050-03-01"), date("2050-03-11"), date("2050-02-20"))
+
+	var buf bytes.Buffer
+	err := report.WriteCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header, room and total lines, but got %d lines", len(lines))
+	}
+
+	if !strings.HasPrefix(lines[0], "room,room_nights_available") {
+		t.Errorf("unexpected header %s", lines[0])
+	}
+
+	if !strings.HasPrefix(lines[2], "Total,10,0,0.00,0.00") {
+		t.Errorf("unexpected total line %s", lines[2])
+	}
+}
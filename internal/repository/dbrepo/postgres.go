@@ -2,11 +2,15 @@ package dbrepo
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/repository"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -14,19 +18,77 @@ func (m *postgresDBRepo) AllUsers() bool {
 	return true
 }
 
-//InsertReservation inserts a reservation into the database
+//InsertReservation inserts a reservation with its taxes and extras into the database
 func (m *postgresDBRepo) InsertReservation(res models.Reservation) (int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	var newID int
 
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	guestID, err := matchGuest(ctx, tx, res)
+	if err != nil {
+		return 0, err
+	}
+
+	guests := res.Guests
+	if guests < 1 {
+		guests = 1
+	}
+
+	//count the redemption, unless the code was used up since it was checked
+	if res.PromoCodeID > 0 {
+		result, err := tx.ExecContext(ctx, `
+			update promo_codes set uses = uses + 1, updated_at = $1
+			where id = $2 and (max_uses = 0 or uses < max_uses)
+		`, time.Now(), res.PromoCodeID)
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		if n == 0 {
+			return 0, repository.ErrPromoCodeUsedUp
+		}
+	}
+
+	//take up the waitlist offer the reservation was booked through, unless it was used or closed meanwhile
+	if res.WaitlistEntryID > 0 {
+		result, err := tx.ExecContext(ctx, `
+			update waitlist_entries set status = $1, updated_at = $2
+			where id = $3 and status = $4
+		`, models.WaitlistBooked, time.Now(), res.WaitlistEntryID, models.WaitlistNotified)
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		if n == 0 {
+			return 0, repository.ErrWaitlistOfferUsed
+		}
+	}
+
 	stmt := `insert into reservations (first_name, last_name, email, phone, start_date,
-			end_date, room_id, created_at, updated_at)
+			end_date, room_id, total_price, created_at, updated_at, guest_id, guests,
+			promo_code_id, promo_code, discount, rate_plan_id, rate_plan)
 			values
-			($1, $2, $3, $4, $5, $6, $7, $8, $9) returning id`
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, nullif($13, 0), $14, $15, nullif($16, 0), $17)
+			returning id`
 
-	err := m.DB.QueryRowContext(ctx,
+	err = tx.QueryRowContext(ctx,
 		stmt,
 		res.FirstName,
 		res.LastName,
@@ -34,17 +96,145 @@ func (m *postgresDBRepo) InsertReservation(res models.Reservation) (int, error)
 		res.StartDate,
 		res.EndDate,
 		res.RoomID,
+		res.TotalPrice,
 		time.Now(),
 		time.Now(),
+		guestID,
+		guests,
+		res.PromoCodeID,
+		res.PromoCode,
+		res.Discount,
+		res.RatePlanID,
+		res.RatePlan,
 	).Scan(&newID)
 
 	if err != nil {
 		return 0, err
 	}
 
+	if err = insertReservationTaxes(ctx, tx, newID, res.Taxes); err != nil {
+		return 0, err
+	}
+
+	if err = insertReservationAdjustments(ctx, tx, newID, res.Adjustments); err != nil {
+		return 0, err
+	}
+
+	for _, x := range res.Extras {
+		if err = insertReservationExtra(ctx, tx, newID, res.StartDate, res.EndDate, x); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
 	return newID, nil
 }
 
+//insertReservationTaxes records the taxes charged on a reservation
+func insertReservationTaxes(ctx context.Context, tx *sql.Tx, reservationID int, taxes []models.ReservationTax) error {
+	stmt := `
+		insert into reservation_taxes (reservation_id, tax_rule_id, name, amount, inclusive, created_at, updated_at)
+		values ($1, nullif($2, 0), $3, $4, $5, $6, $7)
+	`
+
+	for _, t := range taxes {
+		_, err := tx.ExecContext(ctx, stmt, reservationID, t.TaxRuleID, t.Name, t.Amount, t.Inclusive, time.Now(), time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//insertReservationAdjustments records the adjustments price rules made to the price of a reservation
+func insertReservationAdjustments(ctx context.Context, tx *sql.Tx, reservationID int,
+	adjustments []models.PriceAdjustment) error {
+	stmt := `
+		insert into reservation_adjustments (reservation_id, price_rule_id, name, amount, created_at, updated_at)
+		values ($1, nullif($2, 0), $3, $4, $5, $6)
+	`
+
+	for _, a := range adjustments {
+		_, err := tx.ExecContext(ctx, stmt, reservationID, a.PriceRuleID, a.Name, a.Amount, time.Now(), time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//loadReservationAdjustments fills in the price adjustments of one reservation
+func (m *postgresDBRepo) loadReservationAdjustments(ctx context.Context, res *models.Reservation) error {
+	query := `
+		select coalesce(price_rule_id, 0), name, amount from reservation_adjustments
+		where reservation_id = $1 order by id
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, res.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.PriceAdjustment
+		if err := rows.Scan(&a.PriceRuleID, &a.Name, &a.Amount); err != nil {
+			return err
+		}
+		res.Adjustments = append(res.Adjustments, a)
+	}
+
+	return rows.Err()
+}
+
+//loadReservationTaxes fills in the taxes of reservations, selecting them with a condition on the reservations
+//table aliased r
+func (m *postgresDBRepo) loadReservationTaxes(ctx context.Context, reservations []models.Reservation, condition string,
+	args ...interface{}) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(reservations))
+	for i, r := range reservations {
+		index[r.ID] = i
+	}
+
+	query := `
+		select t.reservation_id, coalesce(t.tax_rule_id, 0), t.name, t.amount, t.inclusive
+		from reservation_taxes t
+		join reservations r on (t.reservation_id = r.id)
+		where ` + condition + `
+		order by t.id
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reservationID int
+		var t models.ReservationTax
+		err := rows.Scan(&reservationID, &t.TaxRuleID, &t.Name, &t.Amount, &t.Inclusive)
+		if err != nil {
+			return err
+		}
+
+		if i, ok := index[reservationID]; ok {
+			reservations[i].Taxes = append(reservations[i].Taxes, t)
+		}
+	}
+
+	return rows.Err()
+}
+
 //InsertRoomRestriction inserts a room restriction into the database
 func (m *postgresDBRepo) InsertRoomRestriction(r models.RoomRestriction) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -80,15 +270,18 @@ func (m *postgresDBRepo) SearchAvailabilityByDatesByRoomID(start, end time.Time,
 
 	var numRows int
 
+	//an out of order room counts as restricted
 	query := `select
-				count(id)
-			from
-				room_restrictions
-			where
-				room_id = $1
-				and $2 <= end_date and $3 >= start_date;`
+				(select count(id)
+				from
+					room_restrictions
+				where
+					room_id = $1
+					and $2 <= end_date and $3 >= start_date
+					and (expires_at is null or expires_at > $4))
+				+ (select count(id) from rooms where id = $1 and housekeeping_status = $5);`
 
-	row := m.DB.QueryRowContext(ctx, query, roomID, start, end)
+	row := m.DB.QueryRowContext(ctx, query, roomID, start, end, time.Now(), models.RoomOutOfOrder)
 	err := row.Scan(&numRows)
 	if err != nil {
 		return false, err
@@ -109,14 +302,15 @@ func (m *postgresDBRepo) SearchAvailabilityForAllRooms(start, end time.Time) ([]
 	var rooms []models.Room
 	query := `
 			select
-				r.id, r.room_name
+				r.id, r.room_name, r.price
 			from
 				rooms r
-			where r.id not in
-			(select room_id from room_restrictions rr where $1 <= rr.end_date and $2 >= rr.start_date);
+			where r.housekeeping_status <> $4 and r.id not in
+			(select room_id from room_restrictions rr where $1 <= rr.end_date and $2 >= rr.start_date
+			and (rr.expires_at is null or rr.expires_at > $3));
 			`
 
-	rows, err := m.DB.QueryContext(ctx, query, start, end)
+	rows, err := m.DB.QueryContext(ctx, query, start, end, time.Now(), models.RoomOutOfOrder)
 	if err != nil {
 		return rooms, err
 	}
@@ -126,6 +320,7 @@ func (m *postgresDBRepo) SearchAvailabilityForAllRooms(start, end time.Time) ([]
 		err := rows.Scan(
 			&room.ID,
 			&room.RoomName,
+			&room.Price,
 		)
 
 		if err != nil {
@@ -150,13 +345,17 @@ func (m *postgresDBRepo) GetRoomByID(id int) (models.Room, error) {
 	var room models.Room
 
 	query := `
-		select id, room_name, created_at, updated_at from rooms where id = $1
+		select id, room_name, price, coalesce(cancellation_policy_id, 0), housekeeping_status, created_at, updated_at
+		from rooms where id = $1
 	`
 
 	row := m.DB.QueryRowContext(ctx, query, id)
 	err := row.Scan(
 		&room.ID,
 		&room.RoomName,
+		&room.Price,
+		&room.CancellationPolicyID,
+		&room.HousekeepingStatus,
 		&room.CreatedAt,
 		&room.UpdatedAt,
 	)
@@ -247,84 +446,114 @@ func (m *postgresDBRepo) Authenticate(email, testPassword string) (int, string,
 	return id, hashedPassword, nil
 }
 
-//AllReservations returns a slice of all reservations
-func (m *postgresDBRepo) AllReservations() ([]models.Reservation, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	var reservations []models.Reservation
+//reservationFilterWhere is the where clause matching the arguments of reservationFilterArgs
+const reservationFilterWhere = `
+		where ($1::date is null or r.end_date > $1)
+		and ($2::date is null or r.start_date < $2)
+		and ($3 = 0 or r.room_id = $3)
+		and ($4 = '' or r.status = $4)
+		and ($5 = 0 or ($5 = 1 and r.processed = 0) or ($5 = 2 and r.processed = 1))
+		and ($6 = '' or r.first_name || ' ' || r.last_name ilike $6 or r.email ilike $6 or r.phone ilike $6)
+		and ($7 = '' or exists (select 1 from reservation_tags t where t.reservation_id = r.id and lower(t.tag) = lower($7)))
+`
 
-	query := `
-		select r.id, r.first_name, r.last_name, r.email, r.phone, r.start_date,
-		r.end_date, r.room_id, r.created_at, r.updated_at, r.processed,
-		rm.id, rm.room_name
-		from reservations r
-		left join rooms rm on (r.room_id = rm.id)
-		order by r.start_date asc
-	`
+//reservationFilterArgs returns the query arguments of a reservation filter
+func reservationFilterArgs(f models.ReservationFilter) []interface{} {
+	var start, end sql.NullTime
+	if !f.Start.IsZero() {
+		start = sql.NullTime{Time: f.Start, Valid: true}
+	}
+	if !f.End.IsZero() {
+		end = sql.NullTime{Time: f.End, Valid: true}
+	}
 
-	rows, err := m.DB.QueryContext(ctx, query)
-	if err != nil {
-		return reservations, err
+	search := ""
+	if f.Search != "" {
+		search = "%" + likeEscaper.Replace(f.Search) + "%"
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var i models.Reservation
-		err := rows.Scan(
-			&i.ID,
-			&i.FirstName,
-			&i.LastName,
-			&i.Email,
-			&i.Phone,
-			&i.StartDate,
-			&i.EndDate,
-			&i.RoomID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.Processed,
-			&i.Room.ID,
-			&i.Room.RoomName,
-		)
+	return []interface{}{start, end, f.RoomID, f.Status, f.Processed, search, f.Tag}
+}
 
-		if err != nil {
-			return reservations, err
-		}
-		reservations = append(reservations, i)
-	}
+//likeEscaper escapes the wildcards of a pattern for ilike
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
 
-	if err = rows.Err(); err != nil {
-		return reservations, err
+//reservationSortColumns maps the columns a reservation list can be sorted by to their order by expression
+var reservationSortColumns = map[string]string{
+	"id":         "r.id",
+	"last_name":  "r.last_name",
+	"room":       "rm.room_name",
+	"start_date": "r.start_date",
+	"end_date":   "r.end_date",
+	"status":     "r.status",
+	"created_at": "r.created_at",
+}
+
+//reservationTagsColumn selects the tags of a reservation r joined by commas
+const reservationTagsColumn = `
+		coalesce((select string_agg(t.tag, ',' order by t.tag) from reservation_tags t where t.reservation_id = r.id), '')`
+
+//splitTags splits tags joined by reservationTagsColumn
+func splitTags(s string) []string {
+	if s == "" {
+		return nil
 	}
 
-	return reservations, nil
+	return strings.Split(s, ",")
 }
 
-//AllNewReservations returns a slice of all reservations
-func (m *postgresDBRepo) AllNewReservations() ([]models.Reservation, error) {
+//SearchReservations returns a page of the reservations matching the filter and how many match in total.
+//Unknown sort columns sort by arrival
+func (m *postgresDBRepo) SearchReservations(f models.ReservationFilter, p models.Page) ([]models.Reservation, int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	var reservations []models.Reservation
+	var total int
+
+	args := reservationFilterArgs(f)
+
+	err := m.DB.QueryRowContext(ctx, `select count(*) from reservations r`+reservationFilterWhere, args...).Scan(&total)
+	if err != nil {
+		return reservations, 0, err
+	}
+
+	column, ok := reservationSortColumns[p.Sort]
+	if !ok {
+		column = "r.start_date"
+	}
+
+	direction := "asc"
+	if p.Desc {
+		direction = "desc"
+	}
+
+	if p.Number < 1 {
+		p.Number = 1
+	}
 
 	query := `
 		select r.id, r.first_name, r.last_name, r.email, r.phone, r.start_date,
-		r.end_date, r.room_id, r.created_at, r.updated_at, 
-		rm.id, rm.room_name
+		r.end_date, r.room_id, r.created_at, r.updated_at, r.processed,
+		r.total_price, r.status, r.cancellation_fee,
+		rm.id, rm.room_name, ` + reservationTagsColumn + `
 		from reservations r
-		left join rooms rm on (r.room_id = rm.id)
-		where processed = 0
-		order by r.start_date asc
+		left join rooms rm on (r.room_id = rm.id)` + reservationFilterWhere + `
+		order by ` + column + ` ` + direction + `, r.id ` + direction + `
+		limit $8 offset $9
 	`
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	args = append(args, p.Size, (p.Number-1)*p.Size)
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return reservations, err
+		return reservations, 0, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var i models.Reservation
+		var tags string
 		err := rows.Scan(
 			&i.ID,
 			&i.FirstName,
@@ -336,21 +565,27 @@ func (m *postgresDBRepo) AllNewReservations() ([]models.Reservation, error) {
 			&i.RoomID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Processed,
+			&i.TotalPrice,
+			&i.Status,
+			&i.CancellationFee,
 			&i.Room.ID,
 			&i.Room.RoomName,
+			&tags,
 		)
 
 		if err != nil {
-			return reservations, err
+			return reservations, 0, err
 		}
+		i.Tags = splitTags(tags)
 		reservations = append(reservations, i)
 	}
 
 	if err = rows.Err(); err != nil {
-		return reservations, err
+		return reservations, 0, err
 	}
 
-	return reservations, nil
+	return reservations, total, nil
 }
 
 //GetReservationById returns one reservatin by id
@@ -359,11 +594,15 @@ func (m *postgresDBRepo) GetReservationByID(id int) (models.Reservation, error)
 	defer cancel()
 
 	var res models.Reservation
+	var cancelledAt, checkedInAt, checkedOutAt sql.NullTime
+	var tags string
 
 	query := `
 		select r.id, r.first_name, r.last_name, r.email, r.phone, r.start_date,
 		r.end_date, r.room_id, r.created_at, r.updated_at, r.processed,
-		rm.id, rm.room_name
+		r.total_price, r.status, r.cancelled_at, r.cancellation_fee, coalesce(r.guest_id, 0),
+		r.checked_in_at, r.checked_out_at, r.guests, coalesce(r.promo_code_id, 0), r.promo_code, r.discount,
+		coalesce(r.rate_plan_id, 0), r.rate_plan, rm.id, rm.room_name, rm.price, coalesce(rm.cancellation_policy_id, 0), ` + reservationTagsColumn + `
 		from reservations r
 		left join rooms rm on (r.room_id = rm.id)
 		where r.id = $1
@@ -381,15 +620,49 @@ func (m *postgresDBRepo) GetReservationByID(id int) (models.Reservation, error)
 		&res.CreatedAt,
 		&res.UpdatedAt,
 		&res.Processed,
+		&res.TotalPrice,
+		&res.Status,
+		&cancelledAt,
+		&res.CancellationFee,
+		&res.GuestID,
+		&checkedInAt,
+		&checkedOutAt,
+		&res.Guests,
+		&res.PromoCodeID,
+		&res.PromoCode,
+		&res.Discount,
+		&res.RatePlanID,
+		&res.RatePlan,
 		&res.Room.ID,
 		&res.Room.RoomName,
+		&res.Room.Price,
+		&res.Room.CancellationPolicyID,
+		&tags,
 	)
 
 	if err != nil {
 		return res, err
 	}
 
-	return res, nil
+	res.CancelledAt = cancelledAt.Time
+	res.CheckedInAt = checkedInAt.Time
+	res.CheckedOutAt = checkedOutAt.Time
+	res.Tags = splitTags(tags)
+
+	reservations := []models.Reservation{res}
+	if err = m.loadReservationTaxes(ctx, reservations, "r.id = $1", id); err != nil {
+		return res, err
+	}
+
+	if err = m.loadReservationAdjustments(ctx, &reservations[0]); err != nil {
+		return res, err
+	}
+
+	if err = m.loadReservationExtras(ctx, reservations, "r.id = $1", id); err != nil {
+		return res, err
+	}
+
+	return reservations[0], nil
 }
 
 //UpdateReservation updates a reservation in the database
@@ -461,7 +734,10 @@ func (m *postgresDBRepo) AllRooms() ([]models.Room, error) {
 
 	var rooms []models.Room
 
-	query := `select id, room_name, created_at, updated_at from rooms order by room_name`
+	query := `
+		select id, room_name, price, coalesce(cancellation_policy_id, 0), housekeeping_status, created_at, updated_at
+		from rooms order by room_name
+	`
 
 	rows, err := m.DB.QueryContext(ctx, query)
 	if err != nil {
@@ -474,6 +750,9 @@ func (m *postgresDBRepo) AllRooms() ([]models.Room, error) {
 		err := rows.Scan(
 			&rm.ID,
 			&rm.RoomName,
+			&rm.Price,
+			&rm.CancellationPolicyID,
+			&rm.HousekeepingStatus,
 			&rm.CreatedAt,
 			&rm.UpdatedAt,
 		)
@@ -500,10 +779,10 @@ func (m *postgresDBRepo) GetRestrictionsForRoomByDate(roomID int, start, end tim
 	query := `
 		select id, coalesce(reservation_id, 0), restriction_id, room_id, start_date, end_date
 		from room_restrictions where $1 < end_date and $2 >= start_date
-		and room_id = $3
+		and room_id = $3 and (expires_at is null or expires_at > $4)
 	`
 
-	rows, err := m.DB.QueryContext(ctx, query, start, end, roomID)
+	rows, err := m.DB.QueryContext(ctx, query, start, end, roomID, time.Now())
 	if err != nil {
 		return nil, err
 	}
@@ -569,3 +848,3736 @@ func (m *postgresDBRepo) DeleteBlockByID(id int) error {
 
 	return nil
 }
+
+//AvailabilityCalendarByRoomID returns availability and price for every night from start up to, but not including, end
+func (m *postgresDBRepo) AvailabilityCalendarByRoomID(roomID int, start, end time.Time) ([]models.NightAvailability, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var nights []models.NightAvailability
+
+	//an out of order room is not available on any night, the same as in the searches
+	query := `
+		select n.night::date, count(rr.id) = 0 and rm.housekeeping_status <> $5, rm.price
+		from rooms rm
+		cross join generate_series($2::timestamp, $3::timestamp - interval '1 day', interval '1 day') as n(night)
+		left join room_restrictions rr
+			on (rr.room_id = rm.id and n.night::date >= rr.start_date and n.night::date <= rr.end_date
+			and (rr.expires_at is null or rr.expires_at > $4))
+		where rm.id = $1
+		group by n.night, rm.price, rm.housekeeping_status
+		order by n.night asc
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, roomID, start, end, time.Now(), models.RoomOutOfOrder)
+	if err != nil {
+		return nights, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n models.NightAvailability
+		err := rows.Scan(
+			&n.Date,
+			&n.Available,
+			&n.Price,
+		)
+		if err != nil {
+			return nights, err
+		}
+		nights = append(nights, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nights, err
+	}
+
+	return nights, nil
+}
+
+//CancelReservation marks a reservation as cancelled with a fee and frees its room restrictions
+func (m *postgresDBRepo) CancelReservation(id, fee int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		update reservations set status = $1, cancelled_at = $2, cancellation_fee = $3, updated_at = $4
+		where id = $5 and status <> $1
+	`
+
+	result, err := tx.ExecContext(ctx, query, models.ReservationCancelled, time.Now(), fee, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return errors.New("reservation does not exist or is already cancelled")
+	}
+
+	_, err = tx.ExecContext(ctx, "delete from room_restrictions where reservation_id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+//AllCancellationPolicies returns a slice of all cancellation policies
+func (m *postgresDBRepo) AllCancellationPolicies() ([]models.CancellationPolicy, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var policies []models.CancellationPolicy
+
+	query := `
+		select id, name, free_days_before, fee_type, fee_percent, created_at, updated_at
+		from cancellation_policies order by name
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return policies, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.CancellationPolicy
+		err := rows.Scan(
+			&p.ID,
+			&p.Name,
+			&p.FreeDaysBefore,
+			&p.FeeType,
+			&p.FeePercent,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+		)
+		if err != nil {
+			return policies, err
+		}
+		policies = append(policies, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return policies, err
+	}
+
+	return policies, nil
+}
+
+//GetCancellationPolicyByID returns one cancellation policy by id
+func (m *postgresDBRepo) GetCancellationPolicyByID(id int) (models.CancellationPolicy, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var p models.CancellationPolicy
+
+	query := `
+		select id, name, free_days_before, fee_type, fee_percent, created_at, updated_at
+		from cancellation_policies where id = $1
+	`
+
+	row := m.DB.QueryRowContext(ctx, query, id)
+	err := row.Scan(
+		&p.ID,
+		&p.Name,
+		&p.FreeDaysBefore,
+		&p.FeeType,
+		&p.FeePercent,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+
+	if err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
+
+//InsertCancellationPolicy inserts a cancellation policy into the database
+func (m *postgresDBRepo) InsertCancellationPolicy(p models.CancellationPolicy) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var newID int
+
+	stmt := `
+		insert into cancellation_policies (name, free_days_before, fee_type, fee_percent, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6) returning id
+	`
+
+	err := m.DB.QueryRowContext(ctx,
+		stmt,
+		p.Name,
+		p.FreeDaysBefore,
+		p.FeeType,
+		p.FeePercent,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+//UpdateCancellationPolicy updates a cancellation policy in the database
+func (m *postgresDBRepo) UpdateCancellationPolicy(p models.CancellationPolicy) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		update cancellation_policies set name = $1, free_days_before = $2, fee_type = $3, fee_percent = $4,
+		updated_at = $5
+		where id = $6
+	`
+
+	_, err := m.DB.ExecContext(ctx, query, p.Name, p.FreeDaysBefore, p.FeeType, p.FeePercent, time.Now(), p.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//DeleteCancellationPolicy deletes one cancellation policy by id
+func (m *postgresDBRepo) DeleteCancellationPolicy(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "delete from cancellation_policies where id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//UpdateCancellationPolicyForRoom sets the cancellation policy of a room, zero policyID removes it
+func (m *postgresDBRepo) UpdateCancellationPolicyForRoom(roomID, policyID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		update rooms set cancellation_policy_id = nullif($1, 0), updated_at = $2 where id = $3
+	`
+
+	_, err := m.DB.ExecContext(ctx, query, policyID, time.Now(), roomID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//ChangeReservationStay moves a reservation and its room restriction to new dates and room, returns false if
+//the room is not available for them, ignoring the reservation itself. ErrExtraSoldOut is returned when not
+//enough of an extra booked is left for the new nights
+func (m *postgresDBRepo) ChangeReservationStay(res models.Reservation) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	//lock the room so concurrent changes can't claim the same nights
+	var status string
+	err = tx.QueryRowContext(ctx, "select housekeeping_status from rooms where id = $1 for update", res.RoomID).Scan(&status)
+	if err != nil {
+		return false, err
+	}
+
+	if status == models.RoomOutOfOrder {
+		return false, nil
+	}
+
+	var numRows int
+
+	query := `
+		select count(id) from room_restrictions
+		where room_id = $1 and $2 <= end_date and $3 >= start_date
+		and coalesce(reservation_id, 0) <> $4 and (expires_at is null or expires_at > $5)
+	`
+
+	err = tx.QueryRowContext(ctx, query, res.RoomID, res.StartDate, res.EndDate, res.ID, time.Now()).Scan(&numRows)
+	if err != nil {
+		return false, err
+	}
+
+	if numRows > 0 {
+		return false, nil
+	}
+
+	query = `
+		update reservations set start_date = $1, end_date = $2, room_id = $3, total_price = $4, discount = $5,
+		rate_plan_id = nullif($6, 0), rate_plan = $7, updated_at = $8
+		where id = $9
+	`
+
+	_, err = tx.ExecContext(ctx, query, res.StartDate, res.EndDate, res.RoomID, res.TotalPrice, res.Discount,
+		res.RatePlanID, res.RatePlan, time.Now(), res.ID)
+	if err != nil {
+		return false, err
+	}
+
+	query = `
+		update room_restrictions set start_date = $1, end_date = $2, room_id = $3, updated_at = $4
+		where reservation_id = $5
+	`
+
+	_, err = tx.ExecContext(ctx, query, res.StartDate, res.EndDate, res.RoomID, time.Now(), res.ID)
+	if err != nil {
+		return false, err
+	}
+
+	//the taxes and adjustments follow the new price
+	_, err = tx.ExecContext(ctx, "delete from reservation_taxes where reservation_id = $1", res.ID)
+	if err != nil {
+		return false, err
+	}
+
+	if err = insertReservationTaxes(ctx, tx, res.ID, res.Taxes); err != nil {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, "delete from reservation_adjustments where reservation_id = $1", res.ID)
+	if err != nil {
+		return false, err
+	}
+
+	if err = insertReservationAdjustments(ctx, tx, res.ID, res.Adjustments); err != nil {
+		return false, err
+	}
+
+	//the extras are booked again for the new nights, so their inventory is checked without counting them twice
+	_, err = tx.ExecContext(ctx, "delete from reservation_extras where reservation_id = $1", res.ID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, x := range res.Extras {
+		if err = insertReservationExtra(ctx, tx, res.ID, res.StartDate, res.EndDate, x); err != nil {
+			return false, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+//InsertHold places a temporary hold on a room for the restriction dates, returns false if the room is not
+//available for them
+func (m *postgresDBRepo) InsertHold(r models.RoomRestriction) (int, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	//lock the room so two guests can't hold the same nights
+	var status string
+	err = tx.QueryRowContext(ctx, "select housekeeping_status from rooms where id = $1 for update", r.RoomID).Scan(&status)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if status == models.RoomOutOfOrder {
+		return 0, false, nil
+	}
+
+	var numRows int
+
+	query := `
+		select count(id) from room_restrictions
+		where room_id = $1 and $2 <= end_date and $3 >= start_date
+		and (expires_at is null or expires_at > $4)
+	`
+
+	err = tx.QueryRowContext(ctx, query, r.RoomID, r.StartDate, r.EndDate, time.Now()).Scan(&numRows)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if numRows > 0 {
+		return 0, false, nil
+	}
+
+	var newID int
+
+	stmt := `
+		insert into room_restrictions (start_date, end_date, room_id, restriction_id, expires_at,
+		created_at, updated_at) values ($1, $2, $3, $4, $5, $6, $7) returning id
+	`
+
+	err = tx.QueryRowContext(ctx,
+		stmt,
+		r.StartDate,
+		r.EndDate,
+		r.RoomID,
+		models.RestrictionHold,
+		r.ExpiresAt,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, false, err
+	}
+
+	return newID, true, nil
+}
+
+//HoldIsActive returns true if a hold exists, has not expired yet and is on the room and dates of r
+func (m *postgresDBRepo) HoldIsActive(id int, r models.RoomRestriction) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var numRows int
+
+	query := `
+		select count(id) from room_restrictions
+		where id = $1 and restriction_id = $2 and expires_at > $3
+			and room_id = $4 and start_date = $5 and end_date = $6
+	`
+
+	err := m.DB.QueryRowContext(ctx, query, id, models.RestrictionHold, time.Now(), r.RoomID, r.StartDate,
+		r.EndDate).Scan(&numRows)
+	if err != nil {
+		return false, err
+	}
+
+	return numRows > 0, nil
+}
+
+//DeleteHoldByID releases a hold
+func (m *postgresDBRepo) DeleteHoldByID(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		delete from room_restrictions where id = $1 and restriction_id = $2
+	`
+
+	_, err := m.DB.ExecContext(ctx, query, id, models.RestrictionHold)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//DeleteExpiredHolds releases all expired holds and returns how many were released
+func (m *postgresDBRepo) DeleteExpiredHolds() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		delete from room_restrictions where restriction_id = $1 and expires_at <= $2
+	`
+
+	result, err := m.DB.ExecContext(ctx, query, models.RestrictionHold, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+//InsertWaitlistEntry puts a guest on the waitlist
+func (m *postgresDBRepo) InsertWaitlistEntry(e models.WaitlistEntry) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var newID int
+
+	stmt := `
+		insert into waitlist_entries (first_name, last_name, email, room_id, start_date, end_date,
+			status, created_at, updated_at)
+		values ($1, $2, $3, nullif($4, 0), $5, $6, $7, $8, $9) returning id
+	`
+
+	err := m.DB.QueryRowContext(ctx, stmt,
+		e.FirstName,
+		e.LastName,
+		e.Email,
+		e.RoomID,
+		e.StartDate,
+		e.EndDate,
+		models.WaitlistWaiting,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+//GetWaitlistEntryByID returns one waitlist entry by id
+func (m *postgresDBRepo) GetWaitlistEntryByID(id int) (models.WaitlistEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var e models.WaitlistEntry
+	var notifiedAt, offerExpiresAt sql.NullTime
+
+	query := `
+		select w.id, w.first_name, w.last_name, w.email, coalesce(w.room_id, 0), w.start_date, w.end_date,
+		w.status, w.notified_at, coalesce(w.offered_room_id, 0), w.offer_expires_at, w.created_at, w.updated_at,
+		coalesce(rm.room_name, '')
+		from waitlist_entries w
+		left join rooms rm on (w.room_id = rm.id)
+		where w.id = $1
+	`
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&e.ID,
+		&e.FirstName,
+		&e.LastName,
+		&e.Email,
+		&e.RoomID,
+		&e.StartDate,
+		&e.EndDate,
+		&e.Status,
+		&notifiedAt,
+		&e.OfferedRoomID,
+		&offerExpiresAt,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+		&e.Room.RoomName,
+	)
+	if err != nil {
+		return e, err
+	}
+
+	e.NotifiedAt = notifiedAt.Time
+	e.OfferExpiresAt = offerExpiresAt.Time
+	e.Room.ID = e.RoomID
+
+	return e, nil
+}
+
+//AllWaitlistEntries returns all waitlist entries in the order guests joined
+func (m *postgresDBRepo) AllWaitlistEntries() ([]models.WaitlistEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select w.id, w.first_name, w.last_name, w.email, coalesce(w.room_id, 0), w.start_date, w.end_date,
+		w.status, w.notified_at, coalesce(w.offered_room_id, 0), w.offer_expires_at, w.created_at, w.updated_at,
+		coalesce(rm.room_name, '')
+		from waitlist_entries w
+		left join rooms rm on (w.room_id = rm.id)
+		order by w.created_at, w.id
+	`
+
+	return m.waitlistEntries(ctx, query)
+}
+
+//WaitingEntriesForRoom returns the entries still waiting for a room, or for any room, whose stay overlaps
+//the given dates, in the order guests joined
+func (m *postgresDBRepo) WaitingEntriesForRoom(roomID int, start, end time.Time) ([]models.WaitlistEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select w.id, w.first_name, w.last_name, w.email, coalesce(w.room_id, 0), w.start_date, w.end_date,
+		w.status, w.notified_at, coalesce(w.offered_room_id, 0), w.offer_expires_at, w.created_at, w.updated_at,
+		coalesce(rm.room_name, '')
+		from waitlist_entries w
+		left join rooms rm on (w.room_id = rm.id)
+		where w.status = $1 and (w.room_id = $2 or w.room_id is null)
+		and $3 <= w.end_date and $4 >= w.start_date
+		order by w.created_at, w.id
+	`
+
+	return m.waitlistEntries(ctx, query, models.WaitlistWaiting, roomID, start, end)
+}
+
+//waitlistEntries runs a query selecting waitlist entries joined with their room name
+func (m *postgresDBRepo) waitlistEntries(ctx context.Context, query string, args ...interface{}) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return entries, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.WaitlistEntry
+		var notifiedAt, offerExpiresAt sql.NullTime
+		err := rows.Scan(
+			&e.ID,
+			&e.FirstName,
+			&e.LastName,
+			&e.Email,
+			&e.RoomID,
+			&e.StartDate,
+			&e.EndDate,
+			&e.Status,
+			&notifiedAt,
+			&e.OfferedRoomID,
+			&offerExpiresAt,
+			&e.CreatedAt,
+			&e.UpdatedAt,
+			&e.Room.RoomName,
+		)
+		if err != nil {
+			return entries, err
+		}
+		e.NotifiedAt = notifiedAt.Time
+		e.OfferExpiresAt = offerExpiresAt.Time
+		e.Room.ID = e.RoomID
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return entries, err
+	}
+
+	return entries, nil
+}
+
+//MarkWaitlistEntryNotified records that a guest has been sent a booking link for a room, valid until expires
+func (m *postgresDBRepo) MarkWaitlistEntryNotified(id, roomID int, expires time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		update waitlist_entries set status = $1, notified_at = $2, offered_room_id = $3, offer_expires_at = $4,
+		updated_at = $2
+		where id = $5
+	`
+
+	_, err := m.DB.ExecContext(ctx, query, models.WaitlistNotified, time.Now(), roomID, expires, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//DeclineWaitlistOffer records that a guest turned down the room offered, returns false if the offer wasn't
+//open anymore
+func (m *postgresDBRepo) DeclineWaitlistOffer(id int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		update waitlist_entries set status = $1, updated_at = $2
+		where id = $3 and status = $4
+	`
+
+	result, err := m.DB.ExecContext(ctx, query, models.WaitlistDeclined, time.Now(), id, models.WaitlistNotified)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+//ExpireWaitlistOffers closes the offers whose booking link has expired and returns their entries, so the
+//rooms can be offered to the next guests in line
+func (m *postgresDBRepo) ExpireWaitlistOffers() ([]models.WaitlistEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		with expired as (
+			update waitlist_entries set status = $1, updated_at = $2
+			where status = $3 and offer_expires_at <= $2
+			returning *
+		)
+		select w.id, w.first_name, w.last_name, w.email, coalesce(w.room_id, 0), w.start_date, w.end_date,
+		w.status, w.notified_at, coalesce(w.offered_room_id, 0), w.offer_expires_at, w.created_at, w.updated_at,
+		coalesce(rm.room_name, '')
+		from expired w
+		left join rooms rm on (w.room_id = rm.id)
+		order by w.created_at, w.id
+	`
+
+	return m.waitlistEntries(ctx, query, models.WaitlistExpired, time.Now(), models.WaitlistNotified)
+}
+
+//DeleteWaitlistEntry removes a guest from the waitlist
+func (m *postgresDBRepo) DeleteWaitlistEntry(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		delete from waitlist_entries where id = $1
+	`
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//DashboardCounts returns the arrivals, departures and in-house reservations of a day, together with the
+//number of new reservations
+func (m *postgresDBRepo) DashboardCounts(day time.Time) (models.DashboardCounts, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var c models.DashboardCounts
+
+	query := `
+		select
+			count(id) filter (where start_date = $1::date),
+			count(id) filter (where end_date = $1::date),
+			count(id) filter (where start_date <= $1::date and end_date > $1::date),
+			count(id) filter (where processed = 0)
+		from reservations
+		where status <> $2
+	`
+
+	err := m.DB.QueryRowContext(ctx, query, day, models.ReservationCancelled).Scan(
+		&c.Arrivals,
+		&c.Departures,
+		&c.InHouse,
+		&c.NewReservations,
+	)
+	if err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+//OccupiedRoomsByNight returns the number of rooms taken by reservations for every night from start up to end
+func (m *postgresDBRepo) OccupiedRoomsByNight(start, end time.Time) ([]models.DailyCount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select n.night::date, count(r.id)
+		from generate_series($1::timestamp, $2::timestamp - interval '1 day', interval '1 day') as n(night)
+		left join reservations r
+			on (r.start_date <= n.night::date and r.end_date > n.night::date and r.status <> $3)
+		group by n.night
+		order by n.night asc
+	`
+
+	return m.dailyCounts(ctx, query, start, end, models.ReservationCancelled)
+}
+
+//ReservationsCreatedByDay returns the number of reservations made on every day from start up to end
+func (m *postgresDBRepo) ReservationsCreatedByDay(start, end time.Time) ([]models.DailyCount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select d.day::date, count(r.id)
+		from generate_series($1::timestamp, $2::timestamp - interval '1 day', interval '1 day') as d(day)
+		left join reservations r on (r.created_at::date = d.day::date)
+		group by d.day
+		order by d.day asc
+	`
+
+	return m.dailyCounts(ctx, query, start, end)
+}
+
+//dailyCounts runs a query selecting a date and a count per row
+func (m *postgresDBRepo) dailyCounts(ctx context.Context, query string, args ...interface{}) ([]models.DailyCount, error) {
+	var counts []models.DailyCount
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return counts, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.DailyCount
+		err := rows.Scan(
+			&c.Date,
+			&c.Count,
+		)
+		if err != nil {
+			return counts, err
+		}
+		counts = append(counts, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return counts, err
+	}
+
+	return counts, nil
+}
+
+//ReservationsForReport returns all reservations, cancelled ones included, staying any night from start up to end
+func (m *postgresDBRepo) ReservationsForReport(start, end time.Time) ([]models.Reservation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var reservations []models.Reservation
+
+	query := `
+		select r.id, r.start_date, r.end_date, r.room_id, r.created_at, r.total_price, r.status,
+		r.cancelled_at, rm.id, rm.room_name
+		from reservations r
+		left join rooms rm on (r.room_id = rm.id)
+		where r.start_date < $2 and r.end_date > $1
+		order by r.start_date asc
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, start, end)
+	if err != nil {
+		return reservations, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i models.Reservation
+		var cancelledAt sql.NullTime
+		err := rows.Scan(
+			&i.ID,
+			&i.StartDate,
+			&i.EndDate,
+			&i.RoomID,
+			&i.CreatedAt,
+			&i.TotalPrice,
+			&i.Status,
+			&cancelledAt,
+			&i.Room.ID,
+			&i.Room.RoomName,
+		)
+		if err != nil {
+			return reservations, err
+		}
+		i.CancelledAt = cancelledAt.Time
+		reservations = append(reservations, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		return reservations, err
+	}
+
+	err = m.loadReservationTaxes(ctx, reservations, "r.start_date < $2 and r.end_date > $1", start, end)
+	if err != nil {
+		return reservations, err
+	}
+
+	err = m.loadReservationExtras(ctx, reservations, "r.start_date < $2 and r.end_date > $1", start, end)
+	if err != nil {
+		return reservations, err
+	}
+
+	return reservations, nil
+}
+
+//bulkTimeout bounds exports and imports going over many rows, which take longer than the usual 3 seconds
+const bulkTimeout = 5 * time.Minute
+
+//EachReservation calls fn for every reservation matching the filter, reading one row at a time,
+//and stops at the first error returned by fn
+func (m *postgresDBRepo) EachReservation(f models.ReservationFilter, fn func(models.Reservation) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), bulkTimeout)
+	defer cancel()
+
+	query := `
+		select r.id, r.first_name, r.last_name, r.email, r.phone, r.start_date,
+		r.end_date, r.room_id, r.created_at, r.updated_at, r.processed,
+		r.total_price, r.status, r.cancellation_fee,
+		rm.id, rm.room_name
+		from reservations r
+		left join rooms rm on (r.room_id = rm.id)` + reservationFilterWhere + `
+		order by r.start_date asc, r.id asc
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, reservationFilterArgs(f)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i models.Reservation
+		err := rows.Scan(
+			&i.ID,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.Phone,
+			&i.StartDate,
+			&i.EndDate,
+			&i.RoomID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Processed,
+			&i.TotalPrice,
+			&i.Status,
+			&i.CancellationFee,
+			&i.Room.ID,
+			&i.Room.RoomName,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = fn(i)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+//EachGuestContact calls fn for every guest, identified by email, with the contact details of the latest
+//reservation, reading one row at a time, and stops at the first error returned by fn
+func (m *postgresDBRepo) EachGuestContact(fn func(models.GuestContact) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), bulkTimeout)
+	defer cancel()
+
+	query := `
+		select
+			(array_agg(first_name order by created_at desc))[1],
+			(array_agg(last_name order by created_at desc))[1],
+			(array_agg(email order by created_at desc))[1],
+			(array_agg(phone order by created_at desc))[1],
+			count(id), max(end_date)
+		from reservations
+		group by lower(email)
+		order by lower(email)
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var g models.GuestContact
+		err := rows.Scan(
+			&g.FirstName,
+			&g.LastName,
+			&g.Email,
+			&g.Phone,
+			&g.Reservations,
+			&g.LastStay,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = fn(g)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+//ImportReservations saves reservations together with their room restrictions in a single transaction,
+//returns false and saves nothing if any of the rooms is not available for its dates
+func (m *postgresDBRepo) ImportReservations(res []models.Reservation) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), bulkTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	//lock all rooms, in a fixed order, so nothing gets booked while importing
+	_, err = tx.ExecContext(ctx, "select id from rooms order by id for update")
+	if err != nil {
+		return false, err
+	}
+
+	//an out of order room counts as restricted
+	query := `
+		select (select count(id) from room_restrictions
+		where room_id = $1 and $2 <= end_date and $3 >= start_date
+		and (expires_at is null or expires_at > $4))
+		+ (select count(id) from rooms where id = $1 and housekeeping_status = $5)
+	`
+
+	reservationStmt := `
+		insert into reservations (first_name, last_name, email, phone, start_date, end_date, room_id,
+		total_price, status, processed, created_at, updated_at, guest_id)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) returning id
+	`
+
+	restrictionStmt := `
+		insert into room_restrictions (start_date, end_date, room_id, reservation_id, restriction_id,
+		created_at, updated_at) values ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	for _, r := range res {
+		var numRows int
+
+		err = tx.QueryRowContext(ctx, query, r.RoomID, r.StartDate, r.EndDate, time.Now(), models.RoomOutOfOrder).Scan(&numRows)
+		if err != nil {
+			return false, err
+		}
+
+		if numRows > 0 {
+			return false, nil
+		}
+
+		guestID, err := matchGuest(ctx, tx, r)
+		if err != nil {
+			return false, err
+		}
+
+		var newID int
+
+		err = tx.QueryRowContext(ctx, reservationStmt,
+			r.FirstName,
+			r.LastName,
+			r.Email,
+			r.Phone,
+			r.StartDate,
+			r.EndDate,
+			r.RoomID,
+			r.TotalPrice,
+			r.Status,
+			r.Processed,
+			time.Now(),
+			time.Now(),
+			guestID,
+		).Scan(&newID)
+		if err != nil {
+			return false, err
+		}
+
+		_, err = tx.ExecContext(ctx, restrictionStmt,
+			r.StartDate,
+			r.EndDate,
+			r.RoomID,
+			newID,
+			models.RestrictionReservation,
+			time.Now(),
+			time.Now(),
+		)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+//InsertReservationNote adds a note to a reservation
+func (m *postgresDBRepo) InsertReservationNote(n models.ReservationNote) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		insert into reservation_notes (reservation_id, user_id, body, created_at, updated_at)
+		values ($1, $2, $3, $4, $5)
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt, n.ReservationID, n.UserID, n.Body, time.Now(), time.Now())
+
+	return err
+}
+
+//NotesForReservation returns the notes of a reservation with their authors, newest first
+func (m *postgresDBRepo) NotesForReservation(reservationID int) ([]models.ReservationNote, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var notes []models.ReservationNote
+
+	query := `
+		select n.id, n.reservation_id, n.user_id, n.body, n.created_at, n.updated_at,
+		u.id, u.first_name, u.last_name
+		from reservation_notes n
+		join users u on (n.user_id = u.id)
+		where n.reservation_id = $1
+		order by n.created_at desc, n.id desc
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, reservationID)
+	if err != nil {
+		return notes, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n models.ReservationNote
+		err := rows.Scan(
+			&n.ID,
+			&n.ReservationID,
+			&n.UserID,
+			&n.Body,
+			&n.CreatedAt,
+			&n.UpdatedAt,
+			&n.User.ID,
+			&n.User.FirstName,
+			&n.User.LastName,
+		)
+		if err != nil {
+			return notes, err
+		}
+		notes = append(notes, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return notes, err
+	}
+
+	return notes, nil
+}
+
+//DeleteReservationNote deletes a note of a reservation
+func (m *postgresDBRepo) DeleteReservationNote(id, reservationID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `delete from reservation_notes where id = $1 and reservation_id = $2`, id, reservationID)
+
+	return err
+}
+
+//UpdateReservationTags replaces the tags of a reservation, along with its taxes and total price as tags can
+//exempt it from taxes
+func (m *postgresDBRepo) UpdateReservationTags(res models.Reservation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `delete from reservation_tags where reservation_id = $1`, res.ID)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+		insert into reservation_tags (reservation_id, tag, created_at, updated_at)
+		values ($1, $2, $3, $4)
+	`
+
+	for _, tag := range res.Tags {
+		_, err = tx.ExecContext(ctx, stmt, res.ID, tag, time.Now(), time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "update reservations set total_price = $1, updated_at = $2 where id = $3",
+		res.TotalPrice, time.Now(), res.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "delete from reservation_taxes where reservation_id = $1", res.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = insertReservationTaxes(ctx, tx, res.ID, res.Taxes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+//AllTags returns every tag used on reservations in alphabetical order
+func (m *postgresDBRepo) AllTags() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var tags []string
+
+	rows, err := m.DB.QueryContext(ctx, `select distinct tag from reservation_tags order by tag`)
+	if err != nil {
+		return tags, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag string
+		err := rows.Scan(&tag)
+		if err != nil {
+			return tags, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return tags, err
+	}
+
+	return tags, nil
+}
+
+//queryer runs a query on the database or inside a transaction
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+//minPhoneDigits is how many digits a phone number needs to identify a guest
+const minPhoneDigits = 7
+
+//phoneDigits returns the digits of a phone number, or nothing if there are too few to identify a guest
+func phoneDigits(phone string) string {
+	var b strings.Builder
+	for _, c := range phone {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		}
+	}
+
+	if b.Len() < minPhoneDigits {
+		return ""
+	}
+
+	return b.String()
+}
+
+//matchGuest returns the guest with the email of a reservation, or else with its phone number, and adds
+//a new guest with the details of the reservation if there is none
+func matchGuest(ctx context.Context, q queryer, res models.Reservation) (int, error) {
+	var id int
+
+	query := `
+		select id from guests
+		where ($1 <> '' and lower(email) = lower($1))
+		or ($2 <> '' and regexp_replace(phone, '[^0-9]', '', 'g') = $2)
+		order by lower(email) = lower($1) desc, id asc
+		limit 1
+	`
+
+	err := q.QueryRowContext(ctx, query, res.Email, phoneDigits(res.Phone)).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	stmt := `
+		insert into guests (first_name, last_name, email, phone, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6) returning id
+	`
+
+	err = q.QueryRowContext(ctx, stmt, res.FirstName, res.LastName, res.Email, res.Phone, time.Now(), time.Now()).Scan(&id)
+
+	return id, err
+}
+
+//guestColumns selects a guest g with the figures over the reservations r joined to it, the status of
+//cancelled reservations must be the first argument of the query
+const guestColumns = `
+		select g.id, g.first_name, g.last_name, g.email, g.phone, g.created_at, g.updated_at,
+		count(r.id) filter (where r.status <> $1),
+		coalesce(sum(r.end_date - r.start_date) filter (where r.status <> $1), 0),
+		coalesce(sum(case when r.status = $1 then r.cancellation_fee else r.total_price end), 0),
+		max(r.start_date) filter (where r.status <> $1)
+		from guests g
+		left join reservations r on (r.guest_id = g.id)
+`
+
+//guestSortColumns maps the columns a guest list can be sorted by to their order by expression
+var guestSortColumns = map[string]string{
+	"last_name":      "g.last_name",
+	"email":          "g.email",
+	"stays":          "count(r.id) filter (where r.status <> $1)",
+	"lifetime_value": "coalesce(sum(case when r.status = $1 then r.cancellation_fee else r.total_price end), 0)",
+	"last_stay":      "max(r.start_date) filter (where r.status <> $1)",
+}
+
+//scanGuests reads the rows of a query selecting guestColumns
+func scanGuests(rows *sql.Rows) ([]models.Guest, error) {
+	var guests []models.Guest
+
+	for rows.Next() {
+		var g models.Guest
+		var lastStay sql.NullTime
+		err := rows.Scan(
+			&g.ID,
+			&g.FirstName,
+			&g.LastName,
+			&g.Email,
+			&g.Phone,
+			&g.CreatedAt,
+			&g.UpdatedAt,
+			&g.Stays,
+			&g.Nights,
+			&g.LifetimeValue,
+			&lastStay,
+		)
+		if err != nil {
+			return guests, err
+		}
+		g.LastStay = lastStay.Time
+		guests = append(guests, g)
+	}
+
+	return guests, rows.Err()
+}
+
+//SearchGuests returns a page of the guests whose name, email or phone contains the search text and how
+//many match in total. Unknown sort columns sort by last name
+func (m *postgresDBRepo) SearchGuests(search string, p models.Page) ([]models.Guest, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var total int
+
+	pattern := ""
+	if search != "" {
+		pattern = "%" + likeEscaper.Replace(search) + "%"
+	}
+
+	countQuery := `
+		select count(*) from guests g
+		where ($1 = '' or g.first_name || ' ' || g.last_name ilike $1 or g.email ilike $1 or g.phone ilike $1)
+	`
+
+	err := m.DB.QueryRowContext(ctx, countQuery, pattern).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := guestSortColumns[p.Sort]
+	if !ok {
+		column = "g.last_name"
+	}
+
+	direction := "asc"
+	if p.Desc {
+		direction = "desc"
+	}
+
+	if p.Number < 1 {
+		p.Number = 1
+	}
+
+	query := guestColumns + `
+		where ($2 = '' or g.first_name || ' ' || g.last_name ilike $2 or g.email ilike $2 or g.phone ilike $2)
+		group by g.id
+		order by ` + column + ` ` + direction + ` nulls last, g.id ` + direction + `
+		limit $3 offset $4
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, models.ReservationCancelled, pattern, p.Size, (p.Number-1)*p.Size)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	guests, err := scanGuests(rows)
+	if err != nil {
+		return guests, 0, err
+	}
+
+	return guests, total, nil
+}
+
+//GetGuestByID returns one guest by id
+func (m *postgresDBRepo) GetGuestByID(id int) (models.Guest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := guestColumns + `
+		where g.id = $2
+		group by g.id
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, models.ReservationCancelled, id)
+	if err != nil {
+		return models.Guest{}, err
+	}
+	defer rows.Close()
+
+	guests, err := scanGuests(rows)
+	if err != nil {
+		return models.Guest{}, err
+	}
+
+	if len(guests) == 0 {
+		return models.Guest{}, sql.ErrNoRows
+	}
+
+	return guests[0], nil
+}
+
+//ReservationsForGuest returns the reservations of a guest, latest arrival first
+func (m *postgresDBRepo) ReservationsForGuest(guestID int) ([]models.Reservation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var reservations []models.Reservation
+
+	query := `
+		select r.id, r.first_name, r.last_name, r.email, r.phone, r.start_date,
+		r.end_date, r.room_id, r.created_at, r.updated_at, r.processed,
+		r.total_price, r.status, r.cancellation_fee,
+		rm.id, rm.room_name
+		from reservations r
+		left join rooms rm on (r.room_id = rm.id)
+		where r.guest_id = $1
+		order by r.start_date desc, r.id desc
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, guestID)
+	if err != nil {
+		return reservations, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i models.Reservation
+		err := rows.Scan(
+			&i.ID,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.Phone,
+			&i.StartDate,
+			&i.EndDate,
+			&i.RoomID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Processed,
+			&i.TotalPrice,
+			&i.Status,
+			&i.CancellationFee,
+			&i.Room.ID,
+			&i.Room.RoomName,
+		)
+		if err != nil {
+			return reservations, err
+		}
+		i.GuestID = guestID
+		reservations = append(reservations, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		return reservations, err
+	}
+
+	return reservations, nil
+}
+
+//PossibleDuplicateGuests returns the other guests with the same name, email or phone number as a guest
+func (m *postgresDBRepo) PossibleDuplicateGuests(g models.Guest) ([]models.Guest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := guestColumns + `
+		where g.id <> $2
+		and ((lower(g.first_name) = lower($3) and lower(g.last_name) = lower($4))
+		or ($5 <> '' and lower(g.email) = lower($5))
+		or ($6 <> '' and regexp_replace(g.phone, '[^0-9]', '', 'g') = $6))
+		group by g.id
+		order by g.id
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, models.ReservationCancelled, g.ID, g.FirstName, g.LastName,
+		g.Email, phoneDigits(g.Phone))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanGuests(rows)
+}
+
+//MergeGuests moves the reservations of a duplicate guest to the guest kept, fills in contact details the
+//kept guest lacks and deletes the duplicate
+func (m *postgresDBRepo) MergeGuests(keepID, mergeID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if keepID == mergeID {
+		return errors.New("can't merge a guest into itself")
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `update reservations set guest_id = $1, updated_at = $2 where guest_id = $3`,
+		keepID, time.Now(), mergeID)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+		update guests k set
+		email = case when k.email = '' then d.email else k.email end,
+		phone = case when k.phone = '' then d.phone else k.phone end,
+		updated_at = $3
+		from guests d
+		where k.id = $1 and d.id = $2
+	`
+
+	result, err := tx.ExecContext(ctx, stmt, keepID, mergeID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from guests where id = $1`, mergeID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+//ArrivalsForDay returns the reservations arriving on a day that aren't cancelled
+func (m *postgresDBRepo) ArrivalsForDay(day time.Time) ([]models.Reservation, error) {
+	return m.frontDeskReservations("r.start_date = $1", day)
+}
+
+//DeparturesForDay returns the reservations departing on a day that aren't cancelled
+func (m *postgresDBRepo) DeparturesForDay(day time.Time) ([]models.Reservation, error) {
+	return m.frontDeskReservations("r.end_date = $1", day)
+}
+
+//frontDeskReservations returns the reservations that aren't cancelled matching a condition on a day
+func (m *postgresDBRepo) frontDeskReservations(condition string, day time.Time) ([]models.Reservation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var reservations []models.Reservation
+
+	query := `
+		select r.id, r.first_name, r.last_name, r.email, r.phone, r.start_date,
+		r.end_date, r.room_id, r.total_price, r.status, r.checked_in_at, r.checked_out_at,
+		rm.id, rm.room_name
+		from reservations r
+		left join rooms rm on (r.room_id = rm.id)
+		where ` + condition + ` and r.status <> $2
+		order by rm.room_name asc, r.id asc
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, day, models.ReservationCancelled)
+	if err != nil {
+		return reservations, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i models.Reservation
+		var checkedInAt, checkedOutAt sql.NullTime
+		err := rows.Scan(
+			&i.ID,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.Phone,
+			&i.StartDate,
+			&i.EndDate,
+			&i.RoomID,
+			&i.TotalPrice,
+			&i.Status,
+			&checkedInAt,
+			&checkedOutAt,
+			&i.Room.ID,
+			&i.Room.RoomName,
+		)
+		if err != nil {
+			return reservations, err
+		}
+		i.CheckedInAt = checkedInAt.Time
+		i.CheckedOutAt = checkedOutAt.Time
+		reservations = append(reservations, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		return reservations, err
+	}
+
+	return reservations, nil
+}
+
+//CheckIn records the time a guest arrived, returns false if the reservation isn't confirmed or was
+//checked in already
+func (m *postgresDBRepo) CheckIn(id int, at time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update reservations set checked_in_at = $1, updated_at = $2
+		where id = $3 and status = $4 and checked_in_at is null
+	`
+
+	return m.execUpdated(ctx, stmt, at, time.Now(), id, models.ReservationConfirmed)
+}
+
+//CheckOut records the time a guest left and marks the room dirty, returns false if the reservation isn't
+//checked in or was checked out already
+func (m *postgresDBRepo) CheckOut(id int, at time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var roomID int
+
+	stmt := `
+		update reservations set checked_out_at = $1, updated_at = $2
+		where id = $3 and checked_in_at is not null and checked_out_at is null
+		returning room_id
+	`
+
+	err = tx.QueryRowContext(ctx, stmt, at, time.Now(), id).Scan(&roomID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	stmt = `
+		update rooms set housekeeping_status = $1, updated_at = $2
+		where id = $3 and housekeeping_status <> $4
+	`
+
+	_, err = tx.ExecContext(ctx, stmt, models.RoomDirty, time.Now(), roomID, models.RoomOutOfOrder)
+	if err != nil {
+		return false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+//MarkNoShow marks a confirmed reservation that wasn't checked in as a no-show, returns false if it can't be
+func (m *postgresDBRepo) MarkNoShow(id int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update reservations set status = $1, updated_at = $2
+		where id = $3 and status = $4 and checked_in_at is null
+	`
+
+	return m.execUpdated(ctx, stmt, models.ReservationNoShow, time.Now(), id, models.ReservationConfirmed)
+}
+
+//MarkNoShows marks the confirmed reservations arriving on a day that weren't checked in as no-shows and
+//returns how many there were. Only the one arrival day is looked at, so older stays, which were never checked
+//in because check-ins weren't recorded yet or were imported, keep their status
+func (m *postgresDBRepo) MarkNoShows(arrival time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update reservations set status = $1, updated_at = $2
+		where status = $3 and checked_in_at is null and start_date = $4
+	`
+
+	result, err := m.DB.ExecContext(ctx, stmt, models.ReservationNoShow, time.Now(), models.ReservationConfirmed, arrival)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+//execUpdated runs an update and returns true if it changed a row
+func (m *postgresDBRepo) execUpdated(ctx context.Context, stmt string, args ...interface{}) (bool, error) {
+	result, err := m.DB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+//HousekeepingBoard returns every room with its housekeeping status and the stays of confirmed reservations
+//departing, arriving and going on during a day
+func (m *postgresDBRepo) HousekeepingBoard(day time.Time) ([]models.HousekeepingRoom, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var board []models.HousekeepingRoom
+
+	query := `
+		select rm.id, rm.room_name, rm.housekeeping_status,
+		exists (select 1 from room_restrictions rr join reservations r on (rr.reservation_id = r.id)
+			where rr.room_id = rm.id and rr.end_date = $1 and r.status = $2 and r.checked_out_at is null),
+		exists (select 1 from room_restrictions rr join reservations r on (rr.reservation_id = r.id)
+			where rr.room_id = rm.id and rr.start_date = $1 and r.status = $2),
+		exists (select 1 from room_restrictions rr join reservations r on (rr.reservation_id = r.id)
+			where rr.room_id = rm.id and rr.start_date <= $1 and rr.end_date > $1 and r.status = $2)
+		from rooms rm
+		order by rm.room_name
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, day, models.ReservationConfirmed)
+	if err != nil {
+		return board, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h models.HousekeepingRoom
+		err := rows.Scan(
+			&h.Room.ID,
+			&h.Room.RoomName,
+			&h.Room.HousekeepingStatus,
+			&h.Departing,
+			&h.Arriving,
+			&h.Occupied,
+		)
+		if err != nil {
+			return board, err
+		}
+		board = append(board, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return board, err
+	}
+
+	return board, nil
+}
+
+//UpdateRoomHousekeepingStatus sets the housekeeping status of a room
+func (m *postgresDBRepo) UpdateRoomHousekeepingStatus(roomID int, status string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `update rooms set housekeeping_status = $1, updated_at = $2 where id = $3`
+
+	_, err := m.DB.ExecContext(ctx, stmt, status, time.Now(), roomID)
+
+	return err
+}
+
+//ListUsers returns all users by name
+func (m *postgresDBRepo) ListUsers() ([]models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var users []models.User
+
+	query := `
+		select id, first_name, last_name, email, access_level, created_at, updated_at
+		from users order by last_name, first_name
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return users, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u models.User
+		err := rows.Scan(
+			&u.ID,
+			&u.FirstName,
+			&u.LastName,
+			&u.Email,
+			&u.AccessLevel,
+			&u.CreatedAt,
+			&u.UpdatedAt,
+		)
+		if err != nil {
+			return users, err
+		}
+		users = append(users, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return users, err
+	}
+
+	return users, nil
+}
+
+//maintenanceTicketColumns selects maintenance tickets with their rooms and assignees
+const maintenanceTicketColumns = `
+	select t.id, t.room_id, t.title, t.description, t.priority, t.status, coalesce(t.assignee_id, 0),
+	t.offline_start, t.offline_end, coalesce(t.room_restriction_id, 0), t.closed_at, t.created_at, t.updated_at,
+	rm.room_name, coalesce(u.first_name, ''), coalesce(u.last_name, '')
+	from maintenance_tickets t
+	left join rooms rm on (t.room_id = rm.id)
+	left join users u on (t.assignee_id = u.id)
+`
+
+//scanMaintenanceTickets reads the rows of a maintenanceTicketColumns query
+func scanMaintenanceTickets(rows *sql.Rows) ([]models.MaintenanceTicket, error) {
+	var tickets []models.MaintenanceTicket
+
+	for rows.Next() {
+		var t models.MaintenanceTicket
+		var offlineStart, offlineEnd, closedAt sql.NullTime
+		err := rows.Scan(
+			&t.ID,
+			&t.RoomID,
+			&t.Title,
+			&t.Description,
+			&t.Priority,
+			&t.Status,
+			&t.AssigneeID,
+			&offlineStart,
+			&offlineEnd,
+			&t.RoomRestrictionID,
+			&closedAt,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+			&t.Room.RoomName,
+			&t.Assignee.FirstName,
+			&t.Assignee.LastName,
+		)
+		if err != nil {
+			return tickets, err
+		}
+		t.OfflineStart = offlineStart.Time
+		t.OfflineEnd = offlineEnd.Time
+		t.ClosedAt = closedAt.Time
+		t.Room.ID = t.RoomID
+		t.Assignee.ID = t.AssigneeID
+		tickets = append(tickets, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return tickets, err
+	}
+
+	return tickets, nil
+}
+
+//MaintenanceTickets returns the maintenance tickets with a status, or all tickets that aren't closed if the
+//status is empty, most urgent first
+func (m *postgresDBRepo) MaintenanceTickets(status string) ([]models.MaintenanceTicket, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := maintenanceTicketColumns + `
+		where ($1 = '' and t.status <> $2) or t.status = $1
+		order by case t.priority when $3 then 0 when $4 then 1 when $5 then 2 else 3 end, t.created_at
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, status, models.MaintenanceClosed,
+		models.MaintenanceUrgent, models.MaintenanceHigh, models.MaintenanceNormal)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMaintenanceTickets(rows)
+}
+
+//GetMaintenanceTicketByID returns one maintenance ticket by id
+func (m *postgresDBRepo) GetMaintenanceTicketByID(id int) (models.MaintenanceTicket, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, maintenanceTicketColumns+` where t.id = $1`, id)
+	if err != nil {
+		return models.MaintenanceTicket{}, err
+	}
+	defer rows.Close()
+
+	tickets, err := scanMaintenanceTickets(rows)
+	if err != nil {
+		return models.MaintenanceTicket{}, err
+	}
+
+	if len(tickets) == 0 {
+		return models.MaintenanceTicket{}, sql.ErrNoRows
+	}
+
+	return tickets[0], nil
+}
+
+//placeMaintenanceBlock blocks the room of an open ticket for its offline dates and returns the id of the
+//room restriction, or 0 if the ticket doesn't take the room offline. It returns false if the room isn't
+//available for the dates
+func placeMaintenanceBlock(ctx context.Context, tx *sql.Tx, t models.MaintenanceTicket) (int, bool, error) {
+	if t.Status == models.MaintenanceClosed || !t.Offline() {
+		return 0, true, nil
+	}
+
+	//lock the room so a guest can't book the nights while they are checked
+	_, err := tx.ExecContext(ctx, "select id from rooms where id = $1 for update", t.RoomID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var numRows int
+
+	query := `
+		select count(id) from room_restrictions
+		where room_id = $1 and $2 <= end_date and $3 >= start_date
+		and (expires_at is null or expires_at > $4)
+	`
+
+	err = tx.QueryRowContext(ctx, query, t.RoomID, t.OfflineStart, t.OfflineEnd, time.Now()).Scan(&numRows)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if numRows > 0 {
+		return 0, false, nil
+	}
+
+	var newID int
+
+	stmt := `
+		insert into room_restrictions (start_date, end_date, room_id, restriction_id,
+		created_at, updated_at) values ($1, $2, $3, $4, $5, $6) returning id
+	`
+
+	err = tx.QueryRowContext(ctx,
+		stmt,
+		t.OfflineStart,
+		t.OfflineEnd,
+		t.RoomID,
+		models.RestrictionMaintenance,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return newID, true, nil
+}
+
+//maintenanceNulls returns the optional columns of a maintenance ticket as nullable values
+func maintenanceNulls(t models.MaintenanceTicket, restrictionID int) (sql.NullInt64, sql.NullTime, sql.NullTime, sql.NullInt64) {
+	var assignee, restriction sql.NullInt64
+	var start, end sql.NullTime
+
+	if t.AssigneeID > 0 {
+		assignee = sql.NullInt64{Int64: int64(t.AssigneeID), Valid: true}
+	}
+
+	if t.Offline() {
+		start = sql.NullTime{Time: t.OfflineStart, Valid: true}
+		end = sql.NullTime{Time: t.OfflineEnd, Valid: true}
+	}
+
+	if restrictionID > 0 {
+		restriction = sql.NullInt64{Int64: int64(restrictionID), Valid: true}
+	}
+
+	return assignee, start, end, restriction
+}
+
+//InsertMaintenanceTicket adds a maintenance ticket and blocks its room for the offline dates, returns false
+//if the room isn't available for them
+func (m *postgresDBRepo) InsertMaintenanceTicket(t models.MaintenanceTicket) (int, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	restrictionID, ok, err := placeMaintenanceBlock(ctx, tx, t)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+
+	assignee, start, end, restriction := maintenanceNulls(t, restrictionID)
+
+	var closedAt sql.NullTime
+	if t.Status == models.MaintenanceClosed {
+		closedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+
+	var newID int
+
+	stmt := `
+		insert into maintenance_tickets (room_id, title, description, priority, status, assignee_id,
+		offline_start, offline_end, room_restriction_id, closed_at, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) returning id
+	`
+
+	err = tx.QueryRowContext(ctx,
+		stmt,
+		t.RoomID,
+		t.Title,
+		t.Description,
+		t.Priority,
+		t.Status,
+		assignee,
+		start,
+		end,
+		restriction,
+		closedAt,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, false, err
+	}
+
+	return newID, true, nil
+}
+
+//UpdateMaintenanceTicket updates a maintenance ticket and replaces the block on its room, which is released
+//once the ticket is closed. It returns false if the room isn't available for the offline dates
+func (m *postgresDBRepo) UpdateMaintenanceTicket(t models.MaintenanceTicket) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var oldRestrictionID sql.NullInt64
+
+	query := `select room_restriction_id from maintenance_tickets where id = $1 for update`
+
+	err = tx.QueryRowContext(ctx, query, t.ID).Scan(&oldRestrictionID)
+	if err != nil {
+		return false, err
+	}
+
+	if oldRestrictionID.Valid {
+		_, err = tx.ExecContext(ctx, "delete from room_restrictions where id = $1", oldRestrictionID.Int64)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	restrictionID, ok, err := placeMaintenanceBlock(ctx, tx, t)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	assignee, start, end, restriction := maintenanceNulls(t, restrictionID)
+
+	stmt := `
+		update maintenance_tickets set room_id = $1, title = $2, description = $3, priority = $4, status = $5,
+		assignee_id = $6, offline_start = $7, offline_end = $8, room_restriction_id = $9,
+		closed_at = case when $5 = $10 then coalesce(closed_at, $11) end, updated_at = $11
+		where id = $12
+	`
+
+	_, err = tx.ExecContext(ctx,
+		stmt,
+		t.RoomID,
+		t.Title,
+		t.Description,
+		t.Priority,
+		t.Status,
+		assignee,
+		start,
+		end,
+		restriction,
+		models.MaintenanceClosed,
+		time.Now(),
+		t.ID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+//InsertFolioEntry adds a charge or payment to the folio of a reservation
+func (m *postgresDBRepo) InsertFolioEntry(e models.FolioEntry) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return insertFolioEntry(ctx, m.DB, e)
+}
+
+//insertFolioEntry inserts a folio entry on the database or inside a transaction, returning its id
+func insertFolioEntry(ctx context.Context, q queryer, e models.FolioEntry) (int, error) {
+	var newID int
+
+	var paymentID sql.NullInt64
+	if e.PaymentID > 0 {
+		paymentID = sql.NullInt64{Int64: int64(e.PaymentID), Valid: true}
+	}
+
+	stmt := `
+		insert into folio_entries (reservation_id, payment_id, kind, description, amount, status, provider,
+		reference, checkout_url, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) returning id
+	`
+
+	err := q.QueryRowContext(ctx,
+		stmt,
+		e.ReservationID,
+		paymentID,
+		e.Kind,
+		e.Description,
+		e.Amount,
+		e.Status,
+		e.Provider,
+		e.Reference,
+		e.CheckoutURL,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+//FolioEntries returns the charges and payments on the folio of a reservation, oldest first
+func (m *postgresDBRepo) FolioEntries(reservationID int) ([]models.FolioEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var entries []models.FolioEntry
+
+	query := `
+		select id, reservation_id, coalesce(payment_id, 0), kind, description, amount, status, provider,
+		reference, checkout_url, created_at, updated_at
+		from folio_entries where reservation_id = $1
+		order by created_at, id
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, reservationID)
+	if err != nil {
+		return entries, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.FolioEntry
+		err := rows.Scan(
+			&e.ID,
+			&e.ReservationID,
+			&e.PaymentID,
+			&e.Kind,
+			&e.Description,
+			&e.Amount,
+			&e.Status,
+			&e.Provider,
+			&e.Reference,
+			&e.CheckoutURL,
+			&e.CreatedAt,
+			&e.UpdatedAt,
+		)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return entries, err
+	}
+
+	return entries, nil
+}
+
+//UpdatePaymentStatus settles a pending payment or refund made through a provider, returns false if there
+//is none pending with the reference
+func (m *postgresDBRepo) UpdatePaymentStatus(provider, reference, status string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update folio_entries set status = $1, updated_at = $2
+		where provider = $3 and reference = $4 and kind in ($5, $6) and status = $7
+	`
+
+	return m.execUpdated(ctx, stmt, status, time.Now(), provider, reference,
+		models.FolioPayment, models.FolioRefund, models.PaymentPending)
+}
+
+//lockRefundable locks the reservation of a refund and returns ErrRefundTooLarge if more than is left of its
+//payment would be given back. Like models.Folio.Refundable, refunds that haven't failed count as given back
+func lockRefundable(ctx context.Context, tx *sql.Tx, e models.FolioEntry) error {
+	_, err := tx.ExecContext(ctx, "select id from reservations where id = $1 for update", e.ReservationID)
+	if err != nil {
+		return err
+	}
+
+	var refundable int
+
+	query := `
+		select coalesce(sum(case
+			when id = $2 and kind = $3 and status = $5 then amount
+			when payment_id = $2 and kind = $4 and status <> $6 then -amount
+			else 0 end), 0)
+		from folio_entries where reservation_id = $1
+	`
+
+	err = tx.QueryRowContext(ctx, query, e.ReservationID, e.PaymentID, models.FolioPayment, models.FolioRefund,
+		models.PaymentSucceeded, models.PaymentFailed).Scan(&refundable)
+	if err != nil {
+		return err
+	}
+
+	if e.Amount > refundable {
+		return repository.ErrRefundTooLarge
+	}
+
+	return nil
+}
+
+//InsertRefund enters the refund of a payment on the folio of a reservation, returning its id. The reservation
+//is locked while what is left of the payment is checked, ErrRefundTooLarge is returned when less than the
+//amount is left
+func (m *postgresDBRepo) InsertRefund(e models.FolioEntry) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err = lockRefundable(ctx, tx, e); err != nil {
+		return 0, err
+	}
+
+	refundID, err := insertFolioEntry(ctx, tx, e)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return refundID, nil
+}
+
+//UpdateRefund sets the status and provider reference of a refund once the provider has answered
+func (m *postgresDBRepo) UpdateRefund(id int, status, reference string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update folio_entries set status = $1, reference = $2, updated_at = $3
+		where id = $4 and kind = $5
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt, status, reference, time.Now(), id, models.FolioRefund)
+
+	return err
+}
+
+//InsertInvoice issues an invoice, giving it the number after the last one issued and returning it with its
+//number and date. The invoices table is locked while numbering so that numbers run without gaps
+func (m *postgresDBRepo) InsertInvoice(inv models.Invoice) (models.Invoice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return inv, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `lock table invoices in exclusive mode`)
+	if err != nil {
+		return inv, err
+	}
+
+	err = tx.QueryRowContext(ctx, `select coalesce(max(number), 0) + 1 from invoices`).Scan(&inv.Number)
+	if err != nil {
+		return inv, err
+	}
+
+	inv.IssuedAt = time.Now()
+
+	stmt := `
+		insert into invoices (number, reservation_id, seller_name, seller_company, seller_address,
+		seller_vat_number, buyer_name, buyer_company, buyer_address, buyer_vat_number, total, paid,
+		created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) returning id
+	`
+
+	err = tx.QueryRowContext(ctx,
+		stmt,
+		inv.Number,
+		inv.ReservationID,
+		inv.Seller.Name,
+		inv.Seller.Company,
+		inv.Seller.Address,
+		inv.Seller.VATNumber,
+		inv.Buyer.Name,
+		inv.Buyer.Company,
+		inv.Buyer.Address,
+		inv.Buyer.VATNumber,
+		inv.Total,
+		inv.Paid,
+		inv.IssuedAt,
+		inv.IssuedAt,
+	).Scan(&inv.ID)
+	if err != nil {
+		return inv, err
+	}
+
+	stmt = `
+		insert into invoice_lines (invoice_id, position, description, amount, tax, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	//the taxes follow the lines, flagged so that they can be told apart
+	for i, l := range append(inv.Lines, inv.Taxes...) {
+		_, err = tx.ExecContext(ctx, stmt, inv.ID, i+1, l.Description, l.Amount, i >= len(inv.Lines),
+			inv.IssuedAt, inv.IssuedAt)
+		if err != nil {
+			return inv, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return inv, err
+	}
+
+	return inv, nil
+}
+
+//invoiceColumns are the columns scanned by scanInvoice
+const invoiceColumns = `
+	id, number, coalesce(reservation_id, 0), seller_name, seller_company, seller_address, seller_vat_number,
+	buyer_name, buyer_company, buyer_address, buyer_vat_number, total, paid, created_at
+`
+
+//scanInvoice reads an invoice without its lines from a row of invoiceColumns
+func scanInvoice(row interface{ Scan(...interface{}) error }) (models.Invoice, error) {
+	var inv models.Invoice
+
+	err := row.Scan(
+		&inv.ID,
+		&inv.Number,
+		&inv.ReservationID,
+		&inv.Seller.Name,
+		&inv.Seller.Company,
+		&inv.Seller.Address,
+		&inv.Seller.VATNumber,
+		&inv.Buyer.Name,
+		&inv.Buyer.Company,
+		&inv.Buyer.Address,
+		&inv.Buyer.VATNumber,
+		&inv.Total,
+		&inv.Paid,
+		&inv.IssuedAt,
+	)
+
+	return inv, err
+}
+
+//GetInvoiceByNumber returns an invoice with its lines
+func (m *postgresDBRepo) GetInvoiceByNumber(number int) (models.Invoice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select ` + invoiceColumns + ` from invoices where number = $1`
+
+	inv, err := scanInvoice(m.DB.QueryRowContext(ctx, query, number))
+	if err != nil {
+		return inv, err
+	}
+
+	query = `select description, amount, tax from invoice_lines where invoice_id = $1 order by position`
+
+	rows, err := m.DB.QueryContext(ctx, query, inv.ID)
+	if err != nil {
+		return inv, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l models.InvoiceLine
+		var tax bool
+		err := rows.Scan(&l.Description, &l.Amount, &tax)
+		if err != nil {
+			return inv, err
+		}
+
+		if tax {
+			inv.Taxes = append(inv.Taxes, l)
+		} else {
+			inv.Lines = append(inv.Lines, l)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return inv, err
+	}
+
+	return inv, nil
+}
+
+//InvoicesForReservation returns the invoices issued for a reservation without their lines, oldest first
+func (m *postgresDBRepo) InvoicesForReservation(reservationID int) ([]models.Invoice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var invoices []models.Invoice
+
+	query := `select ` + invoiceColumns + ` from invoices where reservation_id = $1 order by number`
+
+	rows, err := m.DB.QueryContext(ctx, query, reservationID)
+	if err != nil {
+		return invoices, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return invoices, err
+		}
+		invoices = append(invoices, inv)
+	}
+
+	if err = rows.Err(); err != nil {
+		return invoices, err
+	}
+
+	return invoices, nil
+}
+
+//taxRuleColumns are the columns scanned by scanTaxRule
+const taxRuleColumns = `id, name, kind, rate, inclusive, valid_from, valid_to, max_nights, exempt_tag,
+		created_at, updated_at`
+
+func scanTaxRule(row interface{ Scan(...interface{}) error }) (models.TaxRule, error) {
+	var r models.TaxRule
+	var validFrom, validTo sql.NullTime
+
+	err := row.Scan(
+		&r.ID,
+		&r.Name,
+		&r.Kind,
+		&r.Rate,
+		&r.Inclusive,
+		&validFrom,
+		&validTo,
+		&r.MaxNights,
+		&r.ExemptTag,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+	if err != nil {
+		return r, err
+	}
+
+	r.ValidFrom = validFrom.Time
+	r.ValidTo = validTo.Time
+
+	return r, nil
+}
+
+//taxRuleDates returns the dates of a tax rule, null when they are left open
+func taxRuleDates(r models.TaxRule) (sql.NullTime, sql.NullTime) {
+	return sql.NullTime{Time: r.ValidFrom, Valid: !r.ValidFrom.IsZero()},
+		sql.NullTime{Time: r.ValidTo, Valid: !r.ValidTo.IsZero()}
+}
+
+//TaxRules returns all tax rules in the order they are applied
+func (m *postgresDBRepo) TaxRules() ([]models.TaxRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var rules []models.TaxRule
+
+	rows, err := m.DB.QueryContext(ctx, "select "+taxRuleColumns+" from tax_rules order by id")
+	if err != nil {
+		return rules, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r, err := scanTaxRule(rows)
+		if err != nil {
+			return rules, err
+		}
+		rules = append(rules, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return rules, err
+	}
+
+	return rules, nil
+}
+
+//GetTaxRuleByID returns one tax rule by id
+func (m *postgresDBRepo) GetTaxRuleByID(id int) (models.TaxRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanTaxRule(m.DB.QueryRowContext(ctx, "select "+taxRuleColumns+" from tax_rules where id = $1", id))
+}
+
+//InsertTaxRule inserts a tax rule into the database
+func (m *postgresDBRepo) InsertTaxRule(r models.TaxRule) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var newID int
+	validFrom, validTo := taxRuleDates(r)
+
+	stmt := `
+		insert into tax_rules (name, kind, rate, inclusive, valid_from, valid_to, max_nights, exempt_tag,
+		created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) returning id
+	`
+
+	err := m.DB.QueryRowContext(ctx,
+		stmt,
+		r.Name,
+		r.Kind,
+		r.Rate,
+		r.Inclusive,
+		validFrom,
+		validTo,
+		r.MaxNights,
+		r.ExemptTag,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+//UpdateTaxRule updates a tax rule in the database. Reservations keep the taxes they were charged
+func (m *postgresDBRepo) UpdateTaxRule(r models.TaxRule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	validFrom, validTo := taxRuleDates(r)
+
+	query := `
+		update tax_rules set name = $1, kind = $2, rate = $3, inclusive = $4, valid_from = $5, valid_to = $6,
+		max_nights = $7, exempt_tag = $8, updated_at = $9
+		where id = $10
+	`
+
+	_, err := m.DB.ExecContext(ctx, query, r.Name, r.Kind, r.Rate, r.Inclusive, validFrom, validTo, r.MaxNights,
+		r.ExemptTag, time.Now(), r.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//DeleteTaxRule deletes one tax rule by id
+func (m *postgresDBRepo) DeleteTaxRule(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "delete from tax_rules where id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//promoCodeColumns are the columns scanned by scanPromoCode
+const promoCodeColumns = `id, code, description, kind, amount, valid_from, valid_to, min_nights, max_uses, uses,
+		created_at, updated_at`
+
+func scanPromoCode(row interface{ Scan(...interface{}) error }) (models.PromoCode, error) {
+	var p models.PromoCode
+	var validFrom, validTo sql.NullTime
+
+	err := row.Scan(
+		&p.ID,
+		&p.Code,
+		&p.Description,
+		&p.Kind,
+		&p.Amount,
+		&validFrom,
+		&validTo,
+		&p.MinNights,
+		&p.MaxUses,
+		&p.Uses,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return p, err
+	}
+
+	p.ValidFrom = validFrom.Time
+	p.ValidTo = validTo.Time
+
+	return p, nil
+}
+
+//loadPromoCodeRooms fills in the rooms of promo codes, selecting them with a condition on the promo_code_rooms
+//table aliased pr
+func (m *postgresDBRepo) loadPromoCodeRooms(ctx context.Context, codes []models.PromoCode, condition string,
+	args ...interface{}) error {
+	if len(codes) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(codes))
+	for i, p := range codes {
+		index[p.ID] = i
+	}
+
+	query := "select pr.promo_code_id, pr.room_id from promo_code_rooms pr where " + condition + " order by pr.room_id"
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var promoCodeID, roomID int
+		if err := rows.Scan(&promoCodeID, &roomID); err != nil {
+			return err
+		}
+
+		if i, ok := index[promoCodeID]; ok {
+			codes[i].RoomIDs = append(codes[i].RoomIDs, roomID)
+		}
+	}
+
+	return rows.Err()
+}
+
+//getPromoCode returns the promo code matching a condition with its rooms
+func (m *postgresDBRepo) getPromoCode(condition string, arg interface{}) (models.PromoCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	p, err := scanPromoCode(m.DB.QueryRowContext(ctx, "select "+promoCodeColumns+" from promo_codes where "+condition, arg))
+	if err != nil {
+		return p, err
+	}
+
+	codes := []models.PromoCode{p}
+	if err = m.loadPromoCodeRooms(ctx, codes, "pr.promo_code_id = $1", p.ID); err != nil {
+		return p, err
+	}
+
+	return codes[0], nil
+}
+
+//GetPromoCodeByID returns one promo code by id
+func (m *postgresDBRepo) GetPromoCodeByID(id int) (models.PromoCode, error) {
+	return m.getPromoCode("id = $1", id)
+}
+
+//GetPromoCodeByCode returns the promo code guests enter as code, in any case, returns false if there is none
+func (m *postgresDBRepo) GetPromoCodeByCode(code string) (models.PromoCode, bool, error) {
+	p, err := m.getPromoCode("upper(code) = upper($1)", code)
+	if err == sql.ErrNoRows {
+		return p, false, nil
+	}
+	if err != nil {
+		return p, false, err
+	}
+
+	return p, true, nil
+}
+
+//savePromoCodeRooms replaces the rooms of a promo code
+func savePromoCodeRooms(ctx context.Context, tx *sql.Tx, p models.PromoCode) error {
+	_, err := tx.ExecContext(ctx, "delete from promo_code_rooms where promo_code_id = $1", p.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, roomID := range p.RoomIDs {
+		_, err = tx.ExecContext(ctx, `
+			insert into promo_code_rooms (promo_code_id, room_id, created_at, updated_at) values ($1, $2, $3, $4)
+		`, p.ID, roomID, time.Now(), time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//promoCodeDates returns the dates of a promo code, null when they are left open
+func promoCodeDates(p models.PromoCode) (sql.NullTime, sql.NullTime) {
+	return sql.NullTime{Time: p.ValidFrom, Valid: !p.ValidFrom.IsZero()},
+		sql.NullTime{Time: p.ValidTo, Valid: !p.ValidTo.IsZero()}
+}
+
+//InsertPromoCode inserts a promo code with its rooms into the database
+func (m *postgresDBRepo) InsertPromoCode(p models.PromoCode) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	validFrom, validTo := promoCodeDates(p)
+
+	stmt := `
+		insert into promo_codes (code, description, kind, amount, valid_from, valid_to, min_nights, max_uses,
+		created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) returning id
+	`
+
+	err = tx.QueryRowContext(ctx,
+		stmt,
+		p.Code,
+		p.Description,
+		p.Kind,
+		p.Amount,
+		validFrom,
+		validTo,
+		p.MinNights,
+		p.MaxUses,
+		time.Now(),
+		time.Now(),
+	).Scan(&p.ID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err = savePromoCodeRooms(ctx, tx, p); err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return p.ID, nil
+}
+
+//UpdatePromoCode updates a promo code and its rooms in the database, leaving its uses alone
+func (m *postgresDBRepo) UpdatePromoCode(p models.PromoCode) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	validFrom, validTo := promoCodeDates(p)
+
+	query := `
+		update promo_codes set code = $1, description = $2, kind = $3, amount = $4, valid_from = $5, valid_to = $6,
+		min_nights = $7, max_uses = $8, updated_at = $9
+		where id = $10
+	`
+
+	_, err = tx.ExecContext(ctx, query, p.Code, p.Description, p.Kind, p.Amount, validFrom, validTo, p.MinNights,
+		p.MaxUses, time.Now(), p.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = savePromoCodeRooms(ctx, tx, p); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+//DeletePromoCode deletes one promo code by id, reservations keep the code they were made with
+func (m *postgresDBRepo) DeletePromoCode(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "delete from promo_codes where id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//PromoCodeUsage returns how every promo code was used by the reservations made from start up to end
+func (m *postgresDBRepo) PromoCodeUsage(start, end time.Time) ([]models.PromoCodeUsage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var usage []models.PromoCodeUsage
+
+	query := `
+		select p.id, p.code, p.description, p.kind, p.amount, p.valid_from, p.valid_to, p.min_nights, p.max_uses,
+		p.uses, p.created_at, p.updated_at,
+		count(r.id),
+		count(r.id) filter (where r.status = $3),
+		coalesce(sum(r.discount) filter (where r.status <> $3), 0),
+		coalesce(sum(r.total_price) filter (where r.status <> $3), 0)
+		from promo_codes p
+		left join reservations r on (r.promo_code_id = p.id and r.created_at >= $1 and r.created_at < $2)
+		group by p.id
+		order by p.code
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, start, end, models.ReservationCancelled)
+	if err != nil {
+		return usage, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u models.PromoCodeUsage
+		var validFrom, validTo sql.NullTime
+		err := rows.Scan(
+			&u.PromoCode.ID,
+			&u.PromoCode.Code,
+			&u.PromoCode.Description,
+			&u.PromoCode.Kind,
+			&u.PromoCode.Amount,
+			&validFrom,
+			&validTo,
+			&u.PromoCode.MinNights,
+			&u.PromoCode.MaxUses,
+			&u.PromoCode.Uses,
+			&u.PromoCode.CreatedAt,
+			&u.PromoCode.UpdatedAt,
+			&u.Reservations,
+			&u.Cancellations,
+			&u.Discount,
+			&u.Revenue,
+		)
+		if err != nil {
+			return usage, err
+		}
+		u.PromoCode.ValidFrom = validFrom.Time
+		u.PromoCode.ValidTo = validTo.Time
+		usage = append(usage, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return usage, err
+	}
+
+	return usage, nil
+}
+
+//priceRuleColumns are the columns scanned by scanPriceRule
+const priceRuleColumns = "id, name, kind, threshold, adjustment, created_at, updated_at"
+
+func scanPriceRule(row interface{ Scan(...interface{}) error }) (models.PriceRule, error) {
+	var r models.PriceRule
+
+	err := row.Scan(
+		&r.ID,
+		&r.Name,
+		&r.Kind,
+		&r.Threshold,
+		&r.Adjustment,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+
+	return r, err
+}
+
+//PriceRules returns all price rules in the order they are taken on ties
+func (m *postgresDBRepo) PriceRules() ([]models.PriceRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var rules []models.PriceRule
+
+	rows, err := m.DB.QueryContext(ctx, "select "+priceRuleColumns+" from price_rules order by kind, threshold, id")
+	if err != nil {
+		return rules, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r, err := scanPriceRule(rows)
+		if err != nil {
+			return rules, err
+		}
+		rules = append(rules, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return rules, err
+	}
+
+	return rules, nil
+}
+
+//GetPriceRuleByID returns one price rule by id
+func (m *postgresDBRepo) GetPriceRuleByID(id int) (models.PriceRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanPriceRule(m.DB.QueryRowContext(ctx, "select "+priceRuleColumns+" from price_rules where id = $1", id))
+}
+
+//InsertPriceRule inserts a price rule into the database
+func (m *postgresDBRepo) InsertPriceRule(r models.PriceRule) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var newID int
+
+	stmt := `
+		insert into price_rules (name, kind, threshold, adjustment, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6) returning id
+	`
+
+	err := m.DB.QueryRowContext(ctx, stmt, r.Name, r.Kind, r.Threshold, r.Adjustment, time.Now(), time.Now()).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+//UpdatePriceRule updates a price rule in the database. Reservations keep the prices they were charged
+func (m *postgresDBRepo) UpdatePriceRule(r models.PriceRule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		update price_rules set name = $1, kind = $2, threshold = $3, adjustment = $4, updated_at = $5
+		where id = $6
+	`
+
+	_, err := m.DB.ExecContext(ctx, query, r.Name, r.Kind, r.Threshold, r.Adjustment, time.Now(), r.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//DeletePriceRule deletes one price rule by id
+func (m *postgresDBRepo) DeletePriceRule(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "delete from price_rules where id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//ratePlanColumns are the columns scanned by scanRatePlan
+const ratePlanColumns = `
+	p.id, p.room_id, rm.room_name, p.name, p.price, coalesce(p.cancellation_policy_id, 0), p.inclusions,
+	p.created_at, p.updated_at
+	from rate_plans p
+	left join rooms rm on (rm.id = p.room_id)
+`
+
+func scanRatePlan(row interface{ Scan(...interface{}) error }) (models.RatePlan, error) {
+	var p models.RatePlan
+
+	err := row.Scan(
+		&p.ID,
+		&p.RoomID,
+		&p.Room.RoomName,
+		&p.Name,
+		&p.Price,
+		&p.CancellationPolicyID,
+		&p.Inclusions,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	p.Room.ID = p.RoomID
+
+	return p, err
+}
+
+//RatePlans returns the rate plans of all rooms, cheapest first
+func (m *postgresDBRepo) RatePlans() ([]models.RatePlan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var plans []models.RatePlan
+
+	rows, err := m.DB.QueryContext(ctx, "select "+ratePlanColumns+" order by rm.room_name, p.price, p.id")
+	if err != nil {
+		return plans, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanRatePlan(rows)
+		if err != nil {
+			return plans, err
+		}
+		plans = append(plans, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return plans, err
+	}
+
+	return plans, nil
+}
+
+//GetRatePlanByID returns one rate plan by id
+func (m *postgresDBRepo) GetRatePlanByID(id int) (models.RatePlan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanRatePlan(m.DB.QueryRowContext(ctx, "select "+ratePlanColumns+" where p.id = $1", id))
+}
+
+//InsertRatePlan inserts a rate plan into the database
+func (m *postgresDBRepo) InsertRatePlan(p models.RatePlan) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var newID int
+
+	stmt := `
+		insert into rate_plans (room_id, name, price, cancellation_policy_id, inclusions, created_at, updated_at)
+		values ($1, $2, $3, nullif($4, 0), $5, $6, $7) returning id
+	`
+
+	err := m.DB.QueryRowContext(ctx, stmt, p.RoomID, p.Name, p.Price, p.CancellationPolicyID, p.Inclusions,
+		time.Now(), time.Now()).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+//UpdateRatePlan updates a rate plan in the database. Reservations keep the prices they were charged
+func (m *postgresDBRepo) UpdateRatePlan(p models.RatePlan) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		update rate_plans set room_id = $1, name = $2, price = $3, cancellation_policy_id = nullif($4, 0),
+		inclusions = $5, updated_at = $6
+		where id = $7
+	`
+
+	_, err := m.DB.ExecContext(ctx, query, p.RoomID, p.Name, p.Price, p.CancellationPolicyID, p.Inclusions,
+		time.Now(), p.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//DeleteRatePlan deletes one rate plan by id, its reservations fall back to the cancellation policy of the room
+func (m *postgresDBRepo) DeleteRatePlan(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "delete from rate_plans where id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//extraColumns are the columns scanned by scanExtra
+const extraColumns = "id, name, description, kind, price, inventory, created_at, updated_at"
+
+func scanExtra(row interface{ Scan(...interface{}) error }) (models.Extra, error) {
+	var e models.Extra
+
+	err := row.Scan(
+		&e.ID,
+		&e.Name,
+		&e.Description,
+		&e.Kind,
+		&e.Price,
+		&e.Inventory,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+	)
+
+	return e, err
+}
+
+//Extras returns the catalog of extras
+func (m *postgresDBRepo) Extras() ([]models.Extra, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var extras []models.Extra
+
+	rows, err := m.DB.QueryContext(ctx, "select "+extraColumns+" from extras order by name")
+	if err != nil {
+		return extras, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanExtra(rows)
+		if err != nil {
+			return extras, err
+		}
+		extras = append(extras, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return extras, err
+	}
+
+	return extras, nil
+}
+
+//GetExtraByID returns one extra by id
+func (m *postgresDBRepo) GetExtraByID(id int) (models.Extra, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanExtra(m.DB.QueryRowContext(ctx, "select "+extraColumns+" from extras where id = $1", id))
+}
+
+//InsertExtra inserts an extra into the database
+func (m *postgresDBRepo) InsertExtra(e models.Extra) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var newID int
+
+	stmt := `
+		insert into extras (name, description, kind, price, inventory, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7) returning id
+	`
+
+	err := m.DB.QueryRowContext(ctx, stmt, e.Name, e.Description, e.Kind, e.Price, e.Inventory, time.Now(),
+		time.Now()).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+//UpdateExtra updates an extra in the database. Reservations keep the extras they booked
+func (m *postgresDBRepo) UpdateExtra(e models.Extra) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		update extras set name = $1, description = $2, kind = $3, price = $4, inventory = $5, updated_at = $6
+		where id = $7
+	`
+
+	_, err := m.DB.ExecContext(ctx, query, e.Name, e.Description, e.Kind, e.Price, e.Inventory, time.Now(), e.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//DeleteExtra deletes one extra by id, reservations keep the extras they booked
+func (m *postgresDBRepo) DeleteExtra(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "delete from extras where id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//insertReservationExtra records an extra booked with a reservation staying from start to end. The extra is
+//locked while the units booked for each night are counted, and ErrExtraSoldOut is returned when they would
+//go over its inventory
+func insertReservationExtra(ctx context.Context, tx *sql.Tx, reservationID int, start, end time.Time,
+	x models.ReservationExtra) error {
+	if x.ExtraID > 0 {
+		var inventory int
+		err := tx.QueryRowContext(ctx, "select inventory from extras where id = $1 for update", x.ExtraID).Scan(&inventory)
+		if err != nil {
+			return err
+		}
+
+		if inventory > 0 {
+			query := `
+				select coalesce(max(used), 0) from (
+					select sum(x.quantity) as used
+					from generate_series($2::date, $3::date - 1, interval '1 day') as d
+					join reservations r on (r.start_date <= d and r.end_date > d and r.status <> $4)
+					join reservation_extras x on (x.reservation_id = r.id and x.extra_id = $1)
+					group by d
+				) nights
+			`
+
+			var used int
+			err = tx.QueryRowContext(ctx, query, x.ExtraID, start, end, models.ReservationCancelled).Scan(&used)
+			if err != nil {
+				return err
+			}
+
+			if used+x.Quantity > inventory {
+				return repository.ErrExtraSoldOut
+			}
+		}
+	}
+
+	stmt := `
+		insert into reservation_extras (reservation_id, extra_id, name, quantity, amount, created_at, updated_at)
+		values ($1, nullif($2, 0), $3, $4, $5, $6, $7)
+	`
+
+	_, err := tx.ExecContext(ctx, stmt, reservationID, x.ExtraID, x.Name, x.Quantity, x.Amount, time.Now(), time.Now())
+
+	return err
+}
+
+//loadReservationExtras fills in the extras of reservations, selecting them with a condition on the
+//reservations table aliased r
+func (m *postgresDBRepo) loadReservationExtras(ctx context.Context, reservations []models.Reservation, condition string,
+	args ...interface{}) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(reservations))
+	for i, r := range reservations {
+		index[r.ID] = i
+	}
+
+	query := `
+		select x.reservation_id, x.id, coalesce(x.extra_id, 0), x.name, x.quantity, x.amount
+		from reservation_extras x
+		join reservations r on (x.reservation_id = r.id)
+		where ` + condition + `
+		order by x.id
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reservationID int
+		var x models.ReservationExtra
+		err := rows.Scan(&reservationID, &x.ID, &x.ExtraID, &x.Name, &x.Quantity, &x.Amount)
+		if err != nil {
+			return err
+		}
+
+		if i, ok := index[reservationID]; ok {
+			reservations[i].Extras = append(reservations[i].Extras, x)
+		}
+	}
+
+	return rows.Err()
+}
+
+//AddReservationExtra books an extra with an existing reservation and adds its amount to the total price,
+//ErrExtraSoldOut is returned when not enough units are left for its nights
+func (m *postgresDBRepo) AddReservationExtra(reservationID int, x models.ReservationExtra) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var start, end time.Time
+	err = tx.QueryRowContext(ctx, "select start_date, end_date from reservations where id = $1 for update",
+		reservationID).Scan(&start, &end)
+	if err != nil {
+		return err
+	}
+
+	if err = insertReservationExtra(ctx, tx, reservationID, start, end, x); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "update reservations set total_price = total_price + $1, updated_at = $2 where id = $3",
+		x.Amount, time.Now(), reservationID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+//DeleteReservationExtra removes an extra from a reservation and takes its amount off the total price
+func (m *postgresDBRepo) DeleteReservationExtra(id, reservationID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var amount int
+	err = tx.QueryRowContext(ctx, "delete from reservation_extras where id = $1 and reservation_id = $2 returning amount",
+		id, reservationID).Scan(&amount)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "update reservations set total_price = total_price - $1, updated_at = $2 where id = $3",
+		amount, time.Now(), reservationID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+//giftVoucherColumns are the columns scanned by scanGiftVoucher, the balance is what the ledger left of the amount
+const giftVoucherColumns = `v.id, v.code, v.amount,
+		v.amount - coalesce((select sum(x.amount) from voucher_redemptions x where x.gift_voucher_id = v.id), 0),
+		v.purchaser, v.recipient, v.expires_at, v.created_at, v.updated_at`
+
+func scanGiftVoucher(row interface{ Scan(...interface{}) error }) (models.GiftVoucher, error) {
+	var v models.GiftVoucher
+	var expiresAt sql.NullTime
+
+	err := row.Scan(
+		&v.ID,
+		&v.Code,
+		&v.Amount,
+		&v.Balance,
+		&v.Purchaser,
+		&v.Recipient,
+		&expiresAt,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+	)
+	if err != nil {
+		return v, err
+	}
+
+	v.ExpiresAt = expiresAt.Time
+
+	return v, nil
+}
+
+//GiftVouchers returns all gift vouchers with their balances, newest first
+func (m *postgresDBRepo) GiftVouchers() ([]models.GiftVoucher, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var vouchers []models.GiftVoucher
+
+	rows, err := m.DB.QueryContext(ctx, "select "+giftVoucherColumns+" from gift_vouchers v order by v.created_at desc, v.id desc")
+	if err != nil {
+		return vouchers, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		v, err := scanGiftVoucher(rows)
+		if err != nil {
+			return vouchers, err
+		}
+		vouchers = append(vouchers, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return vouchers, err
+	}
+
+	return vouchers, nil
+}
+
+//GetGiftVoucherByID returns one gift voucher by id with its ledger of redemptions, oldest first
+func (m *postgresDBRepo) GetGiftVoucherByID(id int) (models.GiftVoucher, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	v, err := scanGiftVoucher(m.DB.QueryRowContext(ctx, "select "+giftVoucherColumns+" from gift_vouchers v where v.id = $1", id))
+	if err != nil {
+		return v, err
+	}
+
+	query := `
+		select id, gift_voucher_id, coalesce(reservation_id, 0), coalesce(folio_entry_id, 0), amount, created_at
+		from voucher_redemptions where gift_voucher_id = $1
+		order by created_at, id
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return v, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var x models.VoucherRedemption
+		err := rows.Scan(&x.ID, &x.GiftVoucherID, &x.ReservationID, &x.FolioEntryID, &x.Amount, &x.CreatedAt)
+		if err != nil {
+			return v, err
+		}
+		v.Redemptions = append(v.Redemptions, x)
+	}
+
+	if err = rows.Err(); err != nil {
+		return v, err
+	}
+
+	return v, nil
+}
+
+//GetGiftVoucherByCode returns the gift voucher guests enter as code, in any case, returns false if there is none
+func (m *postgresDBRepo) GetGiftVoucherByCode(code string) (models.GiftVoucher, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	v, err := scanGiftVoucher(m.DB.QueryRowContext(ctx,
+		"select "+giftVoucherColumns+" from gift_vouchers v where upper(v.code) = upper($1)", code))
+	if err == sql.ErrNoRows {
+		return v, false, nil
+	}
+	if err != nil {
+		return v, false, err
+	}
+
+	return v, true, nil
+}
+
+//InsertGiftVoucher inserts a gift voucher into the database
+func (m *postgresDBRepo) InsertGiftVoucher(v models.GiftVoucher) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var newID int
+
+	stmt := `
+		insert into gift_vouchers (code, amount, purchaser, recipient, expires_at, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7) returning id
+	`
+
+	expiresAt := sql.NullTime{Time: v.ExpiresAt, Valid: !v.ExpiresAt.IsZero()}
+
+	err := m.DB.QueryRowContext(ctx, stmt, v.Code, v.Amount, v.Purchaser, v.Recipient, expiresAt, time.Now(),
+		time.Now()).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+//UpdateGiftVoucher updates who a gift voucher is from and for and when it expires. Its code and amount are
+//kept as sold
+func (m *postgresDBRepo) UpdateGiftVoucher(v models.GiftVoucher) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		update gift_vouchers set purchaser = $1, recipient = $2, expires_at = $3, updated_at = $4
+		where id = $5
+	`
+
+	expiresAt := sql.NullTime{Time: v.ExpiresAt, Valid: !v.ExpiresAt.IsZero()}
+
+	_, err := m.DB.ExecContext(ctx, query, v.Purchaser, v.Recipient, expiresAt, time.Now(), v.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//RedeemGiftVoucher pays an amount on the folio of a reservation with a gift voucher and records it on the
+//ledger of the voucher, returning the id of the payment. The voucher is locked while its balance is checked,
+//ErrVoucherBalance is returned when less than the amount is left on it
+func (m *postgresDBRepo) RedeemGiftVoucher(id, reservationID, amount int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "select id from gift_vouchers where id = $1 for update", id)
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := scanGiftVoucher(tx.QueryRowContext(ctx, "select "+giftVoucherColumns+" from gift_vouchers v where v.id = $1", id))
+	if err != nil {
+		return 0, err
+	}
+
+	if amount > v.Balance {
+		return 0, repository.ErrVoucherBalance
+	}
+
+	paymentID, err := insertFolioEntry(ctx, tx, models.FolioEntry{
+		ReservationID: reservationID,
+		Kind:          models.FolioPayment,
+		Description:   fmt.Sprintf("Gift voucher %s", v.Code),
+		Amount:        amount,
+		Status:        models.PaymentSucceeded,
+		Provider:      models.PaymentVoucher,
+		Reference:     v.Code,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err = insertVoucherRedemption(ctx, tx, v.ID, reservationID, paymentID, amount); err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return paymentID, nil
+}
+
+//RefundGiftVoucher enters the refund of a payment made with a gift voucher on a folio and puts the amount
+//back on the voucher, returning the id of the refund. ErrRefundTooLarge is returned when less than the amount
+//is left of the payment
+func (m *postgresDBRepo) RefundGiftVoucher(e models.FolioEntry) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err = lockRefundable(ctx, tx, e); err != nil {
+		return 0, err
+	}
+
+	var voucherID int
+	err = tx.QueryRowContext(ctx, "select id from gift_vouchers where code = $1 for update", e.Reference).Scan(&voucherID)
+	if err != nil {
+		return 0, err
+	}
+
+	refundID, err := insertFolioEntry(ctx, tx, e)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = insertVoucherRedemption(ctx, tx, voucherID, e.ReservationID, refundID, -e.Amount); err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return refundID, nil
+}
+
+//insertVoucherRedemption records an entry on the ledger of a gift voucher
+func insertVoucherRedemption(ctx context.Context, tx *sql.Tx, voucherID, reservationID, folioEntryID, amount int) error {
+	stmt := `
+		insert into voucher_redemptions (gift_voucher_id, reservation_id, folio_entry_id, amount, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := tx.ExecContext(ctx, stmt, voucherID, reservationID, folioEntryID, amount, time.Now(), time.Now())
+
+	return err
+}
+
+//exchangeRateColumns are the columns scanned by scanExchangeRate
+const exchangeRateColumns = "id, currency, rate, created_at, updated_at"
+
+func scanExchangeRate(row interface{ Scan(...interface{}) error }) (models.ExchangeRate, error) {
+	var r models.ExchangeRate
+
+	err := row.Scan(
+		&r.ID,
+		&r.Currency,
+		&r.Rate,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+
+	return r, err
+}
+
+//ExchangeRates returns the exchange rates of all display currencies
+func (m *postgresDBRepo) ExchangeRates() ([]models.ExchangeRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var rates []models.ExchangeRate
+
+	rows, err := m.DB.QueryContext(ctx, "select "+exchangeRateColumns+" from exchange_rates order by currency")
+	if err != nil {
+		return rates, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r, err := scanExchangeRate(rows)
+		if err != nil {
+			return rates, err
+		}
+		rates = append(rates, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return rates, err
+	}
+
+	return rates, nil
+}
+
+//GetExchangeRateByID returns one exchange rate by id
+func (m *postgresDBRepo) GetExchangeRateByID(id int) (models.ExchangeRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanExchangeRate(m.DB.QueryRowContext(ctx, "select "+exchangeRateColumns+" from exchange_rates where id = $1",
+		id))
+}
+
+//InsertExchangeRate inserts an exchange rate into the database
+func (m *postgresDBRepo) InsertExchangeRate(r models.ExchangeRate) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var newID int
+
+	stmt := `
+		insert into exchange_rates (currency, rate, created_at, updated_at)
+		values ($1, $2, $3, $4) returning id
+	`
+
+	err := m.DB.QueryRowContext(ctx, stmt, r.Currency, r.Rate, time.Now(), time.Now()).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+//UpdateExchangeRate updates an exchange rate in the database
+func (m *postgresDBRepo) UpdateExchangeRate(r models.ExchangeRate) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		update exchange_rates set currency = $1, rate = $2, updated_at = $3
+		where id = $4
+	`
+
+	_, err := m.DB.ExecContext(ctx, query, r.Currency, r.Rate, time.Now(), r.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//DeleteExchangeRate deletes one exchange rate by id
+func (m *postgresDBRepo) DeleteExchangeRate(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "delete from exchange_rates where id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
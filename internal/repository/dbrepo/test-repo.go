@@ -3,9 +3,11 @@ package dbrepo
 import (
 	"errors"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/yalagtyarzh/leafsite/internal/models"
+	"github.com/yalagtyarzh/leafsite/internal/repository"
 )
 
 func (m *testDBRepo) AllUsers() bool {
@@ -17,6 +19,17 @@ func (m *testDBRepo) InsertReservation(res models.Reservation) (int, error) {
 	if res.RoomID == 2 {
 		return 0, errors.New("some error")
 	}
+	if res.PromoCode == "RACE" {
+		return 0, repository.ErrPromoCodeUsedUp
+	}
+	if res.WaitlistEntryID == 3 {
+		return 0, repository.ErrWaitlistOfferUsed
+	}
+	for _, x := range res.Extras {
+		if x.ExtraID == 2 && x.Quantity > 5 {
+			return 0, repository.ErrExtraSoldOut
+		}
+	}
 	return 1, nil
 }
 
@@ -88,7 +101,7 @@ func (m *testDBRepo) SearchAvailabilityForAllRooms(start, end time.Time) ([]mode
 //GetRoomByID gets a room by ID
 func (m *testDBRepo) GetRoomByID(id int) (models.Room, error) {
 	var room models.Room
-	if id > 2 {
+	if id > 2 && id != 1000 {
 		return room, errors.New("some error")
 	}
 
@@ -111,23 +124,82 @@ func (m *testDBRepo) Authenticate(email, testPassword string) (int, string, erro
 	return 0, "", errors.New("some error")
 }
 
-//AllReservations returns a slice of all reservations
-func (m *testDBRepo) AllReservations() ([]models.Reservation, error) {
+//SearchReservations returns a page of the reservations matching the filter and how many match in total.
+//Unknown sort columns sort by arrival
+func (m *testDBRepo) SearchReservations(f models.ReservationFilter, p models.Page) ([]models.Reservation, int, error) {
 	var reservations []models.Reservation
 
-	return reservations, nil
-}
+	if f.RoomID > 2 {
+		return reservations, 0, errors.New("some error")
+	}
 
-//AllNewReservations returns a slice of all reservations
-func (m *testDBRepo) AllNewReservations() ([]models.Reservation, error) {
-	var reservations []models.Reservation
+	for i := 1; i <= 2; i++ {
+		reservations = append(reservations, models.Reservation{
+			ID:         i,
+			FirstName:  "John",
+			LastName:   "Smith",
+			Email:      "john@smith.com",
+			RoomID:     1,
+			Room:       models.Room{ID: 1, RoomName: "General's Quarters"},
+			StartDate:  time.Date(2050, 1, i, 0, 0, 0, 0, time.UTC),
+			EndDate:    time.Date(2050, 1, i+2, 0, 0, 0, 0, time.UTC),
+			TotalPrice: 20000,
+			Status:     models.ReservationConfirmed,
+		})
+	}
 
-	return reservations, nil
+	return reservations, 60, nil
 }
 
 //GetReservationById returns one reservatin by id
 func (m *testDBRepo) GetReservationByID(id int) (models.Reservation, error) {
 	var res models.Reservation
+	if id > 1000 {
+		return res, errors.New("some error")
+	}
+
+	res.ID = id
+	res.RoomID = 1
+	res.Room.ID = 1
+	res.Room.CancellationPolicyID = 1
+	res.StartDate = time.Now().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	res.EndDate = time.Now().Truncate(24*time.Hour).AddDate(0, 0, 3)
+	res.TotalPrice = 20000
+	res.Status = models.ReservationConfirmed
+
+	if id == 100 {
+		res.Status = models.ReservationCancelled
+	}
+
+	if id == 1 {
+		res.Tags = []string{"VIP"}
+		res.GuestID = 1
+		res.Guests = 2
+		res.Taxes = []models.ReservationTax{
+			{TaxRuleID: 1, Name: "VAT", Amount: 3167, Inclusive: true},
+			{TaxRuleID: 2, Name: "Tourist levy", Amount: 1000},
+		}
+		res.Adjustments = []models.PriceAdjustment{
+			{PriceRuleID: 1, Name: "Busy", Amount: 1000},
+		}
+		res.Extras = []models.ReservationExtra{
+			{ID: 1, ExtraID: 1, Name: "Breakfast", Quantity: 1, Amount: 2000},
+		}
+	}
+
+	//3 arrives today, 4 arrived yesterday and is checked in
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if id == 3 {
+		res.StartDate = today
+		res.EndDate = today.AddDate(0, 0, 2)
+	}
+
+	if id == 4 {
+		res.StartDate = today.AddDate(0, 0, -1)
+		res.EndDate = today
+		res.CheckedInAt = today.AddDate(0, 0, -1).Add(15 * time.Hour)
+	}
 
 	return res, nil
 }
@@ -150,6 +222,13 @@ func (m *testDBRepo) UpdateProcessedForReservation(id, processed int) error {
 func (m *testDBRepo) AllRooms() ([]models.Room, error) {
 	var rooms []models.Room
 
+	rooms = append(rooms, models.Room{
+		ID:                 1,
+		RoomName:           "General's Quarters",
+		Price:              10000,
+		HousekeepingStatus: models.RoomClean,
+	})
+
 	return rooms, nil
 }
 
@@ -169,3 +248,1276 @@ func (m *testDBRepo) InsertBlockForRoom(id int, startDate time.Time) error {
 func (m *testDBRepo) DeleteBlockByID(id int) error {
 	return nil
 }
+
+//AvailabilityCalendarByRoomID returns availability and price for every night from start up to, but not including, end
+func (m *testDBRepo) AvailabilityCalendarByRoomID(roomID int, start, end time.Time) ([]models.NightAvailability, error) {
+	var nights []models.NightAvailability
+
+	if roomID > 2 {
+		return nights, errors.New("some error")
+	}
+
+	layout := "2006-01-02"
+	t, err := time.Parse(layout, "2029-12-31")
+	if err != nil {
+		log.Println(err)
+	}
+
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		nights = append(nights, models.NightAvailability{
+			Date:      d,
+			Available: !d.After(t),
+			Price:     100,
+		})
+	}
+
+	return nights, nil
+}
+
+//CancelReservation marks a reservation as cancelled with a fee and frees its room restrictions
+func (m *testDBRepo) CancelReservation(id, fee int) error {
+	if id == 1000 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//AllCancellationPolicies returns a slice of all cancellation policies
+func (m *testDBRepo) AllCancellationPolicies() ([]models.CancellationPolicy, error) {
+	var policies []models.CancellationPolicy
+
+	return policies, nil
+}
+
+//GetCancellationPolicyByID returns one cancellation policy by id
+func (m *testDBRepo) GetCancellationPolicyByID(id int) (models.CancellationPolicy, error) {
+	var p models.CancellationPolicy
+	if id > 100 {
+		return p, errors.New("some error")
+	}
+
+	p.ID = id
+	p.Name = "Flexible"
+	p.FreeDaysBefore = 2
+	p.FeeType = models.CancellationFeeFirstNight
+
+	return p, nil
+}
+
+//InsertCancellationPolicy inserts a cancellation policy into the database
+func (m *testDBRepo) InsertCancellationPolicy(p models.CancellationPolicy) (int, error) {
+	return 1, nil
+}
+
+//UpdateCancellationPolicy updates a cancellation policy in the database
+func (m *testDBRepo) UpdateCancellationPolicy(p models.CancellationPolicy) error {
+	return nil
+}
+
+//DeleteCancellationPolicy deletes one cancellation policy by id
+func (m *testDBRepo) DeleteCancellationPolicy(id int) error {
+	return nil
+}
+
+//UpdateCancellationPolicyForRoom sets the cancellation policy of a room, zero policyID removes it
+func (m *testDBRepo) UpdateCancellationPolicyForRoom(roomID, policyID int) error {
+	return nil
+}
+
+//ChangeReservationStay moves a reservation and its room restriction to new dates and room, returns false if
+//the room is not available for them, ignoring the reservation itself. ErrExtraSoldOut is returned when not
+//enough of an extra booked is left for the new nights
+func (m *testDBRepo) ChangeReservationStay(res models.Reservation) (bool, error) {
+	if res.RoomID == 1000 {
+		return false, errors.New("some error")
+	}
+
+	if res.StartDate.Format("2006-01-02") == "2029-02-01" && len(res.Extras) > 0 {
+		return false, repository.ErrExtraSoldOut
+	}
+
+	t, err := time.Parse("2006-01-02", "2029-12-31")
+	if err != nil {
+		log.Println(err)
+	}
+
+	if res.StartDate.After(t) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+//InsertHold places a temporary hold on a room for the restriction dates, returns false if the room is not
+//available for them
+func (m *testDBRepo) InsertHold(r models.RoomRestriction) (int, bool, error) {
+	if r.RoomID == 1000 {
+		return 0, false, errors.New("some error")
+	}
+
+	t, err := time.Parse("2006-01-02", "2029-12-31")
+	if err != nil {
+		log.Println(err)
+	}
+
+	if r.StartDate.After(t) {
+		return 0, false, nil
+	}
+
+	return 1, true, nil
+}
+
+//HoldIsActive returns true if a hold exists, has not expired yet and is on the room and dates of r
+func (m *testDBRepo) HoldIsActive(id int, r models.RoomRestriction) (bool, error) {
+	if id == 1000 {
+		return false, errors.New("some error")
+	}
+
+	//hold 1 is on room 1 from 2030-01-01 to 2030-01-02
+	return id == 1 && r.RoomID == 1 && r.StartDate.Format("2006-01-02") == "2030-01-01" &&
+		r.EndDate.Format("2006-01-02") == "2030-01-02", nil
+}
+
+//DeleteHoldByID releases a hold
+func (m *testDBRepo) DeleteHoldByID(id int) error {
+	return nil
+}
+
+//DeleteExpiredHolds releases all expired holds and returns how many were released
+func (m *testDBRepo) DeleteExpiredHolds() (int64, error) {
+	return 0, nil
+}
+
+//InsertWaitlistEntry puts a guest on the waitlist
+func (m *testDBRepo) InsertWaitlistEntry(e models.WaitlistEntry) (int, error) {
+	if e.RoomID == 1000 {
+		return 0, errors.New("some error")
+	}
+
+	return 1, nil
+}
+
+//GetWaitlistEntryByID returns one waitlist entry by id
+func (m *testDBRepo) GetWaitlistEntryByID(id int) (models.WaitlistEntry, error) {
+	var e models.WaitlistEntry
+	if id > 100 {
+		return e, errors.New("some error")
+	}
+
+	e.ID = id
+	e.Email = "john@smith.com"
+	e.StartDate = time.Now().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	e.EndDate = time.Now().Truncate(24*time.Hour).AddDate(0, 0, 3)
+	e.Status = models.WaitlistNotified
+	e.OfferedRoomID = 1
+
+	if id == 2 {
+		e.Status = models.WaitlistWaiting
+		e.OfferedRoomID = 0
+	}
+
+	return e, nil
+}
+
+//AllWaitlistEntries returns all waitlist entries in the order guests joined
+func (m *testDBRepo) AllWaitlistEntries() ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	return entries, nil
+}
+
+//WaitingEntriesForRoom returns the entries still waiting for a room, or for any room, whose stay overlaps
+//the given dates, in the order guests joined
+func (m *testDBRepo) WaitingEntriesForRoom(roomID int, start, end time.Time) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	if roomID > 2 {
+		return entries, errors.New("some error")
+	}
+
+	entries = append(entries, models.WaitlistEntry{
+		ID:        1,
+		Email:     "john@smith.com",
+		StartDate: start,
+		EndDate:   end,
+		Status:    models.WaitlistWaiting,
+	})
+
+	return entries, nil
+}
+
+//MarkWaitlistEntryNotified records that a guest has been sent a booking link for a room, valid until expires
+func (m *testDBRepo) MarkWaitlistEntryNotified(id, roomID int, expires time.Time) error {
+	return nil
+}
+
+//DeclineWaitlistOffer records that a guest turned down the room offered, returns false if the offer wasn't
+//open anymore
+func (m *testDBRepo) DeclineWaitlistOffer(id int) (bool, error) {
+	if id == 3 {
+		return false, errors.New("some error")
+	}
+
+	return id == 1, nil
+}
+
+//ExpireWaitlistOffers closes the offers whose booking link has expired and returns their entries, so the
+//rooms can be offered to the next guests in line
+func (m *testDBRepo) ExpireWaitlistOffers() ([]models.WaitlistEntry, error) {
+	return []models.WaitlistEntry{
+		{ID: 3, Email: "jane@smith.com", StartDate: time.Now().AddDate(0, 0, 1), EndDate: time.Now().AddDate(0, 0, 3),
+			Status: models.WaitlistExpired, OfferedRoomID: 1},
+	}, nil
+}
+
+//DeleteWaitlistEntry removes a guest from the waitlist
+func (m *testDBRepo) DeleteWaitlistEntry(id int) error {
+	return nil
+}
+
+//DashboardCounts returns the arrivals, departures and in-house reservations of a day, together with the
+//number of new reservations
+func (m *testDBRepo) DashboardCounts(day time.Time) (models.DashboardCounts, error) {
+	return models.DashboardCounts{
+		Arrivals:        1,
+		Departures:      1,
+		InHouse:         2,
+		NewReservations: 3,
+	}, nil
+}
+
+//OccupiedRoomsByNight returns the number of rooms taken by reservations for every night from start up to end
+func (m *testDBRepo) OccupiedRoomsByNight(start, end time.Time) ([]models.DailyCount, error) {
+	var counts []models.DailyCount
+
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		counts = append(counts, models.DailyCount{Date: d, Count: 1})
+	}
+
+	return counts, nil
+}
+
+//ReservationsCreatedByDay returns the number of reservations made on every day from start up to end
+func (m *testDBRepo) ReservationsCreatedByDay(start, end time.Time) ([]models.DailyCount, error) {
+	var counts []models.DailyCount
+
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		counts = append(counts, models.DailyCount{Date: d, Count: 2})
+	}
+
+	return counts, nil
+}
+
+//ReservationsForReport returns all reservations, cancelled ones included, staying any night from start up to end
+func (m *testDBRepo) ReservationsForReport(start, end time.Time) ([]models.Reservation, error) {
+	var reservations []models.Reservation
+
+	t, err := time.Parse("2006-01-02", "2040-01-01")
+	if err != nil {
+		log.Println(err)
+	}
+
+	if start.Equal(t) {
+		return reservations, errors.New("some error")
+	}
+
+	reservations = append(reservations, models.Reservation{
+		ID:         1,
+		RoomID:     1,
+		StartDate:  start,
+		EndDate:    start.AddDate(0, 0, 2),
+		CreatedAt:  start.AddDate(0, 0, -10),
+		TotalPrice: 20000,
+		Status:     models.ReservationConfirmed,
+		Taxes: []models.ReservationTax{
+			{TaxRuleID: 1, Name: "VAT", Amount: 3000, Inclusive: true},
+			{TaxRuleID: 2, Name: "Tourist levy", Amount: 1000},
+		},
+	})
+
+	return reservations, nil
+}
+
+//EachReservation calls fn for every reservation matching the filter, reading one row at a time,
+//and stops at the first error returned by fn
+func (m *testDBRepo) EachReservation(f models.ReservationFilter, fn func(models.Reservation) error) error {
+	if f.RoomID > 2 {
+		return errors.New("some error")
+	}
+
+	for i := 1; i <= 2; i++ {
+		err := fn(models.Reservation{
+			ID:         i,
+			FirstName:  "John",
+			LastName:   "Smith",
+			Email:      "john@smith.com",
+			RoomID:     1,
+			Room:       models.Room{ID: 1, RoomName: "General's Quarters"},
+			StartDate:  time.Date(2050, 1, i, 0, 0, 0, 0, time.UTC),
+			EndDate:    time.Date(2050, 1, i+2, 0, 0, 0, 0, time.UTC),
+			TotalPrice: 20000,
+			Status:     models.ReservationConfirmed,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if f.RoomID == 2 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//EachGuestContact calls fn for every guest, identified by email, with the contact details of the latest
+//reservation, reading one row at a time, and stops at the first error returned by fn
+func (m *testDBRepo) EachGuestContact(fn func(models.GuestContact) error) error {
+	return fn(models.GuestContact{
+		FirstName:    "John",
+		LastName:     "Smith",
+		Email:        "john@smith.com",
+		Reservations: 2,
+	})
+}
+
+//ImportReservations saves reservations together with their room restrictions in a single transaction,
+//returns false and saves nothing if any of the rooms is not available for its dates
+func (m *testDBRepo) ImportReservations(res []models.Reservation) (bool, error) {
+	for _, r := range res {
+		if r.FirstName == "Error" {
+			return false, errors.New("some error")
+		}
+
+		if r.FirstName == "Taken" {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+//InsertReservationNote adds a note to a reservation
+func (m *testDBRepo) InsertReservationNote(n models.ReservationNote) error {
+	if n.ReservationID == 1000 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//NotesForReservation returns the notes of a reservation with their authors, newest first
+func (m *testDBRepo) NotesForReservation(reservationID int) ([]models.ReservationNote, error) {
+	var notes []models.ReservationNote
+	if reservationID == 1000 {
+		return notes, errors.New("some error")
+	}
+
+	notes = append(notes, models.ReservationNote{
+		ID:            1,
+		ReservationID: reservationID,
+		UserID:        1,
+		Body:          "Arrives late, keep the key at the desk",
+		CreatedAt:     time.Now(),
+		User:          models.User{ID: 1, FirstName: "Admin", LastName: "User"},
+	})
+
+	return notes, nil
+}
+
+//DeleteReservationNote deletes a note of a reservation
+func (m *testDBRepo) DeleteReservationNote(id, reservationID int) error {
+	if reservationID == 1000 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//UpdateReservationTags replaces the tags of a reservation, along with its taxes and total price as tags can
+//exempt it from taxes
+func (m *testDBRepo) UpdateReservationTags(res models.Reservation) error {
+	if res.ID == 1000 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//AllTags returns every tag used on reservations in alphabetical order
+func (m *testDBRepo) AllTags() ([]string, error) {
+	return []string{"VIP", "dog", "late arrival"}, nil
+}
+
+//SearchGuests returns a page of the guests whose name, email or phone contains the search text and how
+//many match in total. Unknown sort columns sort by last name
+func (m *testDBRepo) SearchGuests(search string, p models.Page) ([]models.Guest, int, error) {
+	if search == "error" {
+		return nil, 0, errors.New("some error")
+	}
+
+	g1, _ := m.GetGuestByID(1)
+	g2, _ := m.GetGuestByID(2)
+
+	return []models.Guest{g1, g2}, 2, nil
+}
+
+//GetGuestByID returns one guest by id
+func (m *testDBRepo) GetGuestByID(id int) (models.Guest, error) {
+	if id > 2 {
+		return models.Guest{}, errors.New("some error")
+	}
+
+	return models.Guest{
+		ID:            id,
+		FirstName:     "John",
+		LastName:      "Smith",
+		Email:         "john@smith.com",
+		Stays:         2,
+		Nights:        4,
+		LifetimeValue: 40000,
+		LastStay:      time.Date(2050, 1, 2, 0, 0, 0, 0, time.UTC),
+	}, nil
+}
+
+//ReservationsForGuest returns the reservations of a guest, latest arrival first
+func (m *testDBRepo) ReservationsForGuest(guestID int) ([]models.Reservation, error) {
+	var reservations []models.Reservation
+
+	reservations = append(reservations, models.Reservation{
+		ID:         1,
+		FirstName:  "John",
+		LastName:   "Smith",
+		Email:      "john@smith.com",
+		RoomID:     1,
+		Room:       models.Room{ID: 1, RoomName: "General's Quarters"},
+		StartDate:  time.Date(2050, 1, 2, 0, 0, 0, 0, time.UTC),
+		EndDate:    time.Date(2050, 1, 4, 0, 0, 0, 0, time.UTC),
+		TotalPrice: 20000,
+		Status:     models.ReservationConfirmed,
+		GuestID:    guestID,
+	})
+
+	return reservations, nil
+}
+
+//PossibleDuplicateGuests returns the other guests with the same name, email or phone number as a guest
+func (m *testDBRepo) PossibleDuplicateGuests(g models.Guest) ([]models.Guest, error) {
+	if g.ID != 1 {
+		return nil, nil
+	}
+
+	dup, _ := m.GetGuestByID(2)
+
+	return []models.Guest{dup}, nil
+}
+
+//MergeGuests moves the reservations of a duplicate guest to the guest kept, fills in contact details the
+//kept guest lacks and deletes the duplicate
+func (m *testDBRepo) MergeGuests(keepID, mergeID int) error {
+	if keepID == mergeID || mergeID > 2 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//ArrivalsForDay returns the reservations arriving on a day that aren't cancelled
+func (m *testDBRepo) ArrivalsForDay(day time.Time) ([]models.Reservation, error) {
+	if day.Year() == 2040 {
+		return nil, errors.New("some error")
+	}
+
+	res, _ := m.GetReservationByID(3)
+
+	return []models.Reservation{res}, nil
+}
+
+//DeparturesForDay returns the reservations departing on a day that aren't cancelled
+func (m *testDBRepo) DeparturesForDay(day time.Time) ([]models.Reservation, error) {
+	res, _ := m.GetReservationByID(4)
+
+	return []models.Reservation{res}, nil
+}
+
+//CheckIn records the time a guest arrived, returns false if the reservation isn't confirmed or was
+//checked in already
+func (m *testDBRepo) CheckIn(id int, at time.Time) (bool, error) {
+	if id == 1000 {
+		return false, errors.New("some error")
+	}
+
+	return id != 4, nil
+}
+
+//CheckOut records the time a guest left and marks the room dirty, returns false if the reservation isn't
+//checked in or was checked out already
+func (m *testDBRepo) CheckOut(id int, at time.Time) (bool, error) {
+	if id == 1000 {
+		return false, errors.New("some error")
+	}
+
+	return id == 4, nil
+}
+
+//MarkNoShow marks a confirmed reservation that wasn't checked in as a no-show, returns false if it can't be
+func (m *testDBRepo) MarkNoShow(id int) (bool, error) {
+	if id == 1000 {
+		return false, errors.New("some error")
+	}
+
+	return id != 4, nil
+}
+
+//MarkNoShows marks the confirmed reservations arriving on a day that weren't checked in as no-shows and
+//returns how many there were
+func (m *testDBRepo) MarkNoShows(arrival time.Time) (int64, error) {
+	if arrival.Year() == 2040 {
+		return 0, errors.New("some error")
+	}
+
+	//one guest didn't arrive on 2050-01-01
+	if arrival.Equal(time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+//HousekeepingBoard returns every room with its housekeeping status and the stays of confirmed reservations
+//departing, arriving and going on during a day
+func (m *testDBRepo) HousekeepingBoard(day time.Time) ([]models.HousekeepingRoom, error) {
+	if day.Year() == 2040 {
+		return nil, errors.New("some error")
+	}
+
+	return []models.HousekeepingRoom{
+		{
+			Room:      models.Room{ID: 1, RoomName: "General's Quarters", HousekeepingStatus: models.RoomClean},
+			Departing: true,
+			Arriving:  true,
+			Occupied:  true,
+		},
+		{
+			Room: models.Room{ID: 2, RoomName: "Major's Suite", HousekeepingStatus: models.RoomOutOfOrder},
+		},
+	}, nil
+}
+
+//UpdateRoomHousekeepingStatus sets the housekeeping status of a room
+func (m *testDBRepo) UpdateRoomHousekeepingStatus(roomID int, status string) error {
+	if roomID > 2 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//ListUsers returns all users by name
+func (m *testDBRepo) ListUsers() ([]models.User, error) {
+	return []models.User{
+		{ID: 1, FirstName: "Admin", LastName: "User", Email: "admin@admin.com", AccessLevel: 3},
+	}, nil
+}
+
+//MaintenanceTickets returns the maintenance tickets with a status, or all tickets that aren't closed if the
+//status is empty, most urgent first
+func (m *testDBRepo) MaintenanceTickets(status string) ([]models.MaintenanceTicket, error) {
+	t, _ := m.GetMaintenanceTicketByID(1)
+
+	return []models.MaintenanceTicket{t}, nil
+}
+
+//GetMaintenanceTicketByID returns one maintenance ticket by id
+func (m *testDBRepo) GetMaintenanceTicketByID(id int) (models.MaintenanceTicket, error) {
+	if id > 2 {
+		return models.MaintenanceTicket{}, errors.New("some error")
+	}
+
+	start := time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t := models.MaintenanceTicket{
+		ID:                id,
+		RoomID:            1,
+		Title:             "Leaking tap",
+		Priority:          models.MaintenanceHigh,
+		Status:            models.MaintenanceOpen,
+		AssigneeID:        1,
+		OfflineStart:      start,
+		OfflineEnd:        start.AddDate(0, 0, 2),
+		RoomRestrictionID: 1,
+		Room:              models.Room{ID: 1, RoomName: "General's Quarters"},
+		Assignee:          models.User{ID: 1, FirstName: "Admin", LastName: "User"},
+	}
+
+	if id == 2 {
+		t.Status = models.MaintenanceClosed
+		t.OfflineStart = time.Time{}
+		t.OfflineEnd = time.Time{}
+		t.RoomRestrictionID = 0
+		t.ClosedAt = start
+	}
+
+	return t, nil
+}
+
+//InsertMaintenanceTicket adds a maintenance ticket and blocks its room for the offline dates, returns false
+//if the room isn't available for them
+func (m *testDBRepo) InsertMaintenanceTicket(t models.MaintenanceTicket) (int, bool, error) {
+	if t.Title == "error" {
+		return 0, false, errors.New("some error")
+	}
+
+	if t.RoomID == 2 && t.Offline() {
+		return 0, false, nil
+	}
+
+	return 3, true, nil
+}
+
+//UpdateMaintenanceTicket updates a maintenance ticket and replaces the block on its room, which is released
+//once the ticket is closed. It returns false if the room isn't available for the offline dates
+func (m *testDBRepo) UpdateMaintenanceTicket(t models.MaintenanceTicket) (bool, error) {
+	if t.Title == "error" {
+		return false, errors.New("some error")
+	}
+
+	if t.RoomID == 2 && t.Offline() && t.Status != models.MaintenanceClosed {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+//InsertFolioEntry adds a charge or payment to the folio of a reservation
+func (m *testDBRepo) InsertFolioEntry(e models.FolioEntry) (int, error) {
+	if e.Description == "error" {
+		return 0, errors.New("some error")
+	}
+
+	return 1, nil
+}
+
+//FolioEntries returns the charges and payments on the folio of a reservation, oldest first
+func (m *testDBRepo) FolioEntries(reservationID int) ([]models.FolioEntry, error) {
+	if reservationID == 999 {
+		return nil, errors.New("some error")
+	}
+
+	entries := []models.FolioEntry{
+		{
+			ID:            1,
+			ReservationID: reservationID,
+			Kind:          models.FolioPayment,
+			Description:   "Deposit",
+			Amount:        4000,
+			Status:        models.PaymentSucceeded,
+			Provider:      "fake",
+			Reference:     "fake_1",
+			CreatedAt:     time.Now(),
+		},
+		{
+			ID:            2,
+			ReservationID: reservationID,
+			Kind:          models.FolioCharge,
+			Description:   "Minibar",
+			Amount:        1500,
+			Status:        models.PaymentSucceeded,
+			CreatedAt:     time.Now(),
+		},
+		{
+			ID:            3,
+			ReservationID: reservationID,
+			Kind:          models.FolioPayment,
+			Description:   "Balance",
+			Amount:        17500,
+			Status:        models.PaymentPending,
+			Provider:      "fake",
+			Reference:     "fake_2",
+			CheckoutURL:   "http://localhost:8080/payments/fake/fake_2",
+			CreatedAt:     time.Now(),
+		},
+		{
+			ID:            4,
+			ReservationID: reservationID,
+			Kind:          models.FolioPayment,
+			Description:   "Cash",
+			Amount:        1000,
+			Status:        models.PaymentSucceeded,
+			Provider:      models.PaymentManual,
+			CreatedAt:     time.Now(),
+		},
+		{
+			ID:            5,
+			ReservationID: reservationID,
+			PaymentID:     1,
+			Kind:          models.FolioRefund,
+			Description:   "Refund",
+			Amount:        500,
+			Status:        models.PaymentSucceeded,
+			Provider:      "fake",
+			Reference:     "fake_3",
+			CreatedAt:     time.Now(),
+		},
+	}
+
+	//2 was partly paid with a gift voucher
+	if reservationID == 2 {
+		entries = append(entries, models.FolioEntry{
+			ID:            6,
+			ReservationID: reservationID,
+			Kind:          models.FolioPayment,
+			Description:   "Gift voucher GIFT-50",
+			Amount:        2000,
+			Status:        models.PaymentSucceeded,
+			Provider:      models.PaymentVoucher,
+			Reference:     "GIFT-50",
+			CreatedAt:     time.Now(),
+		})
+	}
+
+	return entries, nil
+}
+
+//UpdatePaymentStatus settles a pending payment or refund made through a provider, returns false if there
+//is none pending with the reference
+func (m *testDBRepo) UpdatePaymentStatus(provider, reference, status string) (bool, error) {
+	if reference == "fake_error" {
+		return false, errors.New("some error")
+	}
+
+	return true, nil
+}
+
+//InsertRefund enters the refund of a payment on the folio of a reservation, returning its id
+func (m *testDBRepo) InsertRefund(e models.FolioEntry) (int, error) {
+	switch e.Description {
+	case "Refund: error":
+		return 0, errors.New("some error")
+	case "Refund: refunded meanwhile":
+		return 0, repository.ErrRefundTooLarge
+	case "Refund: update error":
+		//refund 2 can't be updated once the provider has answered
+		return 2, nil
+	}
+
+	return 1, nil
+}
+
+//UpdateRefund sets the status and provider reference of a refund once the provider has answered
+func (m *testDBRepo) UpdateRefund(id int, status, reference string) error {
+	if id == 2 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//InsertInvoice issues an invoice, giving it the number after the last one issued and returning it with its
+//number and date
+func (m *testDBRepo) InsertInvoice(inv models.Invoice) (models.Invoice, error) {
+	if inv.Buyer.Name == "error" {
+		return inv, errors.New("some error")
+	}
+
+	inv.ID = 1
+	inv.Number = 1
+	inv.IssuedAt = time.Now()
+
+	return inv, nil
+}
+
+//GetInvoiceByNumber returns an invoice with its lines
+func (m *testDBRepo) GetInvoiceByNumber(number int) (models.Invoice, error) {
+	if number > 1000 {
+		return models.Invoice{}, errors.New("some error")
+	}
+
+	return models.Invoice{
+		ID:            number,
+		Number:        number,
+		ReservationID: 1,
+		Seller:        models.BillingDetails{Name: "Fort Smythe B&B", VATNumber: "GB123"},
+		Buyer:         models.BillingDetails{Name: "John Smith", Company: "Acme"},
+		Lines: []models.InvoiceLine{
+			{Description: "Stay in General's Quarters", Amount: 20000},
+			{Description: "Minibar", Amount: 1500},
+		},
+		Taxes: []models.InvoiceLine{
+			{Description: "VAT", Amount: 3333},
+		},
+		Total:    21500,
+		Paid:     4500,
+		IssuedAt: time.Now(),
+	}, nil
+}
+
+//InvoicesForReservation returns the invoices issued for a reservation without their lines, oldest first
+func (m *testDBRepo) InvoicesForReservation(reservationID int) ([]models.Invoice, error) {
+	if reservationID == 998 {
+		return nil, errors.New("some error")
+	}
+
+	return []models.Invoice{
+		{
+			ID:            1,
+			Number:        1,
+			ReservationID: reservationID,
+			Buyer:         models.BillingDetails{Name: "John Smith", Company: "Acme"},
+			Total:         21500,
+			IssuedAt:      time.Now(),
+		},
+	}, nil
+}
+
+//TaxRules returns all tax rules in the order they are applied
+func (m *testDBRepo) TaxRules() ([]models.TaxRule, error) {
+	return []models.TaxRule{
+		{ID: 1, Name: "VAT", Kind: models.TaxPercent, Rate: 2000, Inclusive: true},
+		{ID: 2, Name: "Tourist levy", Kind: models.TaxPerGuestNight, Rate: 250, MaxNights: 7, ExemptTag: "Resident"},
+	}, nil
+}
+
+//GetTaxRuleByID returns one tax rule by id
+func (m *testDBRepo) GetTaxRuleByID(id int) (models.TaxRule, error) {
+	var r models.TaxRule
+	if id > 100 {
+		return r, errors.New("some error")
+	}
+
+	r.ID = id
+	r.Name = "Tourist levy"
+	r.Kind = models.TaxPerGuestNight
+	r.Rate = 250
+
+	return r, nil
+}
+
+//InsertTaxRule inserts a tax rule into the database
+func (m *testDBRepo) InsertTaxRule(r models.TaxRule) (int, error) {
+	if r.Name == "error" {
+		return 0, errors.New("some error")
+	}
+
+	return 1, nil
+}
+
+//UpdateTaxRule updates a tax rule in the database. Reservations keep the taxes they were charged
+func (m *testDBRepo) UpdateTaxRule(r models.TaxRule) error {
+	if r.Name == "error" {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//DeleteTaxRule deletes one tax rule by id
+func (m *testDBRepo) DeleteTaxRule(id int) error {
+	if id > 100 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//GetPromoCodeByID returns one promo code by id
+func (m *testDBRepo) GetPromoCodeByID(id int) (models.PromoCode, error) {
+	var p models.PromoCode
+	if id > 100 {
+		return p, errors.New("some error")
+	}
+
+	p.ID = id
+	p.Code = "SUMMER"
+	p.Kind = models.PromoPercent
+	p.Amount = 1000
+	p.RoomIDs = []int{1}
+
+	return p, nil
+}
+
+//GetPromoCodeByCode returns the promo code guests enter as code, in any case, returns false if there is none
+func (m *testDBRepo) GetPromoCodeByCode(code string) (models.PromoCode, bool, error) {
+	switch strings.ToUpper(code) {
+	case "SUMMER", "RACE":
+		return models.PromoCode{ID: 1, Code: strings.ToUpper(code), Kind: models.PromoPercent, Amount: 1000}, true, nil
+	case "EXPIRED":
+		return models.PromoCode{ID: 2, Code: "EXPIRED", Kind: models.PromoFixed, Amount: 2500,
+			ValidTo: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}, true, nil
+	case "ERROR":
+		return models.PromoCode{}, false, errors.New("some error")
+	}
+
+	return models.PromoCode{}, false, nil
+}
+
+//InsertPromoCode inserts a promo code with its rooms into the database
+func (m *testDBRepo) InsertPromoCode(p models.PromoCode) (int, error) {
+	if p.Code == "ERROR" {
+		return 0, errors.New("some error")
+	}
+
+	return 1, nil
+}
+
+//UpdatePromoCode updates a promo code and its rooms in the database, leaving its uses alone
+func (m *testDBRepo) UpdatePromoCode(p models.PromoCode) error {
+	if p.Code == "ERROR" {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//DeletePromoCode deletes one promo code by id, reservations keep the code they were made with
+func (m *testDBRepo) DeletePromoCode(id int) error {
+	if id > 100 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//PromoCodeUsage returns how every promo code was used by the reservations made from start up to end
+func (m *testDBRepo) PromoCodeUsage(start, end time.Time) ([]models.PromoCodeUsage, error) {
+	if start.Year() == 2040 {
+		return nil, errors.New("some error")
+	}
+
+	return []models.PromoCodeUsage{
+		{
+			PromoCode:     models.PromoCode{ID: 1, Code: "SUMMER", Kind: models.PromoPercent, Amount: 1000, Uses: 4},
+			Reservations:  4,
+			Cancellations: 1,
+			Discount:      6000,
+			Revenue:       54000,
+		},
+	}, nil
+}
+
+//PriceRules returns all price rules in the order they are taken on ties
+func (m *testDBRepo) PriceRules() ([]models.PriceRule, error) {
+	return []models.PriceRule{
+		{ID: 1, Name: "Weekly", Kind: models.PriceLengthOfStay, Threshold: 7, Adjustment: -1000},
+		{ID: 2, Name: "Last minute", Kind: models.PriceLastMinute, Threshold: 2, Adjustment: -1500},
+		{ID: 3, Name: "Busy", Kind: models.PriceOccupancy, Threshold: 100, Adjustment: 2000},
+	}, nil
+}
+
+//GetPriceRuleByID returns one price rule by id
+func (m *testDBRepo) GetPriceRuleByID(id int) (models.PriceRule, error) {
+	var r models.PriceRule
+	if id > 100 {
+		return r, errors.New("some error")
+	}
+
+	r.ID = id
+	r.Name = "Weekly"
+	r.Kind = models.PriceLengthOfStay
+	r.Threshold = 7
+	r.Adjustment = -1000
+
+	return r, nil
+}
+
+//InsertPriceRule inserts a price rule into the database
+func (m *testDBRepo) InsertPriceRule(r models.PriceRule) (int, error) {
+	if r.Name == "error" {
+		return 0, errors.New("some error")
+	}
+
+	return 1, nil
+}
+
+//UpdatePriceRule updates a price rule in the database. Reservations keep the prices they were charged
+func (m *testDBRepo) UpdatePriceRule(r models.PriceRule) error {
+	if r.Name == "error" {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//DeletePriceRule deletes one price rule by id
+func (m *testDBRepo) DeletePriceRule(id int) error {
+	if id > 100 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//RatePlans returns the rate plans of all rooms, cheapest first
+func (m *testDBRepo) RatePlans() ([]models.RatePlan, error) {
+	return []models.RatePlan{
+		{ID: 1, RoomID: 1, Room: models.Room{ID: 1, RoomName: "General's Quarters"}, Name: "Non-refundable",
+			Price: 9000, CancellationPolicyID: 2},
+		{ID: 2, RoomID: 1, Room: models.Room{ID: 1, RoomName: "General's Quarters"}, Name: "Bed & Breakfast",
+			Price: 12000, Inclusions: "Breakfast for all guests"},
+	}, nil
+}
+
+//GetRatePlanByID returns one rate plan by id
+func (m *testDBRepo) GetRatePlanByID(id int) (models.RatePlan, error) {
+	var p models.RatePlan
+	if id > 100 {
+		return p, errors.New("some error")
+	}
+
+	p.ID = id
+	p.RoomID = 1
+	p.Room = models.Room{ID: 1, RoomName: "General's Quarters"}
+	p.Name = "Non-refundable"
+	p.Price = 9000
+	p.CancellationPolicyID = 2
+
+	//a plan of another room
+	if id == 50 {
+		p.RoomID = 2
+		p.Room.ID = 2
+	}
+
+	return p, nil
+}
+
+//InsertRatePlan inserts a rate plan into the database
+func (m *testDBRepo) InsertRatePlan(p models.RatePlan) (int, error) {
+	if p.Name == "error" {
+		return 0, errors.New("some error")
+	}
+
+	return 1, nil
+}
+
+//UpdateRatePlan updates a rate plan in the database. Reservations keep the prices they were charged
+func (m *testDBRepo) UpdateRatePlan(p models.RatePlan) error {
+	if p.Name == "error" {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//DeleteRatePlan deletes one rate plan by id, its reservations fall back to the cancellation policy of the room
+func (m *testDBRepo) DeleteRatePlan(id int) error {
+	if id > 100 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//Extras returns the catalog of extras
+func (m *testDBRepo) Extras() ([]models.Extra, error) {
+	return []models.Extra{
+		{ID: 1, Name: "Breakfast", Kind: models.ExtraPerGuestNight, Price: 1500},
+		{ID: 2, Name: "Parking", Description: "One space in the garage", Kind: models.ExtraPerNight, Price: 1000,
+			Inventory: 5},
+	}, nil
+}
+
+//GetExtraByID returns one extra by id
+func (m *testDBRepo) GetExtraByID(id int) (models.Extra, error) {
+	var e models.Extra
+	if id > 100 {
+		return e, errors.New("some error")
+	}
+
+	e.ID = id
+	e.Name = "Parking"
+	e.Kind = models.ExtraPerNight
+	e.Price = 1000
+	e.Inventory = 5
+
+	return e, nil
+}
+
+//InsertExtra inserts an extra into the database
+func (m *testDBRepo) InsertExtra(e models.Extra) (int, error) {
+	if e.Name == "error" {
+		return 0, errors.New("some error")
+	}
+
+	return 1, nil
+}
+
+//UpdateExtra updates an extra in the database. Reservations keep the extras they booked
+func (m *testDBRepo) UpdateExtra(e models.Extra) error {
+	if e.Name == "error" {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//DeleteExtra deletes one extra by id, reservations keep the extras they booked
+func (m *testDBRepo) DeleteExtra(id int) error {
+	if id > 100 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//AddReservationExtra books an extra with an existing reservation and adds its amount to the total price,
+//ErrExtraSoldOut is returned when not enough units are left for its nights
+func (m *testDBRepo) AddReservationExtra(reservationID int, x models.ReservationExtra) error {
+	if reservationID == 1000 {
+		return errors.New("some error")
+	}
+
+	if x.Quantity > 5 {
+		return repository.ErrExtraSoldOut
+	}
+
+	return nil
+}
+
+//DeleteReservationExtra removes an extra from a reservation and takes its amount off the total price
+func (m *testDBRepo) DeleteReservationExtra(id, reservationID int) error {
+	if reservationID == 1000 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//GiftVouchers returns all gift vouchers with their balances, newest first
+func (m *testDBRepo) GiftVouchers() ([]models.GiftVoucher, error) {
+	return []models.GiftVoucher{
+		{ID: 1, Code: "GIFT-50", Amount: 5000, Balance: 5000, Purchaser: "Jane Doe", Recipient: "John Doe"},
+		{ID: 3, Code: "USED-UP", Amount: 10000, Balance: 0,
+			ExpiresAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}, nil
+}
+
+//GetGiftVoucherByID returns one gift voucher by id with its ledger of redemptions, oldest first
+func (m *testDBRepo) GetGiftVoucherByID(id int) (models.GiftVoucher, error) {
+	var v models.GiftVoucher
+	if id > 100 {
+		return v, errors.New("some error")
+	}
+
+	v.ID = id
+	v.Code = "USED-UP"
+	v.Amount = 10000
+	v.Redemptions = []models.VoucherRedemption{
+		{ID: 1, GiftVoucherID: id, ReservationID: 1, FolioEntryID: 4, Amount: 12000},
+		{ID: 2, GiftVoucherID: id, ReservationID: 1, FolioEntryID: 5, Amount: -2000},
+	}
+
+	return v, nil
+}
+
+//GetGiftVoucherByCode returns the gift voucher guests enter as code, in any case, returns false if there is none
+func (m *testDBRepo) GetGiftVoucherByCode(code string) (models.GiftVoucher, bool, error) {
+	switch strings.ToUpper(code) {
+	case "GIFT-50":
+		return models.GiftVoucher{ID: 1, Code: "GIFT-50", Amount: 5000, Balance: 5000}, true, nil
+	case "GIFT-1000":
+		return models.GiftVoucher{ID: 2, Code: "GIFT-1000", Amount: 100000, Balance: 100000}, true, nil
+	case "USED-UP":
+		return models.GiftVoucher{ID: 3, Code: "USED-UP", Amount: 10000}, true, nil
+	case "RACE":
+		return models.GiftVoucher{ID: 4, Code: "RACE", Amount: 5000, Balance: 5000}, true, nil
+	case "EXPIRED":
+		return models.GiftVoucher{ID: 5, Code: "EXPIRED", Amount: 5000, Balance: 5000,
+			ExpiresAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}, true, nil
+	case "ERROR":
+		return models.GiftVoucher{}, false, errors.New("some error")
+	}
+
+	return models.GiftVoucher{}, false, nil
+}
+
+//InsertGiftVoucher inserts a gift voucher into the database
+func (m *testDBRepo) InsertGiftVoucher(v models.GiftVoucher) (int, error) {
+	if v.Purchaser == "error" {
+		return 0, errors.New("some error")
+	}
+
+	return 1, nil
+}
+
+//UpdateGiftVoucher updates who a gift voucher is from and for and when it expires. Its code and amount are
+//kept as sold
+func (m *testDBRepo) UpdateGiftVoucher(v models.GiftVoucher) error {
+	if v.Purchaser == "error" {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//RedeemGiftVoucher pays an amount on the folio of a reservation with a gift voucher and records it on the
+//ledger of the voucher, returning the id of the payment. ErrVoucherBalance is returned when less than the
+//amount is left on it
+func (m *testDBRepo) RedeemGiftVoucher(id, reservationID, amount int) (int, error) {
+	if reservationID == 1000 {
+		return 0, errors.New("some error")
+	}
+
+	if id == 4 {
+		return 0, repository.ErrVoucherBalance
+	}
+
+	return 1, nil
+}
+
+//RefundGiftVoucher enters the refund of a payment made with a gift voucher on a folio and puts the amount
+//back on the voucher, returning the id of the refund
+func (m *testDBRepo) RefundGiftVoucher(e models.FolioEntry) (int, error) {
+	if e.Description == "Refund: error" {
+		return 0, errors.New("some error")
+	}
+
+	return 1, nil
+}
+
+//ExchangeRates returns the exchange rates of all display currencies
+func (m *testDBRepo) ExchangeRates() ([]models.ExchangeRate, error) {
+	return []models.ExchangeRate{
+		{ID: 1, Currency: "EUR", Rate: 920000},
+		{ID: 2, Currency: "GBP", Rate: 790000},
+	}, nil
+}
+
+//GetExchangeRateByID returns one exchange rate by id
+func (m *testDBRepo) GetExchangeRateByID(id int) (models.ExchangeRate, error) {
+	var r models.ExchangeRate
+	if id > 100 {
+		return r, errors.New("some error")
+	}
+
+	r.ID = id
+	r.Currency = "EUR"
+	r.Rate = 920000
+
+	return r, nil
+}
+
+//InsertExchangeRate inserts an exchange rate into the database
+func (m *testDBRepo) InsertExchangeRate(r models.ExchangeRate) (int, error) {
+	if r.Currency == "XXX" {
+		return 0, errors.New("some error")
+	}
+
+	return 1, nil
+}
+
+//UpdateExchangeRate updates an exchange rate in the database
+func (m *testDBRepo) UpdateExchangeRate(r models.ExchangeRate) error {
+	if r.Currency == "XXX" {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+//DeleteExchangeRate deletes one exchange rate by id
+func (m *testDBRepo) DeleteExchangeRate(id int) error {
+	if id > 100 {
+		return errors.New("some error")
+	}
+
+	return nil
+}
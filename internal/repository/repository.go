@@ -1,11 +1,29 @@
 package repository
 
 import (
+	"errors"
 	"time"
 
 	"github.com/yalagtyarzh/leafsite/internal/models"
 )
 
+//ErrPromoCodeUsedUp is returned when a reservation redeems a promo code that was used up in the meantime
+var ErrPromoCodeUsedUp = errors.New("promo code has been used up")
+
+//ErrExtraSoldOut is returned when more units of an extra are booked than are left for some night of a stay
+var ErrExtraSoldOut = errors.New("extra is sold out")
+
+//ErrWaitlistOfferUsed is returned when a reservation is booked through a waitlist offer that was used, declined
+//or has expired in the meantime
+var ErrWaitlistOfferUsed = errors.New("waitlist offer is no longer open")
+
+//ErrVoucherBalance is returned when a gift voucher is redeemed for more than is left on it
+var ErrVoucherBalance = errors.New("gift voucher balance is too low")
+
+//ErrRefundTooLarge is returned when a payment is refunded for more than is left of it, because another refund
+//was entered in the meantime
+var ErrRefundTooLarge = errors.New("refund is larger than what is left of the payment")
+
 type DatabaseRepo interface {
 	AllUsers() bool
 	InsertReservation(res models.Reservation) (int, error)
@@ -16,8 +34,7 @@ type DatabaseRepo interface {
 	GetUserByID(id int) (models.User, error)
 	UpdateUser(u models.User) error
 	Authenticate(email, testPassword string) (int, string, error)
-	AllReservations() ([]models.Reservation, error)
-	AllNewReservations() ([]models.Reservation, error)
+	SearchReservations(f models.ReservationFilter, p models.Page) ([]models.Reservation, int, error)
 	GetReservationByID(id int) (models.Reservation, error)
 	UpdateReservation(r models.Reservation) error
 	DeleteReservation(id int) error
@@ -26,4 +43,103 @@ type DatabaseRepo interface {
 	GetRestrictionsForRoomByDate(roomID int, start, end time.Time) ([]models.RoomRestriction, error)
 	InsertBlockForRoom(id int, startDate time.Time) error
 	DeleteBlockByID(id int) error
+	AvailabilityCalendarByRoomID(roomID int, start, end time.Time) ([]models.NightAvailability, error)
+	CancelReservation(id, fee int) error
+	AllCancellationPolicies() ([]models.CancellationPolicy, error)
+	GetCancellationPolicyByID(id int) (models.CancellationPolicy, error)
+	InsertCancellationPolicy(p models.CancellationPolicy) (int, error)
+	UpdateCancellationPolicy(p models.CancellationPolicy) error
+	DeleteCancellationPolicy(id int) error
+	UpdateCancellationPolicyForRoom(roomID, policyID int) error
+	ChangeReservationStay(res models.Reservation) (bool, error)
+	InsertHold(r models.RoomRestriction) (int, bool, error)
+	HoldIsActive(id int, r models.RoomRestriction) (bool, error)
+	DeleteHoldByID(id int) error
+	DeleteExpiredHolds() (int64, error)
+	InsertWaitlistEntry(e models.WaitlistEntry) (int, error)
+	GetWaitlistEntryByID(id int) (models.WaitlistEntry, error)
+	AllWaitlistEntries() ([]models.WaitlistEntry, error)
+	WaitingEntriesForRoom(roomID int, start, end time.Time) ([]models.WaitlistEntry, error)
+	MarkWaitlistEntryNotified(id, roomID int, expires time.Time) error
+	DeclineWaitlistOffer(id int) (bool, error)
+	ExpireWaitlistOffers() ([]models.WaitlistEntry, error)
+	DeleteWaitlistEntry(id int) error
+	DashboardCounts(day time.Time) (models.DashboardCounts, error)
+	OccupiedRoomsByNight(start, end time.Time) ([]models.DailyCount, error)
+	ReservationsCreatedByDay(start, end time.Time) ([]models.DailyCount, error)
+	ReservationsForReport(start, end time.Time) ([]models.Reservation, error)
+	EachReservation(f models.ReservationFilter, fn func(models.Reservation) error) error
+	EachGuestContact(fn func(models.GuestContact) error) error
+	ImportReservations(res []models.Reservation) (bool, error)
+	InsertReservationNote(n models.ReservationNote) error
+	NotesForReservation(reservationID int) ([]models.ReservationNote, error)
+	DeleteReservationNote(id, reservationID int) error
+	UpdateReservationTags(res models.Reservation) error
+	AllTags() ([]string, error)
+	SearchGuests(search string, p models.Page) ([]models.Guest, int, error)
+	GetGuestByID(id int) (models.Guest, error)
+	ReservationsForGuest(guestID int) ([]models.Reservation, error)
+	PossibleDuplicateGuests(g models.Guest) ([]models.Guest, error)
+	MergeGuests(keepID, mergeID int) error
+	ArrivalsForDay(day time.Time) ([]models.Reservation, error)
+	DeparturesForDay(day time.Time) ([]models.Reservation, error)
+	CheckIn(id int, at time.Time) (bool, error)
+	CheckOut(id int, at time.Time) (bool, error)
+	MarkNoShow(id int) (bool, error)
+	MarkNoShows(arrival time.Time) (int64, error)
+	HousekeepingBoard(day time.Time) ([]models.HousekeepingRoom, error)
+	UpdateRoomHousekeepingStatus(roomID int, status string) error
+	ListUsers() ([]models.User, error)
+	MaintenanceTickets(status string) ([]models.MaintenanceTicket, error)
+	GetMaintenanceTicketByID(id int) (models.MaintenanceTicket, error)
+	InsertMaintenanceTicket(t models.MaintenanceTicket) (int, bool, error)
+	UpdateMaintenanceTicket(t models.MaintenanceTicket) (bool, error)
+	InsertFolioEntry(e models.FolioEntry) (int, error)
+	FolioEntries(reservationID int) ([]models.FolioEntry, error)
+	UpdatePaymentStatus(provider, reference, status string) (bool, error)
+	InsertRefund(e models.FolioEntry) (int, error)
+	UpdateRefund(id int, status, reference string) error
+	InsertInvoice(inv models.Invoice) (models.Invoice, error)
+	GetInvoiceByNumber(number int) (models.Invoice, error)
+	InvoicesForReservation(reservationID int) ([]models.Invoice, error)
+	TaxRules() ([]models.TaxRule, error)
+	GetTaxRuleByID(id int) (models.TaxRule, error)
+	InsertTaxRule(r models.TaxRule) (int, error)
+	UpdateTaxRule(r models.TaxRule) error
+	DeleteTaxRule(id int) error
+	GetPromoCodeByID(id int) (models.PromoCode, error)
+	GetPromoCodeByCode(code string) (models.PromoCode, bool, error)
+	InsertPromoCode(p models.PromoCode) (int, error)
+	UpdatePromoCode(p models.PromoCode) error
+	DeletePromoCode(id int) error
+	PromoCodeUsage(start, end time.Time) ([]models.PromoCodeUsage, error)
+	PriceRules() ([]models.PriceRule, error)
+	GetPriceRuleByID(id int) (models.PriceRule, error)
+	InsertPriceRule(r models.PriceRule) (int, error)
+	UpdatePriceRule(r models.PriceRule) error
+	DeletePriceRule(id int) error
+	RatePlans() ([]models.RatePlan, error)
+	GetRatePlanByID(id int) (models.RatePlan, error)
+	InsertRatePlan(p models.RatePlan) (int, error)
+	UpdateRatePlan(p models.RatePlan) error
+	DeleteRatePlan(id int) error
+	Extras() ([]models.Extra, error)
+	GetExtraByID(id int) (models.Extra, error)
+	InsertExtra(e models.Extra) (int, error)
+	UpdateExtra(e models.Extra) error
+	DeleteExtra(id int) error
+	AddReservationExtra(reservationID int, x models.ReservationExtra) error
+	DeleteReservationExtra(id, reservationID int) error
+	GiftVouchers() ([]models.GiftVoucher, error)
+	GetGiftVoucherByID(id int) (models.GiftVoucher, error)
+	GetGiftVoucherByCode(code string) (models.GiftVoucher, bool, error)
+	InsertGiftVoucher(v models.GiftVoucher) (int, error)
+	UpdateGiftVoucher(v models.GiftVoucher) error
+	RedeemGiftVoucher(id, reservationID, amount int) (int, error)
+	RefundGiftVoucher(e models.FolioEntry) (int, error)
+	ExchangeRates() ([]models.ExchangeRate, error)
+	GetExchangeRateByID(id int) (models.ExchangeRate, error)
+	InsertExchangeRate(r models.ExchangeRate) (int, error)
+	UpdateExchangeRate(r models.ExchangeRate) error
+	DeleteExchangeRate(id int) error
 }
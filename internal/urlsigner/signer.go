@@ -0,0 +1,91 @@
+package urlsigner
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	//ErrInvalidSignature is returned for links that were not signed by us or were tampered with
+	ErrInvalidSignature = errors.New("invalid link signature")
+	//ErrExpired is returned for correctly signed links used after their expiry
+	ErrExpired = errors.New("link has expired")
+)
+
+//Signer signs and verifies links with a secret key
+type Signer struct {
+	Secret []byte
+}
+
+//New creates a signer for the secret key
+func New(secret string) *Signer {
+	return &Signer{
+		Secret: []byte(secret),
+	}
+}
+
+//Sign appends the expiry time and a signature to a link
+func (s *Signer) Sign(link string, expires time.Time) string {
+	separator := "?"
+	if strings.Contains(link, "?") {
+		separator = "&"
+	}
+
+	unsigned := link + separator + "expires=" + strconv.FormatInt(expires.Unix(), 10)
+
+	return unsigned + "&signature=" + s.signature(pathAndQuery(unsigned))
+}
+
+//Verify checks that a link carries a valid signature and has not expired yet.
+//Scheme and host are ignored, so a request URI can be verified as well as a full link
+func (s *Signer) Verify(link string) error {
+	signed := pathAndQuery(link)
+
+	i := strings.LastIndex(signed, "&signature=")
+	if i < 0 {
+		return ErrInvalidSignature
+	}
+
+	unsigned, signature := signed[:i], signed[i+len("&signature="):]
+	if !hmac.Equal([]byte(signature), []byte(s.signature(unsigned))) {
+		return ErrInvalidSignature
+	}
+
+	u, err := url.Parse(unsigned)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	expires, err := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if time.Now().After(time.Unix(expires, 0)) {
+		return ErrExpired
+	}
+
+	return nil
+}
+
+func (s *Signer) signature(data string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//pathAndQuery strips scheme and host from a link
+func pathAndQuery(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+
+	return strings.TrimPrefix(link, u.Scheme+"://"+u.Host)
+}
@@ -0,0 +1,45 @@
+package urlsigner
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSigner_Sign(t *testing.T) {
+	s := New("secret")
+
+	link := s.Sign("http://localhost:8080/waitlist/book?id=1", time.Now().Add(time.Hour))
+	if !strings.Contains(link, "&expires=") || !strings.Contains(link, "&signature=") {
+		t.Errorf("signed link %s is missing expiry or signature", link)
+	}
+
+	link = s.Sign("http://localhost:8080/invoice", time.Now().Add(time.Hour))
+	if !strings.HasPrefix(link, "http://localhost:8080/invoice?expires=") {
+		t.Errorf("signed link %s does not start a query string", link)
+	}
+}
+
+func TestSigner_Verify(t *testing.T) {
+	s := New("secret")
+	valid := s.Sign("http://localhost:8080/waitlist/book?id=1", time.Now().Add(time.Hour))
+
+	var theTests = []struct {
+		name     string
+		link     string
+		expected error
+	}{
+		{"valid", valid, nil},
+		{"valid-request-uri", strings.TrimPrefix(valid, "http://localhost:8080"), nil},
+		{"tampered", strings.Replace(valid, "id=1", "id=2", 1), ErrInvalidSignature},
+		{"unsigned", "/waitlist/book?id=1", ErrInvalidSignature},
+		{"other-secret", New("other").Sign("/waitlist/book?id=1", time.Now().Add(time.Hour)), ErrInvalidSignature},
+		{"expired", s.Sign("/waitlist/book?id=1", time.Now().Add(-time.Minute)), ErrExpired},
+	}
+
+	for _, tt := range theTests {
+		if err := s.Verify(tt.link); err != tt.expected {
+			t.Errorf("failed %s: expected %v, but got %v", tt.name, tt.expected, err)
+		}
+	}
+}
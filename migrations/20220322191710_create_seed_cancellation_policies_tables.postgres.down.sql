@@ -0,0 +1 @@
+delete from cancellation_policies where name = 'Flexible';
@@ -0,0 +1,4 @@
+INSERT INTO public.cancellation_policies (name,free_days_before,fee_type,fee_percent,created_at,updated_at) VALUES
+	 ('Flexible',2,'first_night',0,'2022-03-22 00:00:00.000','2022-03-22 00:00:00.000');
+
+UPDATE public.rooms SET cancellation_policy_id = (SELECT id FROM public.cancellation_policies WHERE name = 'Flexible');
@@ -0,0 +1,2 @@
+update reservations set guest_id = null;
+delete from guests;
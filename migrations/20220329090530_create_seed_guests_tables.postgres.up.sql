@@ -0,0 +1,9 @@
+insert into guests (first_name, last_name, email, phone, created_at, updated_at)
+select distinct on (lower(email)) first_name, last_name, email, phone, now(), now()
+from reservations
+where email <> ''
+order by lower(email), created_at desc;
+
+update reservations r set guest_id = g.id
+from guests g
+where lower(g.email) = lower(r.email);
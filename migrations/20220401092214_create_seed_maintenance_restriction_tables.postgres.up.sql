@@ -0,0 +1,5 @@
+delete from restrictions where id = 4;
+INSERT INTO public.restrictions (id,restriction_name,created_at,updated_at) VALUES
+	 (4,'Maintenance','2022-04-01 00:00:00.000','2022-04-01 00:00:00.000');
+
+SELECT setval(pg_get_serial_sequence('public.restrictions', 'id'), (SELECT max(id) FROM public.restrictions));
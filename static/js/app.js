@@ -102,4 +102,25 @@ function Prompt () {
 		error: error,
 		custom: custom,
 	}
-}
\ No newline at end of file
+}
+
+function disableBookedNights (picker, roomID) {
+	const pad = n => String(n).padStart(2, "0");
+	const isoDate = d => d.getFullYear() + "-" + pad(d.getMonth() + 1) + "-" + pad(d.getDate());
+
+	const start = new Date();
+	const end = new Date(start.getFullYear(), start.getMonth() + 6, start.getDate());
+
+	fetch('/availability-calendar?room_id=' + roomID + '&start=' + isoDate(start) + '&end=' + isoDate(end))
+			.then(response => response.json())
+			.then(data => {
+				if (!data.ok) {
+					return;
+				}
+
+				const booked = data.nights.filter(n => !n.available).map(n => n.date);
+				picker.datepickers.forEach(dp => dp.setOptions({datesDisabled: booked}));
+			})
+			.catch(function() {
+			});
+}